@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/backend-guchitter-app/domain/color"
+	"github.com/backend-guchitter-app/usecase"
+)
+
+// アバターの色の検証と配色
+type ColorConfig struct {
+	// フロントエンドの背景色。アバターの色とのコントラスト比を求める。#rrggbb, rgb()またはCSSの色名
+	Background string `yaml:"background"`
+	// 背景色とのコントラスト比がこれ未満の色の登録を拒否する。0なら拒否しない
+	// WCAGの非テキストの基準は3、文字の基準は4.5
+	MinContrast float64 `yaml:"minContrast"`
+}
+
+func defaultColorConfig() ColorConfig {
+	return ColorConfig{
+		Background: "#ffffff",
+	}
+}
+
+// Policy はusecase.NewAvatarUseCaseに渡す色の扱いを返す
+func (c ColorConfig) Policy() usecase.ColorPolicy {
+	// 解釈できない背景色はvalidateで弾くため、ここでは白とする
+	background, err := color.Parse(c.Background)
+	if err != nil {
+		background = color.White
+	}
+	return usecase.ColorPolicy{
+		Background:  background,
+		MinContrast: c.MinContrast,
+	}
+}
+
+func (c ColorConfig) validate() []string {
+	problems := []string{}
+	if _, err := color.Parse(c.Background); err != nil {
+		problems = append(problems, fmt.Sprintf("guchitter_FRONT_BACKGROUND: must be a hex, rgb() or CSS named color, got %q", c.Background))
+	}
+	// コントラスト比は1から21の間
+	if c.MinContrast != 0 && (c.MinContrast < 1 || c.MinContrast > 21) {
+		problems = append(problems, fmt.Sprintf("guchitter_COLOR_MIN_CONTRAST: must be 0 or between 1 and 21, got %g", c.MinContrast))
+	}
+	return problems
+}
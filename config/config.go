@@ -0,0 +1,518 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/backend-guchitter-app/ratelimit"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// アプリケーションの設定
+// Loadで起動時に一度だけ読み込み、必要な値を各コンストラクタに渡す
+//
+// 値の優先順位は 環境変数(.env.<GUCHITTER_ENV>を含む) > guchitter_CONFIG_FILEのYAML > デフォルト
+type Config struct {
+	// development または production。GUCHITTER_ENVで指定する
+	Env string `yaml:"-"`
+	// 待ち受けるポート。PORTで指定する
+	Port string `yaml:"port"`
+	// 停止のシグナルを受けてから、処理中のリクエストなどを待つ時間の上限
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// 停止のシグナルを受けてから、/readyzを503にしたまま新しいリクエストを受け付け続ける時間
+	// ロードバランサーが振り分け先から外すのを待つ。ShutdownTimeoutに含まれる
+	ShutdownDrainDelay time.Duration `yaml:"shutdownDrainDelay"`
+	// CORSで許可するオリジン。guchitter_FRONT_ORIGINにカンマ区切りで指定する
+	AllowOrigins []string `yaml:"allowOrigins"`
+	// X-Forwarded-Forを信頼するプロキシ(IPアドレスまたはCIDR)。guchitter_TRUSTED_PROXIESにカンマ区切りで指定する
+	// 空の場合は接続元のIPアドレスをクライアントのIPアドレスとする
+	TrustedProxies []string          `yaml:"trustedProxies"`
+	Database       DatabaseConfig    `yaml:"database"`
+	Log            LogConfig         `yaml:"log"`
+	Trace          TraceConfig       `yaml:"trace"`
+	Timeouts       TimeoutConfig     `yaml:"timeouts"`
+	RateLimits     RateLimitConfig   `yaml:"rateLimits"`
+	Duplicates     DuplicateConfig   `yaml:"duplicates"`
+	Idempotency    IdempotencyConfig `yaml:"idempotency"`
+	Images         ImageConfig       `yaml:"images"`
+	Colors         ColorConfig       `yaml:"colors"`
+	Features       FeatureConfig     `yaml:"features"`
+}
+
+// DBの接続先とコネクションプール
+type DatabaseConfig struct {
+	// mysql, sqlite, memory のいずれか
+	Driver string `yaml:"driver"`
+	User   string `yaml:"user"`
+	Pass   string `yaml:"pass"`
+	Name   string `yaml:"name"`
+	Host   string `yaml:"host"`
+	Port   string `yaml:"port"`
+	// Driverがsqliteの場合のDBファイル
+	SQLitePath string `yaml:"sqlitePath"`
+
+	// 0の場合は無制限
+	MaxOpenConns int `yaml:"maxOpenConns"`
+	MaxIdleConns int `yaml:"maxIdleConns"`
+	// 0の場合は接続を使い回し続ける
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime"`
+}
+
+// リクエストの処理時間の上限
+type TimeoutConfig struct {
+	// 0の場合は上限なし
+	Request time.Duration `yaml:"request"`
+	// キーは"GET /v1/complaints"のようにメソッドとルートのパターン
+	Routes map[string]time.Duration `yaml:"routes"`
+}
+
+// 機能の有効・無効
+type FeatureConfig struct {
+	// バージョンなしの旧エンドポイントを公開する
+	LegacyRoutes bool `yaml:"legacyRoutes"`
+	// /swagger/*でSwagger UIを公開する
+	Swagger bool `yaml:"swagger"`
+	// /metricsでPrometheusのメトリクスを公開する
+	Metrics bool `yaml:"metrics"`
+	// /import/*でぐちとアバターの取り込みを公開する。認証がないため、必要なときのみ有効にする
+	Import bool `yaml:"import"`
+}
+
+// Default はデフォルトの設定を返す
+func Default() *Config {
+	return &Config{
+		Env:  "development",
+		Port: "8080",
+		// Herokuは停止のシグナルから30秒で強制終了するため、それより短くする
+		ShutdownTimeout: 25 * time.Second,
+		Database: DatabaseConfig{
+			Driver:          DriverMySQL,
+			Port:            "3306",
+			SQLitePath:      defaultSQLitePath,
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Log:   defaultLogConfig(),
+		Trace: defaultTraceConfig(),
+		Timeouts: TimeoutConfig{
+			Request: 10 * time.Second,
+			// 全件の書き出しと取り込みは時間がかかるため長くする
+			Routes: map[string]time.Duration{
+				"GET /export/complaints":  5 * time.Minute,
+				"GET /export/avatars":     5 * time.Minute,
+				"POST /import/complaints": 5 * time.Minute,
+				"POST /import/avatars":    5 * time.Minute,
+			},
+		},
+		RateLimits:  defaultRateLimitConfig(),
+		Duplicates:  defaultDuplicateConfig(),
+		Idempotency: defaultIdempotencyConfig(),
+		Images:      defaultImageConfig(),
+		Colors:      defaultColorConfig(),
+		Features: FeatureConfig{
+			LegacyRoutes: true,
+			Swagger:      true,
+			Metrics:      true,
+		},
+	}
+}
+
+// Load は.envファイル、YAML、環境変数から設定を読み込み、検証する
+// 不正な値があれば、全ての問題を列挙したValidationErrorを返す
+func Load() (*Config, error) {
+	cfg, err := loadFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &envReader{}
+	cfg.readEnv(e)
+	problems := append(e.problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// LoadDatabase はLoadと同じ優先順位で、DBの接続先のみを読み込んで検証する
+// Migrationのコマンドなど、guchitter_FRONT_ORIGINのようなアプリケーションの設定を必要としない場合に使う
+func LoadDatabase() (*DatabaseConfig, error) {
+	cfg, err := loadFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &envReader{}
+	cfg.Database.readEnv(e)
+	problems := append(e.problems, cfg.Database.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return &cfg.Database, nil
+}
+
+// loadFiles はデフォルトの設定を.envファイルとguchitter_CONFIG_FILEのYAMLで上書きする
+func loadFiles() (*Config, error) {
+	cfg := Default()
+	cfg.Env = currentEnv()
+	godotenv.Load(".env." + cfg.Env)
+
+	if path := os.Getenv("guchitter_CONFIG_FILE"); path != "" {
+		if err := cfg.loadYAML(path); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Validate は設定の値を検証する
+func (cfg *Config) Validate() error {
+	if problems := cfg.validate(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ValidationError は設定の不正な値の一覧
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func currentEnv() string {
+	if os.Getenv("GUCHITTER_ENV") == "production" {
+		return "production"
+	}
+	return "development"
+}
+
+// loadYAML はpathのYAMLで設定を上書きする。未知のキーはエラーにする
+func (cfg *Config) loadYAML(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("guchitter_CONFIG_FILE: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("guchitter_CONFIG_FILE %s: %w", path, err)
+	}
+	return nil
+}
+
+// readEnv は環境変数が設定されている項目を上書きする
+func (cfg *Config) readEnv(e *envReader) {
+	e.string("PORT", &cfg.Port)
+	e.duration("guchitter_SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	e.duration("guchitter_SHUTDOWN_DRAIN_DELAY", &cfg.ShutdownDrainDelay)
+	e.list("guchitter_FRONT_ORIGIN", &cfg.AllowOrigins)
+	e.list("guchitter_TRUSTED_PROXIES", &cfg.TrustedProxies)
+
+	cfg.Database.readEnv(e)
+
+	log := &cfg.Log
+	e.string("guchitter_LOG_LEVEL", &log.Level)
+	e.string("guchitter_LOG_FORMAT", &log.Format)
+	e.int("guchitter_LOG_SAMPLE_BURST", &log.SampleBurst)
+	e.int("guchitter_LOG_SAMPLE_EVERY", &log.SampleEvery)
+	e.list("guchitter_LOG_REDACT_KEYS", &log.RedactKeys)
+	e.string("guchitter_SQL_LOG_LEVEL", &log.SQLLevel)
+	e.duration("guchitter_SLOW_QUERY_THRESHOLD", &log.SlowQueryThreshold)
+
+	trace := &cfg.Trace
+	e.string("guchitter_TRACE_EXPORTER", &trace.Exporter)
+	e.string("guchitter_TRACE_ENDPOINT", &trace.Endpoint)
+	e.bool("guchitter_TRACE_INSECURE", &trace.Insecure)
+	e.float("guchitter_TRACE_SAMPLE_RATIO", &trace.SampleRatio)
+	e.string("guchitter_TRACE_SERVICE_NAME", &trace.ServiceName)
+
+	e.duration("guchitter_REQUEST_TIMEOUT", &cfg.Timeouts.Request)
+	if v, ok := e.lookup("guchitter_ROUTE_TIMEOUTS"); ok {
+		routes, err := parseRouteTimeouts(v)
+		if err != nil {
+			e.problems = append(e.problems, err.Error())
+		} else {
+			// 指定したルートだけを上書きし、書き出しなど他のルートのデフォルトの上限は残す
+			for route, d := range routes {
+				cfg.Timeouts.Routes[route] = d
+			}
+		}
+	}
+
+	if v, ok := e.lookup("guchitter_RATE_LIMIT"); ok {
+		if limit, err := ratelimit.ParseLimit(v); err != nil {
+			e.problems = append(e.problems, "guchitter_RATE_LIMIT: "+err.Error())
+		} else {
+			cfg.RateLimits.Default = limit
+		}
+	}
+	if v, ok := e.lookup("guchitter_ROUTE_RATE_LIMITS"); ok {
+		routes, err := parseRouteRateLimits("guchitter_ROUTE_RATE_LIMITS", v)
+		if err != nil {
+			e.problems = append(e.problems, err.Error())
+		} else {
+			// 指定したルートだけを上書きし、他のルートのデフォルトの上限は残す
+			for route, limit := range routes {
+				cfg.RateLimits.Routes[route] = limit
+			}
+		}
+	}
+	if v, ok := e.lookup("guchitter_ROUTE_ITEM_RATE_LIMITS"); ok {
+		routes, err := parseRouteRateLimits("guchitter_ROUTE_ITEM_RATE_LIMITS", v)
+		if err != nil {
+			e.problems = append(e.problems, err.Error())
+		} else {
+			for route, limit := range routes {
+				cfg.RateLimits.Items[route] = limit
+			}
+		}
+	}
+
+	e.string("guchitter_DUPLICATE_ACTION", &cfg.Duplicates.Action)
+	e.duration("guchitter_DUPLICATE_WINDOW", &cfg.Duplicates.Window)
+	e.int("guchitter_DUPLICATE_MAX_DISTANCE", &cfg.Duplicates.MaxDistance)
+
+	e.duration("guchitter_IDEMPOTENCY_TTL", &cfg.Idempotency.TTL)
+	e.duration("guchitter_IDEMPOTENCY_LOCK_TIMEOUT", &cfg.Idempotency.LockTimeout)
+
+	images := &cfg.Images
+	e.int("guchitter_IMAGE_MAX_BYTES", &images.MaxBytes)
+	e.string("guchitter_IMAGE_STORE", &images.Store)
+	e.string("guchitter_IMAGE_DIR", &images.Dir)
+	e.string("guchitter_S3_ENDPOINT", &images.S3.Endpoint)
+	e.string("guchitter_S3_REGION", &images.S3.Region)
+	e.string("guchitter_S3_BUCKET", &images.S3.Bucket)
+	e.string("guchitter_S3_ACCESS_KEY_ID", &images.S3.AccessKeyID)
+	e.string("guchitter_S3_SECRET_ACCESS_KEY", &images.S3.SecretAccessKey)
+	e.bool("guchitter_S3_PATH_STYLE", &images.S3.PathStyle)
+
+	e.string("guchitter_FRONT_BACKGROUND", &cfg.Colors.Background)
+	e.float("guchitter_COLOR_MIN_CONTRAST", &cfg.Colors.MinContrast)
+
+	e.bool("guchitter_FEATURE_LEGACY_ROUTES", &cfg.Features.LegacyRoutes)
+	e.bool("guchitter_FEATURE_SWAGGER", &cfg.Features.Swagger)
+	e.bool("guchitter_FEATURE_METRICS", &cfg.Features.Metrics)
+	e.bool("guchitter_FEATURE_IMPORT", &cfg.Features.Import)
+}
+
+// readEnv は環境変数が設定されているDBの項目を上書きする
+func (db *DatabaseConfig) readEnv(e *envReader) {
+	e.string("guchitter_DRIVER", &db.Driver)
+	e.string("guchitter_USER", &db.User)
+	e.string("guchitter_PASS", &db.Pass)
+	e.string("guchitter_DBNAME", &db.Name)
+	e.string("guchitter_HOST", &db.Host)
+	e.string("guchitter_PORT", &db.Port)
+	e.string("guchitter_SQLITE_PATH", &db.SQLitePath)
+	e.int("guchitter_DB_MAX_OPEN_CONNS", &db.MaxOpenConns)
+	e.int("guchitter_DB_MAX_IDLE_CONNS", &db.MaxIdleConns)
+	e.duration("guchitter_DB_CONN_MAX_LIFETIME", &db.ConnMaxLifetime)
+	e.duration("guchitter_DB_CONN_MAX_IDLE_TIME", &db.ConnMaxIdleTime)
+}
+
+func (cfg *Config) validate() []string {
+	problems := []string{}
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT: must be a port number between 1 and 65535, got %q", cfg.Port))
+	}
+
+	if cfg.ShutdownTimeout <= 0 {
+		problems = append(problems, "guchitter_SHUTDOWN_TIMEOUT: must be positive")
+	}
+	if cfg.ShutdownDrainDelay < 0 || (cfg.ShutdownTimeout > 0 && cfg.ShutdownDrainDelay >= cfg.ShutdownTimeout) {
+		problems = append(problems, fmt.Sprintf("guchitter_SHUTDOWN_DRAIN_DELAY: must be between 0 and guchitter_SHUTDOWN_TIMEOUT (%s), got %s", cfg.ShutdownTimeout, cfg.ShutdownDrainDelay))
+	}
+
+	if len(cfg.AllowOrigins) == 0 {
+		problems = append(problems, "guchitter_FRONT_ORIGIN: at least one origin is required")
+	}
+	for _, origin := range cfg.AllowOrigins {
+		if err := validateOrigin(origin); err != nil {
+			problems = append(problems, fmt.Sprintf("guchitter_FRONT_ORIGIN: %v", err))
+		}
+	}
+
+	for _, proxy := range cfg.TrustedProxies {
+		if err := validateTrustedProxy(proxy); err != nil {
+			problems = append(problems, fmt.Sprintf("guchitter_TRUSTED_PROXIES: %v", err))
+		}
+	}
+
+	problems = append(problems, cfg.Database.validate()...)
+
+	if _, err := cfg.Log.options(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	problems = append(problems, cfg.Trace.validate()...)
+	problems = append(problems, cfg.RateLimits.validate()...)
+	problems = append(problems, cfg.Duplicates.validate()...)
+	problems = append(problems, cfg.Idempotency.validate(cfg.Timeouts.Request)...)
+	problems = append(problems, cfg.Images.validate()...)
+	problems = append(problems, cfg.Colors.validate()...)
+
+	if cfg.Timeouts.Request < 0 {
+		problems = append(problems, "guchitter_REQUEST_TIMEOUT: must not be negative")
+	}
+	for route, d := range cfg.Timeouts.Routes {
+		if len(strings.Fields(route)) != 2 || d < 0 {
+			problems = append(problems, fmt.Sprintf("guchitter_ROUTE_TIMEOUTS: %q=%s must be \"METHOD /path\" with a non-negative duration", route, d))
+		}
+	}
+
+	return problems
+}
+
+// validateOrigin はoriginが"scheme://host[:port]"の形式であることを確認する
+func validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q must be an http(s) origin like https://example.com", origin)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q must not have a path, query or fragment", origin)
+	}
+	return nil
+}
+
+func (db DatabaseConfig) validate() []string {
+	problems := []string{}
+
+	switch db.Driver {
+	case DriverMySQL:
+		for _, required := range []struct{ name, value string }{
+			{"guchitter_USER", db.User},
+			{"guchitter_DBNAME", db.Name},
+			{"guchitter_HOST", db.Host},
+		} {
+			if required.value == "" {
+				problems = append(problems, required.name+": required when guchitter_DRIVER is mysql")
+			}
+		}
+		if port, err := strconv.Atoi(db.Port); err != nil || port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("guchitter_PORT: must be a port number between 1 and 65535, got %q", db.Port))
+		}
+	case DriverSQLite:
+		if db.SQLitePath == "" {
+			problems = append(problems, "guchitter_SQLITE_PATH: required when guchitter_DRIVER is sqlite")
+		}
+	case DriverMemory:
+	default:
+		problems = append(problems, fmt.Sprintf("guchitter_DRIVER: must be %s, %s or %s, got %q", DriverMySQL, DriverSQLite, DriverMemory, db.Driver))
+	}
+
+	if db.MaxOpenConns < 0 {
+		problems = append(problems, "guchitter_DB_MAX_OPEN_CONNS: must not be negative")
+	}
+	if db.MaxIdleConns < 0 {
+		problems = append(problems, "guchitter_DB_MAX_IDLE_CONNS: must not be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		problems = append(problems, fmt.Sprintf("guchitter_DB_MAX_IDLE_CONNS: %d must not exceed guchitter_DB_MAX_OPEN_CONNS (%d)", db.MaxIdleConns, db.MaxOpenConns))
+	}
+	if db.ConnMaxLifetime < 0 {
+		problems = append(problems, "guchitter_DB_CONN_MAX_LIFETIME: must not be negative")
+	}
+	if db.ConnMaxIdleTime < 0 {
+		problems = append(problems, "guchitter_DB_CONN_MAX_IDLE_TIME: must not be negative")
+	}
+
+	return problems
+}
+
+// envReader は環境変数を型に合わせて読み込み、解釈できない値を記録する
+type envReader struct {
+	problems []string
+}
+
+// lookup は空でない値が設定されていればそれを返す
+func (e *envReader) lookup(name string) (string, bool) {
+	v := strings.TrimSpace(os.Getenv(name))
+	return v, v != ""
+}
+
+func (e *envReader) string(name string, dst *string) {
+	if v, ok := e.lookup(name); ok {
+		*dst = v
+	}
+}
+
+func (e *envReader) list(name string, dst *[]string) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	items := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (e *envReader) int(name string, dst *int) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s: must be an integer, got %q", name, v))
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) float(name string, dst *float64) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s: must be a number, got %q", name, v))
+		return
+	}
+	*dst = f
+}
+
+func (e *envReader) duration(name string, dst *time.Duration) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s: must be a duration like 10s or 500ms, got %q", name, v))
+		return
+	}
+	*dst = d
+}
+
+func (e *envReader) bool(name string, dst *bool) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s: must be true or false, got %q", name, v))
+		return
+	}
+	*dst = b
+}
@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/backend-guchitter-app/config"
+)
+
+func TestLoadDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "without app settings",
+			env:  map[string]string{"guchitter_USER": "guchitter", "guchitter_DBNAME": "guchitter", "guchitter_HOST": "localhost"},
+			want: "guchitter:@tcp(localhost:3306)/guchitter?charset=utf8mb4&parseTime=True&loc=Local&multiStatements=true",
+		},
+		{
+			name:    "missing host",
+			env:     map[string]string{"guchitter_USER": "guchitter", "guchitter_DBNAME": "guchitter"},
+			wantErr: "guchitter_HOST: required when guchitter_DRIVER is mysql",
+		},
+		{
+			name:    "invalid pool size",
+			env:     map[string]string{"guchitter_USER": "guchitter", "guchitter_DBNAME": "guchitter", "guchitter_HOST": "localhost", "guchitter_DB_MAX_OPEN_CONNS": "ten"},
+			wantErr: "guchitter_DB_MAX_OPEN_CONNS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// アプリケーションの設定は空にしておく。Loadでは不正になる
+			for _, key := range []string{"guchitter_CONFIG_FILE", "guchitter_FRONT_ORIGIN", "guchitter_HOST", "guchitter_USER", "guchitter_DBNAME", "guchitter_DB_MAX_OPEN_CONNS"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			db, err := config.LoadDatabase()
+			if tt.wantErr != "" {
+				var invalid *config.ValidationError
+				if !errors.As(err, &invalid) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadDatabase() error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadDatabase() error = %v", err)
+			}
+			if got := db.DSN(); got != tt.want {
+				t.Errorf("DSN() = %q; want %q", got, tt.want)
+			}
+			if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), "guchitter_FRONT_ORIGIN") {
+				t.Errorf("Load() error = %v; want guchitter_FRONT_ORIGIN to be required", err)
+			}
+		})
+	}
+}
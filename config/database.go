@@ -1,51 +1,92 @@
 package config
 
 import (
-	"os"
-
-	"github.com/joho/godotenv"
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/idempotency"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/glebarez/sqlite"
+	pkgerrors "github.com/pkg/errors"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func Connect() *gorm.DB {
+// guchitter_DRIVER に指定できる値
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+	// DBを使わず、プロセス内のメモリに保存する(再起動で消える)
+	DriverMemory = "memory"
+)
 
-	CONNECT := GetDsn()
-
-	db, err := gorm.Open(mysql.Open(CONNECT), &gorm.Config{
-		// level infoに設定することでSQLがログ出力される
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+// SQLiteのファイルのデフォルト
+const defaultSQLitePath = "guchitter.db"
 
+// Connect はdb.Driverに応じたDBに接続し、コネクションプールを設定する
+// memoryの場合はDBを使わないのでエラーになる
+func Connect(db DatabaseConfig, sqlLogger logger.Interface) (*gorm.DB, error) {
+	var (
+		conn *gorm.DB
+		err  error
+	)
+	switch db.Driver {
+	case DriverMemory:
+		return nil, pkgerrors.New("guchitter_DRIVER is memory: there is no database to connect to")
+	case DriverSQLite:
+		conn, err = openSQLite(db.SQLitePath, sqlLogger)
+	default:
+		conn, err = gorm.Open(mysql.Open(db.DSN()), &gorm.Config{
+			// guchitter_SQL_LOG_LEVEL=infoでSQLがDebugのログに出力される
+			Logger: sqlLogger,
+		})
+	}
 	if err != nil {
-		panic("connect failed")
+		return nil, pkgerrors.Wrap(err, "connect failed")
 	}
 
-	return db
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "connect failed")
+	}
+	sqlDB.SetMaxOpenConns(db.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(db.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(db.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(db.ConnMaxIdleTime)
 
+	return conn, nil
 }
 
-func GetDsn() string {
-	env := os.Getenv("GUCHITTER_ENV")
+// ConnectSQLite はpathのSQLiteに接続し、モデルからテーブルを作成する
+// インメモリで使う場合は"file::memory:?cache=shared"のように共有キャッシュを指定する
+// テスト用で、SQLはデフォルトの設定(失敗と遅いSQLのみ)でログに出力する
+func ConnectSQLite(path string) *gorm.DB {
+	db, err := openSQLite(path, logging.NewGormLogger(logging.DefaultOptions()))
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
 
-	if env == "production" {
-		env = "production"
-	} else {
-		env = "development"
+// openSQLite はpathのSQLiteに接続し、モデルからテーブルを作成する
+// db/migrationsのSQLはMySQL用なので、SQLiteではAutoMigrateでスキーマを作る
+func openSQLite(path string, sqlLogger logger.Interface) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: sqlLogger,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	godotenv.Load(".env." + env)
+	if err := db.AutoMigrate(&model.Avatar{}, &model.Complaint{}, &idempotency.Record{}); err != nil {
+		return nil, pkgerrors.Wrap(err, "auto migration failed")
+	}
 
-	DBUSER := os.Getenv("guchitter_USER")
-	DBPASS := os.Getenv("guchitter_PASS")
-	DBNAME := os.Getenv("guchitter_DBNAME")
-	HOST := os.Getenv("guchitter_HOST")
-	DBPORT := os.Getenv("guchitter_PORT")
+	return db, nil
+}
 
+// DSN はMySQLの接続文字列を返す
+func (db DatabaseConfig) DSN() string {
 	// golang-migrate での migrate 実行時に、下記が設定されていないとエラーになる
 	// multiStatements=true
-	CONNECT := DBUSER + ":" + DBPASS + "@tcp(" + HOST + ":" + DBPORT + ")/" + DBNAME + "?charset=utf8mb4&parseTime=True&loc=Local&multiStatements=true"
-
-	return CONNECT
+	return db.User + ":" + db.Pass + "@tcp(" + db.Host + ":" + db.Port + ")/" + db.Name + "?charset=utf8mb4&parseTime=True&loc=Local&multiStatements=true"
 }
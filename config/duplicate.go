@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/backend-guchitter-app/usecase"
+)
+
+// 重複・類似のぐちの投稿の扱い
+// ぐちには投稿したユーザーがないため、同じアバターへの投稿どうしを比べる。別のユーザーの同じ投稿も重複とみなすため、デフォルトでは判定しない
+type DuplicateConfig struct {
+	// off(デフォルト), reject(409を返す), merge(元のぐちのduplicateCountを増やす)
+	Action string `yaml:"action"`
+	// 同じアバターのこの期間内の投稿と比べる
+	Window time.Duration `yaml:"window"`
+	// SimHash(64bit)の異なるビットがこの数以下なら類似とみなす。-1で完全一致のみ
+	MaxDistance int `yaml:"maxDistance"`
+}
+
+func defaultDuplicateConfig() DuplicateConfig {
+	return DuplicateConfig{
+		Action: usecase.DuplicateActionOff,
+		Window: time.Hour,
+		// 数文字の違いは概ね10以下、無関係なテキストは20以上になる
+		MaxDistance: 10,
+	}
+}
+
+// Policy はusecase.NewComplaintUseCaseに渡す判定方法を返す
+func (c DuplicateConfig) Policy() usecase.DuplicatePolicy {
+	return usecase.DuplicatePolicy{
+		Action:      c.Action,
+		Window:      c.Window,
+		MaxDistance: c.MaxDistance,
+	}
+}
+
+func (c DuplicateConfig) validate() []string {
+	problems := []string{}
+	switch c.Action {
+	case usecase.DuplicateActionOff, usecase.DuplicateActionReject, usecase.DuplicateActionMerge:
+	default:
+		problems = append(problems, fmt.Sprintf("guchitter_DUPLICATE_ACTION: must be %s, %s or %s, got %q", usecase.DuplicateActionOff, usecase.DuplicateActionReject, usecase.DuplicateActionMerge, c.Action))
+	}
+	if c.Action != usecase.DuplicateActionOff && c.Window <= 0 {
+		problems = append(problems, "guchitter_DUPLICATE_WINDOW: must be positive")
+	}
+	if c.MaxDistance < -1 || c.MaxDistance > 64 {
+		problems = append(problems, fmt.Sprintf("guchitter_DUPLICATE_MAX_DISTANCE: must be between -1 and 64, got %d", c.MaxDistance))
+	}
+	return problems
+}
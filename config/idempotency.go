@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Idempotency-Keyの扱い
+type IdempotencyConfig struct {
+	// 処理が終わったレスポンスを保存する期間
+	TTL time.Duration `yaml:"ttl"`
+	// 処理中のキーの予約の期限。処理中にプロセスが落ちた場合、この期間が過ぎると同じキーで再試行できる
+	LockTimeout time.Duration `yaml:"lockTimeout"`
+}
+
+func defaultIdempotencyConfig() IdempotencyConfig {
+	return IdempotencyConfig{
+		TTL:         24 * time.Hour,
+		LockTimeout: time.Minute,
+	}
+}
+
+// validate はrequestTimeout(guchitter_REQUEST_TIMEOUT)も使って検証する
+// 処理中に予約が切れると、再送されたリクエストも処理してしまうため
+func (c IdempotencyConfig) validate(requestTimeout time.Duration) []string {
+	problems := []string{}
+	if c.TTL <= 0 {
+		problems = append(problems, "guchitter_IDEMPOTENCY_TTL: must be positive")
+	}
+	if c.LockTimeout <= 0 || (requestTimeout > 0 && c.LockTimeout <= requestTimeout) {
+		problems = append(problems, fmt.Sprintf("guchitter_IDEMPOTENCY_LOCK_TIMEOUT: must be positive and longer than guchitter_REQUEST_TIMEOUT (%s), got %s", requestTimeout, c.LockTimeout))
+	}
+	if c.TTL > 0 && c.LockTimeout > c.TTL {
+		problems = append(problems, fmt.Sprintf("guchitter_IDEMPOTENCY_LOCK_TIMEOUT: %s must not exceed guchitter_IDEMPOTENCY_TTL (%s)", c.LockTimeout, c.TTL))
+	}
+	return problems
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// guchitter_IMAGE_STORE に指定できる値
+const (
+	// guchitter_IMAGE_DIRのディレクトリに保存する
+	ImageStoreLocal = "local"
+	// S3互換のストレージに保存する
+	ImageStoreS3 = "s3"
+	// プロセス内のメモリに保存する(再起動で消える)
+	ImageStoreMemory = "memory"
+)
+
+// アップロードできるアバターの画像のバイト数の上限の上限。リクエストのボディをメモリに読み込むため
+const maxImageMaxBytes = 32 << 20
+
+// アバターの画像のアップロードの上限と保存先
+type ImageConfig struct {
+	// アップロードできる画像のバイト数の上限
+	MaxBytes int `yaml:"maxBytes"`
+	// local, s3, memory のいずれか
+	Store string `yaml:"store"`
+	// Storeがlocalの場合に保存するディレクトリ
+	Dir string   `yaml:"dir"`
+	S3  S3Config `yaml:"s3"`
+}
+
+// S3互換のストレージの接続先と認証情報
+type S3Config struct {
+	// MinIOなどのURL。空の場合はRegionのAWSのエンドポイント
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"accessKeyId"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	// バケットをホスト名でなくパスに含める。MinIOなどで使う
+	PathStyle bool `yaml:"pathStyle"`
+}
+
+func defaultImageConfig() ImageConfig {
+	return ImageConfig{
+		MaxBytes: 5 << 20,
+		Store:    ImageStoreLocal,
+		Dir:      "uploads",
+		S3: S3Config{
+			Region: "us-east-1",
+		},
+	}
+}
+
+func (c ImageConfig) validate() []string {
+	problems := []string{}
+	if c.MaxBytes < 1 || c.MaxBytes > maxImageMaxBytes {
+		problems = append(problems, fmt.Sprintf("guchitter_IMAGE_MAX_BYTES: must be between 1 and %d, got %d", maxImageMaxBytes, c.MaxBytes))
+	}
+
+	switch c.Store {
+	case ImageStoreLocal:
+		if c.Dir == "" {
+			problems = append(problems, "guchitter_IMAGE_DIR: required when guchitter_IMAGE_STORE is local")
+		}
+	case ImageStoreS3:
+		for _, required := range []struct{ name, value string }{
+			{"guchitter_S3_REGION", c.S3.Region},
+			{"guchitter_S3_BUCKET", c.S3.Bucket},
+			{"guchitter_S3_ACCESS_KEY_ID", c.S3.AccessKeyID},
+			{"guchitter_S3_SECRET_ACCESS_KEY", c.S3.SecretAccessKey},
+		} {
+			if required.value == "" {
+				problems = append(problems, required.name+": required when guchitter_IMAGE_STORE is s3")
+			}
+		}
+		if c.S3.Endpoint != "" {
+			if u, err := url.Parse(c.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems = append(problems, fmt.Sprintf("guchitter_S3_ENDPOINT: must be an http(s) URL like http://localhost:9000, got %q", c.S3.Endpoint))
+			}
+		}
+	case ImageStoreMemory:
+	default:
+		problems = append(problems, fmt.Sprintf("guchitter_IMAGE_STORE: must be %s, %s or %s, got %q", ImageStoreLocal, ImageStoreS3, ImageStoreMemory, c.Store))
+	}
+	return problems
+}
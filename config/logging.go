@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/backend-guchitter-app/logging"
+	"github.com/bloom42/rz-go"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// ロガーの設定
+type LogConfig struct {
+	// debug, info, warn, error
+	Level string `yaml:"level"`
+	// json, console
+	Format string `yaml:"format"`
+	// Debug, Infoのログを1秒あたりこの件数まで出力する(0で間引かない)
+	SampleBurst int `yaml:"sampleBurst"`
+	// 上限を超えたDebug, Infoのログをこの件数に1件だけ出力する
+	SampleEvery int `yaml:"sampleEvery"`
+	// 値を伏せるフィールド名。logging.DefaultRedactKeysに追加される
+	RedactKeys []string `yaml:"redactKeys"`
+	// silent, error, warn, info(infoで全てのSQLをDebugで出力する)
+	SQLLevel string `yaml:"sqlLevel"`
+	// この時間を超えたSQLをWarnで出力する(0で出力しない)
+	SlowQueryThreshold time.Duration `yaml:"slowQueryThreshold"`
+}
+
+func defaultLogConfig() LogConfig {
+	defaults := logging.DefaultOptions()
+	return LogConfig{
+		Level:              "info",
+		Format:             defaults.Format,
+		SQLLevel:           "warn",
+		SlowQueryThreshold: defaults.SlowQueryThreshold,
+	}
+}
+
+// Options はlogging.Setupに渡す設定を返す
+// Loadで検証済みの設定に対して呼ぶこと。不正な項目はデフォルトの値になる
+func (c LogConfig) Options() logging.Options {
+	opts, _ := c.options()
+	return opts
+}
+
+func (c LogConfig) options() (logging.Options, error) {
+	opts := logging.DefaultOptions()
+
+	switch strings.ToLower(c.Level) {
+	case "debug":
+		opts.Level = rz.DebugLevel
+	case "info", "":
+		opts.Level = rz.InfoLevel
+	case "warn", "warning":
+		opts.Level = rz.WarnLevel
+	case "error":
+		opts.Level = rz.ErrorLevel
+	default:
+		return opts, fmt.Errorf("guchitter_LOG_LEVEL: must be debug, info, warn or error, got %q", c.Level)
+	}
+
+	switch c.Format {
+	case logging.FormatJSON, logging.FormatConsole:
+		opts.Format = c.Format
+	case "":
+	default:
+		return opts, fmt.Errorf("guchitter_LOG_FORMAT: must be %s or %s, got %q", logging.FormatJSON, logging.FormatConsole, c.Format)
+	}
+
+	if c.SampleBurst < 0 || c.SampleEvery < 0 {
+		return opts, fmt.Errorf("guchitter_LOG_SAMPLE_BURST, guchitter_LOG_SAMPLE_EVERY: must not be negative")
+	}
+	opts.SampleBurst = c.SampleBurst
+	opts.SampleEvery = c.SampleEvery
+
+	opts.RedactKeys = append(append([]string{}, logging.DefaultRedactKeys...), c.RedactKeys...)
+
+	switch strings.ToLower(c.SQLLevel) {
+	case "silent":
+		opts.SQLLevel = gormlogger.Silent
+	case "error":
+		opts.SQLLevel = gormlogger.Error
+	case "warn", "warning", "":
+		opts.SQLLevel = gormlogger.Warn
+	case "info":
+		opts.SQLLevel = gormlogger.Info
+	default:
+		return opts, fmt.Errorf("guchitter_SQL_LOG_LEVEL: must be silent, error, warn or info, got %q", c.SQLLevel)
+	}
+
+	if c.SlowQueryThreshold < 0 {
+		return opts, fmt.Errorf("guchitter_SLOW_QUERY_THRESHOLD: must not be negative")
+	}
+	opts.SlowQueryThreshold = c.SlowQueryThreshold
+
+	return opts, nil
+}
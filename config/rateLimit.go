@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/backend-guchitter-app/ratelimit"
+	"github.com/backend-guchitter-app/usecase"
+)
+
+// リクエスト数の上限
+type RateLimitConfig struct {
+	// ルートごとの指定がない場合の上限。"10/1m"の形式、noneで制限なし
+	Default ratelimit.Limit `yaml:"default"`
+	// キーは"POST /v1/complaints"のようなメソッドとルートのパターン、または"complaints.create"のような操作の名前
+	// 操作の名前で指定すると、/v1, /v2, バージョンなしの別名のルートで1つの上限を共有する
+	Routes map[string]ratelimit.Limit `yaml:"routes"`
+	// 一括処理の項目数の上限。キーはRoutesと同じ。1回で送れる項目数(1000)以上の容量にする
+	Items map[string]ratelimit.Limit `yaml:"items"`
+}
+
+// ぐちの投稿の連投と、取り込みの繰り返しを防ぐデフォルトの上限
+func defaultRateLimitConfig() RateLimitConfig {
+	postComplaint := ratelimit.Limit{Requests: 10, Period: time.Minute}
+	importFile := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	return RateLimitConfig{
+		Routes: map[string]ratelimit.Limit{
+			// POST /v1/complaints, /v2/complaints, /complaints
+			"complaints.create": postComplaint,
+			// 一括登録(/v2/complaints:batch)と一括削除。1回で最大1000件を扱う
+			"POST /v2/complaints:method": postComplaint,
+			// 1回で最大32MiBのファイルを取り込む
+			"POST /import/complaints": importFile,
+			"POST /import/avatars":    importFile,
+		},
+		Items: map[string]ratelimit.Limit{
+			// 1回分の1000件を送った後は、1時間かけて回復する
+			"POST /v2/complaints:method": {Requests: usecase.MaxBatchSize, Period: time.Hour},
+		},
+	}
+}
+
+// parseRouteRateLimits は"complaints.create=10/1m,GET /v1/complaints=none"の形式の操作またはルートごとの上限を解釈する
+// nameはエラーメッセージに使う環境変数の名前
+func parseRouteRateLimits(name, v string) (map[string]ratelimit.Limit, error) {
+	limits := map[string]ratelimit.Limit{}
+	for _, entry := range strings.Split(v, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		fields := strings.Fields(route)
+		if !ok || len(fields) < 1 || len(fields) > 2 {
+			return nil, fmt.Errorf("%s: %q must be \"METHOD /path=requests/period\" or \"action=requests/period\"", name, entry)
+		}
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if len(fields) == 1 {
+			limits[fields[0]] = limit
+			continue
+		}
+		limits[strings.ToUpper(fields[0])+" "+fields[1]] = limit
+	}
+	return limits, nil
+}
+
+func (c RateLimitConfig) validate() []string {
+	problems := []string{}
+	for route := range c.Routes {
+		if n := len(strings.Fields(route)); n < 1 || n > 2 {
+			problems = append(problems, fmt.Sprintf("guchitter_ROUTE_RATE_LIMITS: %q must be \"METHOD /path\" or an action like complaints.create", route))
+		}
+	}
+	for route, limit := range c.Items {
+		if n := len(strings.Fields(route)); n < 1 || n > 2 {
+			problems = append(problems, fmt.Sprintf("guchitter_ROUTE_ITEM_RATE_LIMITS: %q must be \"METHOD /path\" or an action like complaints.create", route))
+		}
+		// 容量が1回で送れる項目数より小さいと、最大の一括処理が常に429になる
+		if limit.Enabled() && limit.Requests < usecase.MaxBatchSize {
+			problems = append(problems, fmt.Sprintf("guchitter_ROUTE_ITEM_RATE_LIMITS: %q must allow at least %d items, got %s", route, usecase.MaxBatchSize, limit))
+		}
+	}
+	return problems
+}
+
+// validateTrustedProxy はproxyがIPアドレスまたはCIDRであることを確認する
+func validateTrustedProxy(proxy string) error {
+	if net.ParseIP(proxy) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return nil
+	}
+	return fmt.Errorf("%q must be an IP address or CIDR like 10.0.0.0/8", proxy)
+}
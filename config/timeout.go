@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parseRouteTimeouts は"GET /v1/complaints=3s,POST /v2/complaints=5s"の形式のルートごとの上限を解釈する
+func parseRouteTimeouts(v string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, entry := range strings.Split(v, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		fields := strings.Fields(route)
+		if !ok || len(fields) != 2 {
+			return nil, fmt.Errorf("guchitter_ROUTE_TIMEOUTS: %q must be \"METHOD /path=duration\"", entry)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("guchitter_ROUTE_TIMEOUTS: invalid duration %q for %s", value, route)
+		}
+		timeouts[strings.ToUpper(fields[0])+" "+fields[1]] = d
+	}
+	return timeouts, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/backend-guchitter-app/tracing"
+)
+
+// トレースの設定
+type TraceConfig struct {
+	// none, stdout, otlp
+	Exporter string `yaml:"exporter"`
+	// OTLPの送信先(host:port)。空の場合はOTEL_EXPORTER_OTLP_ENDPOINTまたはlocalhost:4318
+	Endpoint string `yaml:"endpoint"`
+	// OTLPをTLSなしで送る
+	Insecure bool `yaml:"insecure"`
+	// 記録するトレースの割合(0〜1)
+	SampleRatio float64 `yaml:"sampleRatio"`
+	ServiceName string  `yaml:"serviceName"`
+}
+
+func defaultTraceConfig() TraceConfig {
+	defaults := tracing.DefaultOptions()
+	return TraceConfig{
+		Exporter:    defaults.Exporter,
+		SampleRatio: defaults.SampleRatio,
+		ServiceName: defaults.ServiceName,
+	}
+}
+
+// Options はtracing.Setupに渡す設定を返す
+// サービスのバージョンと環境は呼び出し側で設定する
+func (c TraceConfig) Options() tracing.Options {
+	opts := tracing.DefaultOptions()
+	opts.Exporter = c.Exporter
+	opts.Endpoint = c.Endpoint
+	opts.Insecure = c.Insecure
+	opts.SampleRatio = c.SampleRatio
+	if c.ServiceName != "" {
+		opts.ServiceName = c.ServiceName
+	}
+	return opts
+}
+
+func (c TraceConfig) validate() []string {
+	problems := []string{}
+	switch c.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		problems = append(problems, fmt.Sprintf("guchitter_TRACE_EXPORTER: must be %s, %s or %s, got %q", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP, c.Exporter))
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		problems = append(problems, fmt.Sprintf("guchitter_TRACE_SAMPLE_RATIO: must be between 0 and 1, got %v", c.SampleRatio))
+	}
+	return problems
+}
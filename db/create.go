@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// 000001_create_schema.up.sql
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_.+\.(up|down)\.sql$`)
+	migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// createMigration は次の連番で空のup/downファイルを作成する
+// golang-migrateの`migrate create -ext sql -seq`と同じ命名にする
+func createMigration(out *reporter, dir, name string) error {
+	if !migrationNamePattern.MatchString(name) {
+		return usageError{fmt.Sprintf("create: NAME must match %s, got %q", migrationNamePattern, name)}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	next := 1
+	for _, e := range entries {
+		m := migrationFilePattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		if v, _ := strconv.Atoi(m[1]); v >= next {
+			next = v + 1
+		}
+	}
+
+	files := []string{}
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", next, name, direction))
+		// 既存ファイルを上書きしない
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		files = append(files, path)
+	}
+
+	return out.write(report{Command: "create", OK: true, Message: "migration files created", Files: files})
+}
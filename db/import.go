@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"github.com/backend-guchitter-app/config"
+	"github.com/backend-guchitter-app/importer"
+	"github.com/backend-guchitter-app/infrastructure/fingerprint"
+	"github.com/backend-guchitter-app/infrastructure/persistence"
+	"github.com/backend-guchitter-app/infrastructure/sentiment"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/usecase"
+)
+
+// 進み具合を保存するファイルの拡張子。取り込むファイルと同じディレクトリに作る
+const progressSuffix = ".progress"
+
+// importFile はCSVまたはJSON Linesのファイルからぐちまたはアバターを取り込む
+// バッチを登録するたびに進み具合をFILE.progressに保存し、--resumeでその続きから再開する。最後まで取り込むと削除する
+func importFile(out *reporter, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.Usage = func() {}
+	format := fs.String("format", "", "csvまたはjsonl。省略時は拡張子(.jsonl, .ndjson)から判断し、それ以外はcsv")
+	dryRun := fs.Bool("dry-run", false, "検証のみ行い、DBは変更しない")
+	resume := fs.Bool("resume", false, "FILE.progressに保存した行の続きから再開する")
+	batchSize := fs.Int("batch-size", importer.DefaultBatchSize, "1つのトランザクションで登録する行数")
+	// 位置引数の後ろのフラグも受け付けるため、1つずつ読み進める
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return usageError{err.Error()}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) != 2 || (positional[0] != "complaints" && positional[0] != "avatars") {
+		return usageError{"import requires complaints|avatars and FILE"}
+	}
+	kind, path := positional[0], positional[1]
+
+	opts := importer.Options{Format: *format, DryRun: *dryRun, BatchSize: *batchSize}
+	if opts.Format == "" {
+		opts.Format = formatFromExt(path)
+	}
+	if err := importer.ValidateOptions(opts); err != nil {
+		return usageError{"import: " + err.Error()}
+	}
+	progressPath := path + progressSuffix
+	switch {
+	case *resume:
+		progress, err := readProgress(progressPath)
+		if err != nil {
+			return err
+		}
+		opts.ResumeAfter = progress.Line
+	case !opts.DryRun:
+		// 前回の途中から最初に戻ると、登録済みの行を二重に登録してしまう
+		if _, err := os.Stat(progressPath); err == nil {
+			return usageError{fmt.Sprintf("import: %s exists; pass --resume to continue, or delete it to start over", progressPath)}
+		}
+	}
+	// dry-runでは登録しないので、進み具合も保存しない
+	if !opts.DryRun {
+		opts.OnProgress = func(p importer.Progress) error {
+			return writeProgress(progressPath, p)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	db, err := config.Connect(cfg.Database, logging.NewGormLogger(cfg.Log.Options()))
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Ctrl-Cで止めても、登録済みのバッチまでの進み具合は残る
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var result *importer.Result
+	switch kind {
+	case "complaints":
+		cu := usecase.NewComplaintUseCase(persistence.NewComplaintPersistence(db), sentiment.NewLexiconAnalyzer(), fingerprint.NewSimHashFingerprinter(), cfg.Duplicates.Policy())
+		result, err = importer.Run(ctx, file, importer.Complaints(cu), opts)
+	default:
+		// 取り込みでは画像を扱わないので、画像の保存先は渡さない
+		au := usecase.NewAvatarUseCase(persistence.NewAvatarPersistence(db), nil, nil, cfg.Colors.Policy())
+		result, err = importer.Run(ctx, file, importer.Avatars(au), opts)
+	}
+	if err != nil {
+		if result != nil && result.Line > 0 && !opts.DryRun {
+			return pkgerrors.Wrapf(err, "stopped after line %d, rerun with --resume to continue", result.Line)
+		}
+		return err
+	}
+	if !opts.DryRun {
+		if err := os.Remove(progressPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	message := fmt.Sprintf("imported %s from %s", kind, path)
+	if opts.DryRun {
+		message = fmt.Sprintf("dry run, checked %s in %s", kind, path)
+	}
+	return out.write(report{
+		Command: "import",
+		OK:      true,
+		Message: message,
+		DryRun:  opts.DryRun,
+		Files:   []string{path},
+		Counts: map[string]int{
+			"line":      result.Line,
+			"succeeded": result.Succeeded,
+			"failed":    result.Failed,
+			"skipped":   result.Skipped,
+		},
+		Errors:        result.Errors,
+		ErrorsOmitted: result.ErrorsOmitted,
+	})
+}
+
+// formatFromExt はファイルの拡張子から形式を返す
+func formatFromExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return importer.FormatJSONL
+	default:
+		return importer.FormatCSV
+	}
+}
+
+// readProgress は保存した進み具合を読む。ファイルがなければ最初から取り込む
+func readProgress(path string) (importer.Progress, error) {
+	var progress importer.Progress
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return progress, nil
+	}
+	if err != nil {
+		return progress, err
+	}
+	if err := json.Unmarshal(body, &progress); err != nil {
+		return progress, pkgerrors.Wrapf(err, "error at parsing %s", path)
+	}
+	return progress, nil
+}
+
+// writeProgress は進み具合を一時ファイルに書いてから置き換える。途中で止まっても壊れたファイルを残さない
+func writeProgress(path string, progress importer.Progress) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := json.NewEncoder(tmp).Encode(progress); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -2,88 +2,305 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"strconv"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/pkg/errors"
+	pkgerrors "github.com/pkg/errors"
 
-	// "github.com/backend-guchitter-app/config"
 	"github.com/backend-guchitter-app/config"
 	"github.com/golang-migrate/migrate"
 	"github.com/golang-migrate/migrate/database/mysql"
+	"github.com/golang-migrate/migrate/source"
 	_ "github.com/golang-migrate/migrate/source/file"
 )
 
 const (
-	migrationFilePath = "file://./db/migrations/"
+	migrationDir      = "./db/migrations"
+	migrationFilePath = "file://" + migrationDir + "/"
 )
 
+// 終了コード
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const usage = `usage: go run ./db [--json] <command> [args]
+
+commands:
+  status              現在のバージョンと各Migrationの適用状況を表示する
+  up [N] [--dry-run]  未適用のMigrationを全て(NがあればN件)適用する
+  down [N] [--dry-run]
+                      適用済みのMigrationを全て(NがあればN件)戻す
+  goto V [--dry-run]  バージョンVまで適用または戻す
+  force V             dirtyを解消し、バージョンをVとして記録する(SQLは実行しない)
+  create NAME         空のMigrationファイル(up/down)を作成する
+  drop --confirm      全てのテーブルを削除する
+  seed [--env ENV] [--dir DIR] [--random N] [--rand-seed S]
+                      db/fixtures/<ENV>のFixtureを登録する(自然キーで冪等)
+                      --randomでランダムなぐちをN件追加する
+  import complaints|avatars FILE [--format csv|jsonl] [--dry-run] [--resume] [--batch-size N]
+                      CSVまたはJSON Linesのファイルを取り込む
+                      登録したバッチまでの進み具合をFILE.progressに保存し、--resumeで続きから再開する
+
+--dry-run は実行予定のSQLを表示するだけで、DBは変更しない
+--json は結果をJSONで出力する
+`
+
+// usageError はコマンドライン引数の誤り
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
 func main() {
+	global := flag.NewFlagSet("migration", flag.ContinueOnError)
+	jsonOutput := global.Bool("json", false, "結果をJSONで出力する")
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(exitUsage)
+	}
+
+	out := newReporter(os.Stdout, *jsonOutput)
+	args := global.Args()
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	err := run(out, args[0], args[1:])
 
-	// extract command line args
-	flag.Parse()
-	args := flag.Args()
-	for i := 0; i < len(args); i++ {
-		fmt.Printf("args[%d]: %v\n", i, args[i])
+	var uerr usageError
+	switch {
+	case err == nil:
+		os.Exit(exitOK)
+	case errors.As(err, &uerr):
+		out.fail(args[0], err)
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	default:
+		out.fail(args[0], err)
+		os.Exit(exitError)
 	}
+}
+
+// run はサブコマンドを実行する
+func run(out *reporter, command string, args []string) error {
+	// seed, importは独自のフラグを持つ
+	switch command {
+	case "seed":
+		return seed(out, args)
+	case "import":
+		return importFile(out, args)
+	}
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.Usage = func() {}
+	dryRun := fs.Bool("dry-run", false, "実行予定のSQLを表示するだけでDBは変更しない")
+	confirm := fs.Bool("confirm", false, "dropの実行を確認する")
+	// 位置引数の後ろのフラグも受け付けるため、1つずつ読み進める
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return usageError{err.Error()}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	switch command {
+	case "create":
+		if len(positional) != 1 {
+			return usageError{"create requires NAME"}
+		}
+		return createMigration(out, migrationDir, positional[0])
+	case "status":
+		return withMigrate(func(m *migrate.Migrate, src source.Driver) error {
+			return status(out, m, src)
+		})
+	case "up", "down":
+		n := 0
+		if len(positional) > 1 {
+			return usageError{command + " accepts at most one argument"}
+		}
+		if len(positional) == 1 {
+			var err error
+			if n, err = strconv.Atoi(positional[0]); err != nil || n < 1 {
+				return usageError{fmt.Sprintf("%s: N must be a positive integer, got %q", command, positional[0])}
+			}
+		}
+		return withMigrate(func(m *migrate.Migrate, src source.Driver) error {
+			return step(out, m, src, command, n, *dryRun)
+		})
+	case "goto":
+		if len(positional) != 1 {
+			return usageError{"goto requires V"}
+		}
+		v, err := strconv.ParseUint(positional[0], 10, 64)
+		if err != nil {
+			return usageError{fmt.Sprintf("goto: V must be a non-negative integer, got %q", positional[0])}
+		}
+		return withMigrate(func(m *migrate.Migrate, src source.Driver) error {
+			return gotoVersion(out, m, src, uint(v), *dryRun)
+		})
+	case "force":
+		if len(positional) != 1 {
+			return usageError{"force requires V"}
+		}
+		v, err := strconv.Atoi(positional[0])
+		if err != nil || v < -1 {
+			return usageError{fmt.Sprintf("force: V must be an integer >= -1, got %q", positional[0])}
+		}
+		return withMigrate(func(m *migrate.Migrate, src source.Driver) error {
+			if err := m.Force(v); err != nil {
+				return pkgerrors.Wrap(err, "error at m.Force()")
+			}
+			return out.done("force", m, "version forced")
+		})
+	case "drop":
+		if !*confirm {
+			return usageError{"drop deletes every table; pass --confirm to proceed"}
+		}
+		return withMigrate(func(m *migrate.Migrate, src source.Driver) error {
+			if err := m.Drop(); err != nil {
+				return pkgerrors.Wrap(err, "error at m.Drop()")
+			}
+			return out.done("drop", m, "all tables dropped")
+		})
+	default:
+		return usageError{fmt.Sprintf("unknown command %q", command)}
+	}
+}
 
-	m := newMigrate()
-	v, dirty, versionErr := m.Version()
-	if versionErr != nil {
-		fmt.Println(errors.Wrap(versionErr, "error at m.Version()"))
+// withMigrate はDBとMigrationファイルに接続してfnを実行する
+// 設定はDBの接続先のみを読み込むので、アプリケーションの設定(guchitter_FRONT_ORIGINなど)がなくても実行できる
+func withMigrate(fn func(m *migrate.Migrate, src source.Driver) error) error {
+	database, err := config.LoadDatabase()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("mysql", database.DSN())
+	if err != nil {
+		return pkgerrors.Wrap(err, "error at sql.Open()")
+	}
+	defer db.Close()
+
+	driver, err := mysql.WithInstance(db, &mysql.Config{})
+	if err != nil {
+		return pkgerrors.Wrap(err, "error at mysql.WithInstance()")
+	}
+
+	m, err := migrate.NewWithDatabaseInstance(migrationFilePath, "mysql", driver)
+	if err != nil {
+		return pkgerrors.Wrap(err, "error at migrate.NewWithDatabaseInstance()")
+	}
+
+	// dry-runとstatusのために、Migrationファイルは別途読めるようにしておく
+	src, err := source.Open(migrationFilePath)
+	if err != nil {
+		return pkgerrors.Wrap(err, "error at source.Open()")
+	}
+	defer src.Close()
+
+	return fn(m, src)
+}
+
+// currentVersion は適用済みのバージョンを返す。未適用の場合は-1
+func currentVersion(m *migrate.Migrate) (version int, dirty bool, err error) {
+	v, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return -1, false, nil
+	}
+	if err != nil {
+		return 0, false, pkgerrors.Wrap(err, "error at m.Version()")
+	}
+	return int(v), dirty, nil
+}
+
+// ensureClean はdirtyな場合にエラーを返す
+// 以前は自動でForceしていたが、失敗したSQLが途中まで適用されている可能性があるため人が確認する
+func ensureClean(m *migrate.Migrate) error {
+	v, dirty, err := currentVersion(m)
+	if err != nil {
+		return err
 	}
-	fmt.Println("version:", v)
 	if dirty {
-		fmt.Printf("version %d is dirty\n", v)
-		m.Force(int(v))
+		return fmt.Errorf("version %d is dirty: fix the schema by hand, then run 'force %d' (or 'force %d' if it was not applied)", v, v, v-1)
+	}
+	return nil
+}
+
+func step(out *reporter, m *migrate.Migrate, src source.Driver, direction string, n int, dryRun bool) error {
+	if err := ensureClean(m); err != nil {
+		return err
+	}
+
+	if dryRun {
+		current, _, err := currentVersion(m)
+		if err != nil {
+			return err
+		}
+		plan, err := planSteps(src, current, direction, n)
+		if err != nil {
+			return err
+		}
+		return out.plan(direction, current, plan)
 	}
 
-	// execute migration by command line args
 	var err error
-	if len(args) > 0 && args[0] == "up" {
+	switch {
+	case direction == "up" && n == 0:
 		err = m.Up()
-	} else if len(args) > 0 && args[0] == "down" {
+	case direction == "up":
+		err = m.Steps(n)
+	case n == 0:
 		err = m.Down()
-	} else {
-		fmt.Println("command line args[0] must be 'up' or 'down'")
-		panic("")
-	}
-	if err.Error() == "no change" {
-		fmt.Println("no change")
-	} else if err != nil {
-		panic(err)
-	} else {
-		fmt.Println("migration finished")
-		v, _, _ := m.Version()
-		fmt.Println("version:", v)
+	default:
+		err = m.Steps(-n)
 	}
+	return finish(out, m, direction, err)
 }
 
-func newMigrate() *migrate.Migrate {
-	dsn := config.GetDsn()
-	fmt.Println("dsn:", dsn)
-
-	db, openErr := sql.Open("mysql", dsn)
-	if openErr != nil {
-		fmt.Println(errors.Wrap(openErr, "error at sql.Open()"))
+func gotoVersion(out *reporter, m *migrate.Migrate, src source.Driver, target uint, dryRun bool) error {
+	if err := ensureClean(m); err != nil {
+		return err
 	}
 
-	// error at mysql.WithInstance(): Error 1045: Access denied for user 'nakajimahidenari'@'172.27.0.1' (using password: YES)
-	// →dsnがおかしい。環境変数「USER」が正しく読み込まれていない...?
-	driver, instanceErr := mysql.WithInstance(db, &mysql.Config{})
-	if instanceErr != nil {
-		fmt.Println(errors.Wrap(instanceErr, "error at mysql.WithInstance()"))
+	if dryRun {
+		current, _, err := currentVersion(m)
+		if err != nil {
+			return err
+		}
+		plan, err := planGoto(src, current, target)
+		if err != nil {
+			return err
+		}
+		return out.plan("goto", current, plan)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		migrationFilePath,
-		"mysql",
-		driver,
-	)
+	return finish(out, m, "goto", m.Migrate(target))
+}
+
+// finish はMigrationの結果を出力する
+// 変更がない場合(ErrNoChange)も成功として扱う
+func finish(out *reporter, m *migrate.Migrate, command string, err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return out.done(command, m, "no change")
+	}
 	if err != nil {
-		fmt.Println(errors.Wrap(err, "error at migrate.NewWithDatabaseInstance()"))
+		return err
 	}
-
-	return m
+	return out.done(command, m, "migration finished")
 }
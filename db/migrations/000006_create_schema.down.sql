@@ -0,0 +1,4 @@
+ALTER TABLE `complaints`
+ DROP `negativity`,
+ DROP `anger`,
+ DROP `sadness`;
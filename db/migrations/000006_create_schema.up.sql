@@ -0,0 +1,4 @@
+ALTER TABLE `complaints`
+ ADD `negativity` decimal(4,3) NOT NULL DEFAULT 0,
+ ADD `anger` decimal(4,3) NOT NULL DEFAULT 0,
+ ADD `sadness` decimal(4,3) NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE `complaints`
+ DROP `created_at`;
+ALTER TABLE `avatars`
+ DROP `created_at`;
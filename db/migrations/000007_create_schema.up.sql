@@ -0,0 +1,4 @@
+ALTER TABLE `complaints`
+ ADD `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE `avatars`
+ ADD `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP;
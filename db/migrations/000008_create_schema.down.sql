@@ -0,0 +1,5 @@
+ALTER TABLE `complaints`
+ DROP INDEX `idx_complaints_avatar_id_created_at`,
+ DROP `text_hash`,
+ DROP `sim_hash`,
+ DROP `duplicate_count`;
@@ -0,0 +1,5 @@
+ALTER TABLE `complaints`
+ ADD `text_hash` char(64) NOT NULL DEFAULT '',
+ ADD `sim_hash` bigint NOT NULL DEFAULT 0,
+ ADD `duplicate_count` int NOT NULL DEFAULT 0,
+ ADD INDEX `idx_complaints_avatar_id_created_at` (`avatar_id`, `created_at`);
@@ -0,0 +1,12 @@
+CREATE TABLE `idempotency_keys` (
+  `idempotency_key` char(64) NOT NULL,
+  `request_hash` char(64) NOT NULL,
+  `completed` tinyint(1) NOT NULL DEFAULT 0,
+  `status` int NOT NULL DEFAULT 0,
+  `content_type` varchar(255) NOT NULL DEFAULT '',
+  `body` mediumblob,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` timestamp NOT NULL,
+  PRIMARY KEY (`idempotency_key`),
+  INDEX `idx_idempotency_keys_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
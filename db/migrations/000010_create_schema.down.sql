@@ -0,0 +1,2 @@
+ALTER TABLE `avatars`
+ DROP `image_file`;
@@ -0,0 +1,2 @@
+ALTER TABLE `avatars`
+ ADD `image_file` varchar(64) NOT NULL DEFAULT '';
@@ -0,0 +1,38 @@
+// Package migrations はMigrationファイルをバイナリに埋め込み、最新のバージョンを返す
+// 起動中のアプリケーションが、DBに未適用のMigrationがないかを確認するために使う
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Latest はMigrationファイルの最大のバージョンを返す
+func Latest() (uint, error) {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, e := range entries {
+		// 000007_create_schema.up.sql のようなファイル名の先頭がバージョン
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: invalid version %q", e.Name(), prefix)
+		}
+		if uint(v) > latest {
+			latest = uint(v)
+		}
+	}
+	return latest, nil
+}
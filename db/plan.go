@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"github.com/golang-migrate/migrate/source"
+)
+
+// 実行予定(または適用状況)のMigration1件
+type plannedMigration struct {
+	Version    uint   `json:"version"`
+	Identifier string `json:"identifier"`
+	Direction  string `json:"direction,omitempty"`
+	Applied    *bool  `json:"applied,omitempty"`
+	SQL        string `json:"sql,omitempty"`
+}
+
+// sourceVersions はMigrationファイルのバージョンを昇順で返す
+func sourceVersions(src source.Driver) ([]uint, error) {
+	versions := []uint{}
+	v, err := src.First()
+	for err == nil {
+		versions = append(versions, v)
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, pkgerrors.Wrap(err, "error at reading migration source")
+	}
+	return versions, nil
+}
+
+// planSteps はup/downで実行されるMigrationを実行順に返す
+// nが0の場合は全件
+func planSteps(src source.Driver, current int, direction string, n int) ([]plannedMigration, error) {
+	versions, err := sourceVersions(src)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []uint{}
+	if direction == "up" {
+		for _, v := range versions {
+			if int(v) > current {
+				targets = append(targets, v)
+			}
+		}
+	} else {
+		for i := len(versions) - 1; i >= 0; i-- {
+			if int(versions[i]) <= current {
+				targets = append(targets, versions[i])
+			}
+		}
+	}
+
+	if n > 0 {
+		if n > len(targets) {
+			return nil, fmt.Errorf("%s %d: only %d migrations available", direction, n, len(targets))
+		}
+		targets = targets[:n]
+	}
+	return readMigrations(src, targets, direction)
+}
+
+// planGoto はgoto targetで実行されるMigrationを実行順に返す
+func planGoto(src source.Driver, current int, target uint) ([]plannedMigration, error) {
+	versions, err := sourceVersions(src)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := false
+	for _, v := range versions {
+		if v == target {
+			exists = true
+		}
+	}
+	if !exists {
+		return nil, fmt.Errorf("goto %d: no migration with that version", target)
+	}
+
+	targets := []uint{}
+	if int(target) >= current {
+		for _, v := range versions {
+			if int(v) > current && v <= target {
+				targets = append(targets, v)
+			}
+		}
+		return readMigrations(src, targets, "up")
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i] > target && int(versions[i]) <= current {
+			targets = append(targets, versions[i])
+		}
+	}
+	return readMigrations(src, targets, "down")
+}
+
+// statusList は全てのMigrationの適用状況を返す
+func statusList(src source.Driver, current int) ([]plannedMigration, error) {
+	versions, err := sourceVersions(src)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]plannedMigration, 0, len(versions))
+	for _, v := range versions {
+		_, identifier, err := readSQL(src, v, "up")
+		if err != nil {
+			return nil, err
+		}
+		applied := int(v) <= current
+		list = append(list, plannedMigration{Version: v, Identifier: identifier, Applied: &applied})
+	}
+	return list, nil
+}
+
+func readMigrations(src source.Driver, versions []uint, direction string) ([]plannedMigration, error) {
+	plan := make([]plannedMigration, 0, len(versions))
+	for _, v := range versions {
+		sql, identifier, err := readSQL(src, v, direction)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, plannedMigration{Version: v, Identifier: identifier, Direction: direction, SQL: sql})
+	}
+	return plan, nil
+}
+
+func readSQL(src source.Driver, version uint, direction string) (sql string, identifier string, err error) {
+	var r io.ReadCloser
+	if direction == "up" {
+		r, identifier, err = src.ReadUp(version)
+	} else {
+		r, identifier, err = src.ReadDown(version)
+	}
+	if err != nil {
+		return "", "", pkgerrors.Wrapf(err, "error at reading %s migration %d", direction, version)
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", pkgerrors.Wrapf(err, "error at reading %s migration %d", direction, version)
+	}
+	return string(body), identifier, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/database/stub"
+	"github.com/golang-migrate/migrate/source"
+)
+
+// newTestSource はバージョン1〜3のMigrationファイルを一時ディレクトリに作り、そのURLを返す
+func newTestSource(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, name := range []string{"avatar", "complaints", "sentiment"} {
+		files := map[string]string{
+			fmt.Sprintf("%d_%s.up.sql", i+1, name):   "CREATE " + name + ";",
+			fmt.Sprintf("%d_%s.down.sql", i+1, name): "DROP " + name + ";",
+		}
+		for file, sql := range files {
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(sql), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return "file://" + dir
+}
+
+func openTestSource(t *testing.T) source.Driver {
+	t.Helper()
+	src, err := source.Open(newTestSource(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { src.Close() })
+	return src
+}
+
+// versions はplanのバージョンと向きを"1 up"の形式で返す
+func versions(plan []plannedMigration) []string {
+	got := []string{}
+	for _, m := range plan {
+		got = append(got, fmt.Sprintf("%d %s", m.Version, m.Direction))
+	}
+	return got
+}
+
+func TestPlanSteps(t *testing.T) {
+	src := openTestSource(t)
+	tests := []struct {
+		name      string
+		current   int
+		direction string
+		n         int
+		want      []string
+		wantErr   string
+	}{
+		{name: "up all from none", current: -1, direction: "up", want: []string{"1 up", "2 up", "3 up"}},
+		{name: "up 1", current: 1, direction: "up", n: 1, want: []string{"2 up"}},
+		{name: "up at latest", current: 3, direction: "up", want: []string{}},
+		{name: "up more than available", current: 1, direction: "up", n: 5, wantErr: "up 5: only 2 migrations available"},
+		{name: "down 1", current: 3, direction: "down", n: 1, want: []string{"3 down"}},
+		{name: "down all", current: 2, direction: "down", want: []string{"2 down", "1 down"}},
+		{name: "down from none", current: -1, direction: "down", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planSteps(src, tt.current, tt.direction, tt.n)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("planSteps() error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planSteps() error = %v", err)
+			}
+			if got := versions(plan); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planSteps() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanGoto(t *testing.T) {
+	src := openTestSource(t)
+	tests := []struct {
+		name    string
+		current int
+		target  uint
+		want    []string
+		wantErr string
+	}{
+		{name: "forward from none", current: -1, target: 2, want: []string{"1 up", "2 up"}},
+		{name: "forward", current: 1, target: 3, want: []string{"2 up", "3 up"}},
+		{name: "backward", current: 3, target: 1, want: []string{"3 down", "2 down"}},
+		{name: "same version", current: 2, target: 2, want: []string{}},
+		{name: "unknown version", current: 1, target: 9, wantErr: "goto 9: no migration with that version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planGoto(src, tt.current, tt.target)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("planGoto() error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planGoto() error = %v", err)
+			}
+			if got := versions(plan); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planGoto() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDryRun はstubのDBで、dry-runの出力と、dirtyなバージョンで止まることを確認する
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		dirty   bool
+		run     func(out *reporter, m *migrate.Migrate, src source.Driver) error
+		want    string
+		wantErr string
+	}{
+		{
+			name: "up", version: 1,
+			run: func(out *reporter, m *migrate.Migrate, src source.Driver) error {
+				return step(out, m, src, "up", 1, true)
+			},
+			want: "up: dry run, 1 migrations would be applied\nversion: 1\n-- up 2 complaints\nCREATE complaints;\n",
+		},
+		{
+			name: "down", version: 2,
+			run: func(out *reporter, m *migrate.Migrate, src source.Driver) error {
+				return step(out, m, src, "down", 0, true)
+			},
+			want: "down: dry run, 2 migrations would be applied\nversion: 2\n-- down 2 complaints\nDROP complaints;\n-- down 1 avatar\nDROP avatar;\n",
+		},
+		{
+			name: "goto", version: 3,
+			run: func(out *reporter, m *migrate.Migrate, src source.Driver) error {
+				return gotoVersion(out, m, src, 3, true)
+			},
+			want: "goto: dry run, no change\nversion: 3\n",
+		},
+		{
+			name: "dirty", version: 2, dirty: true,
+			run: func(out *reporter, m *migrate.Migrate, src source.Driver) error {
+				return step(out, m, src, "up", 0, true)
+			},
+			wantErr: "version 2 is dirty: fix the schema by hand, then run 'force 2' (or 'force 1' if it was not applied)",
+		},
+		{
+			name: "dirty goto", version: 2, dirty: true,
+			run: func(out *reporter, m *migrate.Migrate, src source.Driver) error {
+				return gotoVersion(out, m, src, 1, true)
+			},
+			wantErr: "version 2 is dirty: fix the schema by hand, then run 'force 2' (or 'force 1' if it was not applied)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newTestSource(t)
+			driver, _ := stub.WithInstance(nil, &stub.Config{})
+			db := driver.(*stub.Stub)
+			db.CurrentVersion, db.IsDirty = tt.version, tt.dirty
+			m, err := migrate.NewWithDatabaseInstance(url, "stub", driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, err := source.Open(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer src.Close()
+
+			var buf bytes.Buffer
+			err = tt.run(newReporter(&buf, false), m, src)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q; want %q", got, tt.want)
+			}
+			// dry-runではSQLを実行しない
+			if len(db.MigrationSequence) != 0 || db.CurrentVersion != tt.version {
+				t.Errorf("dry run applied %v, version %d", db.MigrationSequence, db.CurrentVersion)
+			}
+		})
+	}
+}
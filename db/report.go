@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/backend-guchitter-app/importer"
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/source"
+)
+
+// コマンドの実行結果
+// --jsonの場合はこの構造体を1行のJSONで出力する
+type report struct {
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+	// 適用済みのバージョン。未適用の場合は-1
+	Version    *int               `json:"version,omitempty"`
+	Dirty      bool               `json:"dirty,omitempty"`
+	DryRun     bool               `json:"dryRun,omitempty"`
+	Migrations []plannedMigration `json:"migrations,omitempty"`
+	Files      []string           `json:"files,omitempty"`
+	Counts     map[string]int     `json:"counts,omitempty"`
+	// importで検証に失敗した行
+	Errors        []importer.LineError `json:"errors,omitempty"`
+	ErrorsOmitted int                  `json:"errorsOmitted,omitempty"`
+	Error         string               `json:"error,omitempty"`
+}
+
+type reporter struct {
+	w    io.Writer
+	json bool
+}
+
+func newReporter(w io.Writer, jsonOutput bool) *reporter {
+	return &reporter{w: w, json: jsonOutput}
+}
+
+func (r *reporter) write(rep report) error {
+	if r.json {
+		return json.NewEncoder(r.w).Encode(rep)
+	}
+
+	if rep.Error != "" {
+		fmt.Fprintf(r.w, "%s: error: %s\n", rep.Command, rep.Error)
+		return nil
+	}
+	if rep.Message != "" {
+		fmt.Fprintf(r.w, "%s: %s\n", rep.Command, rep.Message)
+	}
+	if rep.Version != nil {
+		if *rep.Version < 0 {
+			fmt.Fprintln(r.w, "version: none")
+		} else if rep.Dirty {
+			fmt.Fprintf(r.w, "version: %d (dirty)\n", *rep.Version)
+		} else {
+			fmt.Fprintf(r.w, "version: %d\n", *rep.Version)
+		}
+	}
+	for _, mig := range rep.Migrations {
+		switch {
+		case mig.Applied != nil && *mig.Applied:
+			fmt.Fprintf(r.w, "  [applied] %d %s\n", mig.Version, mig.Identifier)
+		case mig.Applied != nil:
+			fmt.Fprintf(r.w, "  [pending] %d %s\n", mig.Version, mig.Identifier)
+		default:
+			fmt.Fprintf(r.w, "-- %s %d %s\n%s\n", mig.Direction, mig.Version, mig.Identifier, mig.SQL)
+		}
+	}
+	for _, file := range rep.Files {
+		fmt.Fprintf(r.w, "  %s\n", file)
+	}
+	keys := make([]string, 0, len(rep.Counts))
+	for key := range rep.Counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(r.w, "  %s: %d\n", key, rep.Counts[key])
+	}
+	for _, e := range rep.Errors {
+		fmt.Fprintf(r.w, "  line %d: %s\n", e.Line, e.Message)
+	}
+	if rep.ErrorsOmitted > 0 {
+		fmt.Fprintf(r.w, "  ... %d more errors\n", rep.ErrorsOmitted)
+	}
+	return nil
+}
+
+// done は成功時の結果と現在のバージョンを出力する
+func (r *reporter) done(command string, m *migrate.Migrate, message string) error {
+	v, dirty, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	return r.write(report{Command: command, OK: true, Message: message, Version: &v, Dirty: dirty})
+}
+
+// plan はdry-runで実行予定のSQLを出力する
+func (r *reporter) plan(command string, current int, plan []plannedMigration) error {
+	message := fmt.Sprintf("dry run, %d migrations would be applied", len(plan))
+	if len(plan) == 0 {
+		message = "dry run, no change"
+	}
+	return r.write(report{Command: command, OK: true, Message: message, Version: &current, DryRun: true, Migrations: plan})
+}
+
+// status は現在のバージョンと各Migrationの適用状況を出力する
+func status(r *reporter, m *migrate.Migrate, src source.Driver) error {
+	current, dirty, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	list, err := statusList(src, current)
+	if err != nil {
+		return err
+	}
+	return r.write(report{Command: "status", OK: true, Version: &current, Dirty: dirty, Migrations: list})
+}
+
+func (r *reporter) fail(command string, err error) {
+	r.write(report{Command: command, OK: false, Error: err.Error()})
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"github.com/backend-guchitter-app/config"
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/service"
+	"github.com/backend-guchitter-app/infrastructure/fingerprint"
+	"github.com/backend-guchitter-app/infrastructure/sentiment"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/usecase"
+)
+
+const (
+	fixtureDir = "./db/fixtures"
+	// ランダム生成時のINSERTの単位
+	seedBatchSize = 100
+)
+
+// Fixtureファイルの内容
+// YAMLとJSONのどちらでも同じ形で書ける
+type fixture struct {
+	Avatars    []avatarFixture    `yaml:"avatars" json:"avatars"`
+	Complaints []complaintFixture `yaml:"complaints" json:"complaints"`
+}
+
+// avatarNameが自然キー
+type avatarFixture struct {
+	AvatarName string `yaml:"avatarName" json:"avatarName"`
+	AvatarText string `yaml:"avatarText" json:"avatarText"`
+	ImageUrl   string `yaml:"imageUrl" json:"imageUrl"`
+	Color      string `yaml:"color" json:"color"`
+}
+
+// avatarNameとcomplaintTextの組が自然キー
+// アバターのIDは環境ごとに異なるため名前で参照する
+type complaintFixture struct {
+	AvatarName    string `yaml:"avatarName" json:"avatarName"`
+	ComplaintText string `yaml:"complaintText" json:"complaintText"`
+}
+
+// seed はFixtureを読み込み、アバターとぐちを登録する
+// 何度実行しても同じ状態になる(ランダム生成を除く)
+func seed(out *reporter, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.Usage = func() {}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	env := fs.String("env", cfg.Env, "読み込むFixtureの環境(db/fixtures/<env>)")
+	dir := fs.String("dir", fixtureDir, "Fixtureのルートディレクトリ")
+	random := fs.Int("random", 0, "Fixtureに加えてランダムなぐちをN件登録する(負荷試験用)")
+	randSeed := fs.Int64("rand-seed", 0, "ランダム生成のシード。0の場合は現在時刻")
+	if err := fs.Parse(args); err != nil {
+		return usageError{err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return usageError{fmt.Sprintf("seed: unexpected argument %q", fs.Arg(0))}
+	}
+	if *random < 0 {
+		return usageError{"seed: --random must not be negative"}
+	}
+
+	// Fixtureは環境ごとに用意したものだけを使う。productionなどにはないので、--envで指定する
+	if _, err := os.Stat(filepath.Join(*dir, *env)); err != nil {
+		envs, _ := fixtureEnvs(*dir)
+		return fmt.Errorf("no fixtures for env %q in %s (available: %s)", *env, *dir, strings.Join(envs, ", "))
+	}
+	fx, files, err := loadFixtures(filepath.Join(*dir, *env))
+	if err != nil {
+		return err
+	}
+
+	db, err := config.Connect(cfg.Database, logging.NewGormLogger(cfg.Log.Options()))
+	if err != nil {
+		return err
+	}
+	analyzer := sentiment.NewLexiconAnalyzer()
+	fingerprinter := fingerprint.NewSimHashFingerprinter()
+	counts := map[string]int{}
+
+	if err := seedFixtures(db, cfg.Colors.Policy(), analyzer, fingerprinter, fx, counts); err != nil {
+		return err
+	}
+
+	if *random > 0 {
+		if *randSeed == 0 {
+			*randSeed = time.Now().UnixNano()
+		}
+		if err := seedRandomComplaints(db, analyzer, fingerprinter, *random, rand.New(rand.NewSource(*randSeed)), counts); err != nil {
+			return err
+		}
+	}
+
+	return out.write(report{
+		Command: "seed",
+		OK:      true,
+		Message: fmt.Sprintf("seeded %s fixtures", *env),
+		Files:   files,
+		Counts:  counts,
+	})
+}
+
+// fixtureEnvs はdir直下にあるFixtureの環境(ディレクトリ)の名前を返す
+func fixtureEnvs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	envs := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			envs = append(envs, e.Name())
+		}
+	}
+	return envs, nil
+}
+
+// seedFixtures はfxのアバターとぐちを1つのトランザクションで登録し、件数をcountsに数える
+func seedFixtures(db *gorm.DB, colors usecase.ColorPolicy, analyzer service.SentimentAnalyzer, fingerprinter service.Fingerprinter, fx fixture, counts map[string]int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := seedAvatars(tx, colors, fx.Avatars, counts); err != nil {
+			return err
+		}
+		return seedComplaints(tx, analyzer, fingerprinter, fx.Complaints, counts)
+	})
+}
+
+// loadFixtures はdir直下の*.yaml, *.yml, *.jsonをファイル名順に読み込んでまとめる
+func loadFixtures(dir string) (fixture, []string, error) {
+	all := fixture{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return all, nil, pkgerrors.Wrapf(err, "error at reading fixture directory %s", dir)
+	}
+	names := []string{}
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	files := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return all, nil, err
+		}
+
+		var fx fixture
+		if filepath.Ext(name) == ".json" {
+			err = json.Unmarshal(body, &fx)
+		} else {
+			err = yaml.Unmarshal(body, &fx)
+		}
+		if err != nil {
+			return all, nil, pkgerrors.Wrapf(err, "error at parsing fixture %s", path)
+		}
+
+		all.Avatars = append(all.Avatars, fx.Avatars...)
+		all.Complaints = append(all.Complaints, fx.Complaints...)
+		files = append(files, path)
+	}
+	return all, files, nil
+}
+
+// seedAvatars は同名のアバターがあれば更新し、なければ登録する
+// 色はAPIからの登録と同じくcolorsで検証し、#rrggbbにして保存する
+func seedAvatars(tx *gorm.DB, colors usecase.ColorPolicy, avatars []avatarFixture, counts map[string]int) error {
+	for _, fx := range avatars {
+		if fx.AvatarName == "" {
+			return fmt.Errorf("avatar fixture without avatarName: %+v", fx)
+		}
+		color, err := colors.NormalizeColor(fx.Color)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "avatar fixture %q", fx.AvatarName)
+		}
+		fx.Color = color
+
+		var existing model.Avatar
+		err = tx.Where("avatar_name = ?", fx.AvatarName).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			avatar := model.Avatar{
+				AvatarName: fx.AvatarName,
+				AvatarText: fx.AvatarText,
+				ImageUrl:   fx.ImageUrl,
+				Color:      fx.Color,
+			}
+			if err := tx.Create(&avatar).Error; err != nil {
+				return err
+			}
+			counts["avatarsCreated"]++
+		case err != nil:
+			return err
+		case existing.AvatarText == fx.AvatarText && existing.ImageUrl == fx.ImageUrl && existing.Color == fx.Color:
+			counts["avatarsUnchanged"]++
+		default:
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"avatar_text": fx.AvatarText,
+				"image_url":   fx.ImageUrl,
+				"color":       fx.Color,
+			}).Error; err != nil {
+				return err
+			}
+			counts["avatarsUpdated"]++
+		}
+	}
+	return nil
+}
+
+// seedComplaints は同じアバター・同じ本文のぐちがなければ登録する
+func seedComplaints(tx *gorm.DB, analyzer service.SentimentAnalyzer, fingerprinter service.Fingerprinter, complaints []complaintFixture, counts map[string]int) error {
+	avatarIds := map[string]int{}
+	for _, fx := range complaints {
+		id, ok := avatarIds[fx.AvatarName]
+		if !ok {
+			var avatar model.Avatar
+			if err := tx.Where("avatar_name = ?", fx.AvatarName).First(&avatar).Error; err != nil {
+				return pkgerrors.Wrapf(err, "complaint fixture refers to avatar %q", fx.AvatarName)
+			}
+			id = avatar.AvatarId
+			avatarIds[fx.AvatarName] = id
+		}
+
+		var n int64
+		if err := tx.Model(&model.Complaint{}).
+			Where("avatar_id = ? AND complaint_text = ?", id, fx.ComplaintText).
+			Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			counts["complaintsSkipped"]++
+			continue
+		}
+
+		complaint := model.Complaint{
+			ComplaintText: fx.ComplaintText,
+			AvatarId:      id,
+			Sentiment:     analyzer.Analyze(fx.ComplaintText),
+			Fingerprint:   fingerprinter.Fingerprint(fx.ComplaintText),
+		}
+		if err := tx.Create(&complaint).Error; err != nil {
+			return err
+		}
+		counts["complaintsCreated"]++
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/service"
+)
+
+// ランダムなぐちの組み立てに使う語句
+var (
+	randomWhen = []string{"", "今日も", "朝から", "また", "昨日の夜", "月曜から", "せっかくの休みに", "帰り道で"}
+	randomWho  = []string{"上司が", "電車が", "隣の席の人が", "スマホが", "天気が", "家族が", "取引先が", "パソコンが", "猫が", "自分が"}
+	randomWhat = []string{
+		"話を聞いてくれない",
+		"遅れてきてほんとにムカつく",
+		"うるさくてイライラする",
+		"急に動かなくなって最悪",
+		"雨でつらい",
+		"締め切りを勝手に変えた",
+		"約束を忘れていて悲しい",
+		"残業を押し付けてきた",
+		"失敗ばかりで落ち込む",
+		"やる気を出してくれない",
+		"めんどくさいことを言い出した",
+		"寂しそうにしている",
+	}
+	randomEnd = []string{"", "。", "!", "…", "。勘弁してくれ", "。もう無理"}
+)
+
+// randomComplaintText はランダムな日本語のぐちを1件作る
+func randomComplaintText(r *rand.Rand) string {
+	var b strings.Builder
+	b.WriteString(randomWhen[r.Intn(len(randomWhen))])
+	b.WriteString(randomWho[r.Intn(len(randomWho))])
+	b.WriteString(randomWhat[r.Intn(len(randomWhat))])
+	b.WriteString(randomEnd[r.Intn(len(randomEnd))])
+	return b.String()
+}
+
+// seedRandomComplaints は既存のアバターにランダムなぐちをn件登録する
+// 負荷試験用のため自然キーによる重複チェックはしない
+func seedRandomComplaints(db *gorm.DB, analyzer service.SentimentAnalyzer, fingerprinter service.Fingerprinter, n int, r *rand.Rand, counts map[string]int) error {
+	var avatarIds []int
+	if err := db.Model(&model.Avatar{}).Pluck("avatar_id", &avatarIds).Error; err != nil {
+		return err
+	}
+	if len(avatarIds) == 0 {
+		return fmt.Errorf("seed --random requires at least one avatar")
+	}
+
+	complaints := make([]model.Complaint, 0, n)
+	for i := 0; i < n; i++ {
+		text := randomComplaintText(r)
+		complaints = append(complaints, model.Complaint{
+			ComplaintText: text,
+			AvatarId:      avatarIds[r.Intn(len(avatarIds))],
+			Sentiment:     analyzer.Analyze(text),
+			Fingerprint:   fingerprinter.Fingerprint(text),
+		})
+	}
+
+	if err := db.CreateInBatches(complaints, seedBatchSize).Error; err != nil {
+		return err
+	}
+	counts["randomComplaintsCreated"] += n
+	return nil
+}
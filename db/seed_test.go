@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/backend-guchitter-app/config"
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/infrastructure/fingerprint"
+	"github.com/backend-guchitter-app/infrastructure/sentiment"
+)
+
+// TestSeedFixturesTwice は全ての環境のFixtureを2回登録し、2回目に何も変わらないことを確認する
+func TestSeedFixturesTwice(t *testing.T) {
+	root := filepath.Join("fixtures")
+	envs, err := fixtureEnvs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, env := range envs {
+		found[env] = true
+	}
+	if !found["development"] || !found["test"] {
+		t.Fatalf("fixture envs = %v; want development and test", envs)
+	}
+
+	for _, env := range envs {
+		t.Run(env, func(t *testing.T) {
+			fx, _, err := loadFixtures(filepath.Join(root, env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := config.ConnectSQLite(fmt.Sprintf("file:seed_%s?mode=memory&cache=shared", env))
+			t.Cleanup(func() {
+				if sqlDB, err := db.DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+			seedOnce := func() map[string]int {
+				counts := map[string]int{}
+				err := seedFixtures(db, config.Default().Colors.Policy(), sentiment.NewLexiconAnalyzer(), fingerprint.NewSimHashFingerprinter(), fx, counts)
+				if err != nil {
+					t.Fatalf("seedFixtures() error = %v", err)
+				}
+				return counts
+			}
+
+			first := seedOnce()
+			if first["avatarsCreated"] != len(fx.Avatars) || first["complaintsCreated"] != len(fx.Complaints) {
+				t.Errorf("first seed counts = %v; want %d avatars and %d complaints created", first, len(fx.Avatars), len(fx.Complaints))
+			}
+			second := seedOnce()
+			want := map[string]int{"avatarsUnchanged": len(fx.Avatars), "complaintsSkipped": len(fx.Complaints)}
+			if fmt.Sprint(second) != fmt.Sprint(want) {
+				t.Errorf("second seed counts = %v; want %v", second, want)
+			}
+
+			var avatars, complaints int64
+			db.Model(&model.Avatar{}).Count(&avatars)
+			db.Model(&model.Complaint{}).Count(&complaints)
+			if int(avatars) != len(fx.Avatars) || int(complaints) != len(fx.Complaints) {
+				t.Errorf("rows = %d avatars, %d complaints; want %d, %d", avatars, complaints, len(fx.Avatars), len(fx.Complaints))
+			}
+		})
+	}
+}
@@ -1,10 +1,10 @@
-// Package docs GENERATED BY SWAG; DO NOT EDIT
+// Package v1 GENERATED BY SWAG; DO NOT EDIT
 // This file was generated by swaggo/swag
-package docs
+package v1
 
 import "github.com/swaggo/swag"
 
-const docTemplate = `{
+const docTemplatev1 = `{
     "schemes": {{ marshal .Schemes }},
     "swagger": "2.0",
     "info": {
@@ -18,6 +18,7 @@ const docTemplate = `{
     "paths": {
         "/avatars": {
             "get": {
+                "description": "filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in\nfieldはavatarId, avatarName, color, createdAt, lastUpdate",
                 "produces": [
                     "application/json"
                 ],
@@ -25,6 +26,32 @@ const docTemplate = `{
                     "Avatars"
                 ],
                 "summary": "Avatarsを全件取得",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "アバター名",
+                        "name": "filter[avatarName]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の下限(例: -24h)",
+                        "name": "filter[lastUpdate][gte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の上限",
+                        "name": "filter[lastUpdate][lte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asia/Tokyo",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -59,6 +86,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Avatar"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -71,6 +104,9 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "422": {
+                        "description": "同じIdempotency-Keyを異なるボディで使った"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -79,13 +115,15 @@ const docTemplate = `{
         },
         "/avatars/between-time": {
             "get": {
+                "description": "GET /avatars?filter[lastUpdate][gte]=...\u0026filter[lastUpdate][lte]=... を使うこと\nfrom, toはRFC 3339、タイムゾーンなしの日時(tzで解釈)、相対指定(now, today, yesterday, -24h, -7d)を受け付ける",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Avatars"
                 ],
-                "summary": "更新日時がfrom, toの間のAvatarを返す",
+                "summary": "更新日時がfrom, toの間のAvatarを返す(非推奨)",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "string",
@@ -98,6 +136,12 @@ const docTemplate = `{
                         "description": "2022-11-28 0:00:00",
                         "name": "to",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asia/Tokyo",
+                        "name": "tz",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -113,6 +157,9 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -180,8 +227,122 @@ const docTemplate = `{
                 }
             }
         },
+        "/avatars/{id}/image": {
+            "get": {
+                "description": "?v=が現在の画像のハッシュと一致する場合は期限なくキャッシュでき、それ以外はETagで確認させる",
+                "produces": [
+                    "image/png",
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "Avatarのアップロードした画像を返す",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "medium",
+                        "description": "small, medium, large",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "画像のハッシュ(imageUrlに含まれる)",
+                        "name": "v",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "前回のETag",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "description": "PNG, JPEG, WebPを受け付け、形式はファイルの中身で判定する。中央を正方形に切り抜き、small(64px), medium(256px), large(512px)に縮小して保存する\nimageUrlは GET /avatars/{id}/image?v=\u003c画像のハッシュ\u003e になる",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "Avatarの画像をアップロードする",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "画像(PNG, JPEG, WebP。上限はguchitter_IMAGE_MAX_BYTES、デフォルトは5MB)",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "画像を更新したAvatar",
+                        "schema": {
+                            "$ref": "#/definitions/model.Avatar"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
         "/complaints": {
             "get": {
+                "description": "filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in\nfieldはcomplaintId, avatarId, negativity, anger, sadness, createdAt, lastUpdate\nminAngerはfilter[anger][gte]の省略形",
                 "produces": [
                     "application/json"
                 ],
@@ -189,6 +350,38 @@ const docTemplate = `{
                     "Complaints"
                 ],
                 "summary": "Complaintsを全件取得",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "filter[avatarId]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の下限(例: -24h)",
+                        "name": "filter[lastUpdate][gte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の上限",
+                        "name": "filter[lastUpdate][lte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "怒りスコアの下限(0〜1)",
+                        "name": "minAnger",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asia/Tokyo",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -208,6 +401,7 @@ const docTemplate = `{
                 }
             },
             "post": {
+                "description": "同じアバターの最近の投稿と同じ・似たテキストの場合、設定に応じて409を返すか、元のComplaintのduplicateCountを増やして返す",
                 "produces": [
                     "application/json"
                 ],
@@ -223,6 +417,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Complaint"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -235,6 +435,12 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "422": {
+                        "description": "同じIdempotency-Keyを異なるボディで使った"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -243,13 +449,15 @@ const docTemplate = `{
         },
         "/complaints/between-time": {
             "get": {
+                "description": "GET /complaints?filter[lastUpdate][gte]=...\u0026filter[lastUpdate][lte]=... を使うこと\nfrom, toはRFC 3339、タイムゾーンなしの日時(tzで解釈)、相対指定(now, today, yesterday, -24h, -7d)を受け付ける",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Complaints"
                 ],
-                "summary": "更新日時がfrom, toの間のComplaintを返す",
+                "summary": "更新日時がfrom, toの間のComplaintを返す(非推奨)",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "string",
@@ -262,6 +470,12 @@ const docTemplate = `{
                         "description": "2022-11-28 0:00:00",
                         "name": "to",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asia/Tokyo",
+                        "name": "tz",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -277,6 +491,9 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
+                    "404": {
+                        "description": "Not Found"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -361,40 +578,37 @@ const docTemplate = `{
                     "type": "string",
                     "example": "なのよ"
                 },
-                "imageUrl": {
+                "color": {
+                    "description": "#rrggbbに正規化して保存する。登録時はrgb()やCSSの色名も受け付ける",
                     "type": "string",
-                    "example": "https://hoge.com/fuga"
-                }
-            }
-        },
-        "model.Complaint": {
-            "type": "object",
-            "properties": {
-                "avatarId": {
-                    "type": "integer",
-                    "example": 1234567890
+                    "example": "#f6f6f6"
                 },
-                "avatarName": {
+                "createdAt": {
+                    "description": "登録日時・更新日時はサーバー側で付与する",
                     "type": "string",
-                    "example": "Nino"
+                    "example": "2022-11-27T00:00:00+09:00"
                 },
-                "avatarText": {
+                "imageUrl": {
                     "type": "string",
-                    "example": "なのよ"
+                    "example": "https://hoge.com/fuga"
                 },
-                "color": {
+                "lastUpdate": {
                     "type": "string",
-                    "example": "#f6f6f6"
+                    "example": "2022-11-27T00:00:00+09:00"
                 },
-                "imageUrl": {
-                    "type": "string",
-                    "example": "https://hoge.com/fuga"
+                "palette": {
+                    "description": "Colorから求めた配色。色がなければ省く",
+                    "$ref": "#/definitions/model.Palette"
                 }
             }
         },
         "model.Complaint": {
             "type": "object",
             "properties": {
+                "anger": {
+                    "type": "number",
+                    "example": 0.6
+                },
                 "avatarId": {
                     "type": "integer",
                     "example": 1
@@ -407,24 +621,94 @@ const docTemplate = `{
                 "complaintText": {
                     "type": "string",
                     "example": "勘弁してくれ!"
+                },
+                "createdAt": {
+                    "description": "登録日時・更新日時はサーバー側で付与する",
+                    "type": "string",
+                    "example": "2022-11-27T00:00:00+09:00"
+                },
+                "duplicateCount": {
+                    "description": "このぐちにまとめた重複・類似の投稿の数(\"+N more\")。0の場合は返さない",
+                    "type": "integer",
+                    "example": 2
+                },
+                "lastUpdate": {
+                    "type": "string",
+                    "example": "2022-11-27T00:00:00+09:00"
+                },
+                "negativity": {
+                    "type": "number",
+                    "example": 0.75
+                },
+                "sadness": {
+                    "type": "number",
+                    "example": 0.2
+                }
+            }
+        },
+        "model.Palette": {
+            "type": "object",
+            "properties": {
+                "backgroundAccessible": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "backgroundContrast": {
+                    "description": "フロントエンドの背景色とBaseのコントラスト比と、WCAGの非テキストの基準(3以上)を満たすか",
+                    "type": "number",
+                    "example": 3.23
+                },
+                "base": {
+                    "description": "正規化したアバターの色",
+                    "type": "string",
+                    "example": "#1e90ff"
+                },
+                "dark": {
+                    "type": "string",
+                    "example": "#125699"
+                },
+                "light": {
+                    "description": "Baseを白に寄せた色と黒に寄せた色。背景やホバーなどに使う",
+                    "type": "string",
+                    "example": "#78bcff"
+                },
+                "textColor": {
+                    "description": "Baseの上に置く文字の色(#000000か#ffffff)と、そのコントラスト比",
+                    "type": "string",
+                    "example": "#000000"
+                },
+                "textContrast": {
+                    "type": "number",
+                    "example": 6.48
+                },
+                "textLevel": {
+                    "description": "TextContrastのWCAGの達成基準。AAA(7以上), AA(4.5以上), AA-large(3以上、大きな文字のみ), fail",
+                    "type": "string",
+                    "enum": [
+                        "AAA",
+                        "AA",
+                        "AA-large",
+                        "fail"
+                    ],
+                    "example": "AA"
                 }
             }
         }
     }
 }`
 
-// SwaggerInfo holds exported Swagger Info so clients can modify it
-var SwaggerInfo = &swag.Spec{
+// SwaggerInfov1 holds exported Swagger Info so clients can modify it
+var SwaggerInfov1 = &swag.Spec{
 	Version:          "0.0.1",
 	Host:             "",
-	BasePath:         "",
+	BasePath:         "/v1",
 	Schemes:          []string{},
 	Title:            "gin-swagger guchitter",
 	Description:      "はじめてのswagger",
-	InfoInstanceName: "swagger",
-	SwaggerTemplate:  docTemplate,
+	InfoInstanceName: "v1",
+	SwaggerTemplate:  docTemplatev1,
 }
 
 func init() {
-	swag.Register(SwaggerInfo.InstanceName(), SwaggerInfo)
+	swag.Register(SwaggerInfov1.InstanceName(), SwaggerInfov1)
 }
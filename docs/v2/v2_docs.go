@@ -0,0 +1,1196 @@
+// Package v2 GENERATED BY SWAG; DO NOT EDIT
+// This file was generated by swaggo/swag
+package v2
+
+import "github.com/swaggo/swag"
+
+const docTemplatev2 = `{
+    "schemes": {{ marshal .Schemes }},
+    "swagger": "2.0",
+    "info": {
+        "description": "{{escape .Description}}",
+        "title": "{{.Title}}",
+        "contact": {},
+        "version": "{{.Version}}"
+    },
+    "host": "{{.Host}}",
+    "basePath": "{{.BasePath}}",
+    "paths": {
+        "/avatars": {
+            "get": {
+                "description": "filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in\nfieldはavatarId, avatarName, color, createdAt, lastUpdate",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "Avatarsをページングして取得",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "アバター名",
+                        "name": "filter[avatarName]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の下限(例: -24h)",
+                        "name": "filter[lastUpdate][gte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の上限",
+                        "name": "filter[lastUpdate][lte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asia/Tokyo",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "取得件数(1〜100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "取得開始位置",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "Avatarを一件登録する",
+                "parameters": [
+                    {
+                        "description": "Avatar",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Avatar"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "登録したAvatar",
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "同じIdempotency-Keyを異なるボディで使った"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/avatars/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "avatarIdで検索したAvatarを1件返す",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "avatarIdで指定したAvatarを1件削除する",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/avatars/{id}/image": {
+            "get": {
+                "description": "?v=が現在の画像のハッシュと一致する場合は期限なくキャッシュでき、それ以外はETagで確認させる",
+                "produces": [
+                    "image/png",
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "Avatarのアップロードした画像を返す",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "medium",
+                        "description": "small, medium, large",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "画像のハッシュ(imageUrlに含まれる)",
+                        "name": "v",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "前回のETag",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "PNG, JPEG, WebPを受け付け、形式はファイルの中身で判定する。中央を正方形に切り抜き、small(64px), medium(256px), large(512px)に縮小して保存する\nimageUrlは GET /v2/avatars/{id}/image?v=\u003c画像のハッシュ\u003e になる",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "Avatarの画像をアップロードする",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "画像(PNG, JPEG, WebP。上限はguchitter_IMAGE_MAX_BYTES、デフォルトは5MB)",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "画像を更新したAvatar",
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/avatars:batch": {
+            "post": {
+                "description": "1つのトランザクションで最大1000件登録し、項目ごとの結果をitemsと同じ順で返す。各項目はPOST /avatarsと同じく検証する",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Avatars"
+                ],
+                "summary": "Avatarsを一括登録する",
+                "parameters": [
+                    {
+                        "description": "登録するAvatars",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarBatchRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "全て登録した",
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarBatchResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "bestEffortで一部の項目が失敗した",
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarBatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "atomicで失敗した項目があり、何も登録しなかった",
+                        "schema": {
+                            "$ref": "#/definitions/v2.AvatarBatchResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "一括処理の項目数の上限を超えた",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/complaints": {
+            "get": {
+                "description": "filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in\nfieldはcomplaintId, avatarId, negativity, anger, sadness, createdAt, lastUpdate",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Complaints"
+                ],
+                "summary": "Complaintsをページングして取得",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "filter[avatarId]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の下限(例: -24h)",
+                        "name": "filter[lastUpdate][gte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "更新日時の上限",
+                        "name": "filter[lastUpdate][lte]",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Asia/Tokyo",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "取得件数(1〜100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "取得開始位置",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "同じアバターの最近の投稿と同じ・似たテキストの場合、設定に応じて409を返すか、元のComplaintのduplicateCountを増やして200で返す",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Complaints"
+                ],
+                "summary": "Complaintを一件登録する",
+                "parameters": [
+                    {
+                        "description": "Complaint",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Complaint"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "重複としてまとめた元のComplaint",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "登録したComplaint",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "同じIdempotency-Keyを異なるボディで使った"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/complaints/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Complaints"
+                ],
+                "summary": "avatarIdで検索したComplaintを1件返す",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "アバターID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Complaints"
+                ],
+                "summary": "complaintIdで指定したComplaintを1件削除する",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "愚痴ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/complaints:batch": {
+            "post": {
+                "description": "1つのトランザクションで最大1000件登録し、項目ごとの結果をitemsと同じ順で返す\n各項目はPOST /complaintsと同じく検証する。重複・類似の投稿はバッチ内の前の項目とも比べ、設定によらず409の項目にする",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Complaints"
+                ],
+                "summary": "Complaintsを一括登録する",
+                "parameters": [
+                    {
+                        "description": "登録するComplaints",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintBatchRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "全て登録した",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintBatchResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "bestEffortで一部の項目が失敗した",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintBatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "atomicで失敗した項目があり、何も登録しなかった",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ComplaintBatchResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "一括処理の項目数の上限を超えた",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/complaints:batchDelete": {
+            "post": {
+                "description": "最大1000件のIDをまとめて削除し、項目ごとの結果をidsと同じ順で返す",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Complaints"
+                ],
+                "summary": "Complaintsを一括削除する",
+                "parameters": [
+                    {
+                        "description": "削除するComplaintのID",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v2.BatchDeleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "全て削除した",
+                        "schema": {
+                            "$ref": "#/definitions/v2.BatchDeleteResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "bestEffortで存在しないIDがあった",
+                        "schema": {
+                            "$ref": "#/definitions/v2.BatchDeleteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "atomicで存在しないIDがあり、何も削除しなかった",
+                        "schema": {
+                            "$ref": "#/definitions/v2.BatchDeleteResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "一括処理の項目数の上限を超えた",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v2.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.Avatar": {
+            "type": "object",
+            "properties": {
+                "avatarId": {
+                    "type": "integer",
+                    "example": 1234567890
+                },
+                "avatarName": {
+                    "type": "string",
+                    "example": "Nino"
+                },
+                "avatarText": {
+                    "type": "string",
+                    "example": "なのよ"
+                },
+                "color": {
+                    "description": "#rrggbbに正規化して保存する。登録時はrgb()やCSSの色名も受け付ける",
+                    "type": "string",
+                    "example": "#f6f6f6"
+                },
+                "createdAt": {
+                    "description": "登録日時・更新日時はサーバー側で付与する",
+                    "type": "string",
+                    "example": "2022-11-27T00:00:00+09:00"
+                },
+                "imageUrl": {
+                    "type": "string",
+                    "example": "https://hoge.com/fuga"
+                },
+                "lastUpdate": {
+                    "type": "string",
+                    "example": "2022-11-27T00:00:00+09:00"
+                },
+                "palette": {
+                    "description": "Colorから求めた配色。色がなければ省く",
+                    "$ref": "#/definitions/model.Palette"
+                }
+            }
+        },
+        "model.Complaint": {
+            "type": "object",
+            "properties": {
+                "anger": {
+                    "type": "number",
+                    "example": 0.6
+                },
+                "avatarId": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "complaintId": {
+                    "description": "ID, CreatedAt, UpdatedAt, DeletedAt が付与される\n=\u003e Error 1054: Unknown column 'created_at' in 'field list'のエラー\ngorm.Model\nタグ` + "`" + `gorm:\"primaryKey\"` + "`" + `を付与。goの構造体は、複数のタグがある場合は半角スペースで区切って記載",
+                    "type": "integer",
+                    "example": 56
+                },
+                "complaintText": {
+                    "type": "string",
+                    "example": "勘弁してくれ!"
+                },
+                "createdAt": {
+                    "description": "登録日時・更新日時はサーバー側で付与する",
+                    "type": "string",
+                    "example": "2022-11-27T00:00:00+09:00"
+                },
+                "duplicateCount": {
+                    "description": "このぐちにまとめた重複・類似の投稿の数(\"+N more\")。0の場合は返さない",
+                    "type": "integer",
+                    "example": 2
+                },
+                "lastUpdate": {
+                    "type": "string",
+                    "example": "2022-11-27T00:00:00+09:00"
+                },
+                "negativity": {
+                    "type": "number",
+                    "example": 0.75
+                },
+                "sadness": {
+                    "type": "number",
+                    "example": 0.2
+                }
+            }
+        },
+        "model.Palette": {
+            "type": "object",
+            "properties": {
+                "backgroundAccessible": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "backgroundContrast": {
+                    "description": "フロントエンドの背景色とBaseのコントラスト比と、WCAGの非テキストの基準(3以上)を満たすか",
+                    "type": "number",
+                    "example": 3.23
+                },
+                "base": {
+                    "description": "正規化したアバターの色",
+                    "type": "string",
+                    "example": "#1e90ff"
+                },
+                "dark": {
+                    "type": "string",
+                    "example": "#125699"
+                },
+                "light": {
+                    "description": "Baseを白に寄せた色と黒に寄せた色。背景やホバーなどに使う",
+                    "type": "string",
+                    "example": "#78bcff"
+                },
+                "textColor": {
+                    "description": "Baseの上に置く文字の色(#000000か#ffffff)と、そのコントラスト比",
+                    "type": "string",
+                    "example": "#000000"
+                },
+                "textContrast": {
+                    "type": "number",
+                    "example": 6.48
+                },
+                "textLevel": {
+                    "description": "TextContrastのWCAGの達成基準。AAA(7以上), AA(4.5以上), AA-large(3以上、大きな文字のみ), fail",
+                    "type": "string",
+                    "enum": [
+                        "AAA",
+                        "AA",
+                        "AA-large",
+                        "fail"
+                    ],
+                    "example": "AA"
+                }
+            }
+        },
+        "v2.AvatarBatchItem": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.Avatar"
+                },
+                "error": {
+                    "$ref": "#/definitions/v2.ErrorBody"
+                },
+                "index": {
+                    "description": "リクエストのitemsでの位置",
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "description": "この項目を1件ずつ登録した場合のHTTPステータス",
+                    "type": "integer",
+                    "example": 201
+                }
+            }
+        },
+        "v2.AvatarBatchRequest": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Avatar"
+                    }
+                },
+                "mode": {
+                    "description": "atomic(省略時): 1件でも失敗すれば何も登録しない。bestEffort: 失敗した項目を除いて登録する",
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "bestEffort"
+                    ],
+                    "example": "atomic"
+                }
+            }
+        },
+        "v2.AvatarBatchResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v2.AvatarBatchItem"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/v2.BatchSummary"
+                }
+            }
+        },
+        "v2.AvatarList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Avatar"
+                    }
+                },
+                "page": {
+                    "$ref": "#/definitions/v2.Page"
+                }
+            }
+        },
+        "v2.AvatarResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.Avatar"
+                }
+            }
+        },
+        "v2.BatchDeleteItem": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/v2.ErrorBody"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "index": {
+                    "description": "リクエストのidsでの位置",
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "description": "この項目を1件ずつ削除した場合のHTTPステータス。存在しないIDは404",
+                    "type": "integer",
+                    "example": 204
+                }
+            }
+        },
+        "v2.BatchDeleteRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
+                },
+                "mode": {
+                    "description": "atomic(省略時): 存在しないIDがあれば何も削除しない。bestEffort: 存在するIDのみ削除する",
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "bestEffort"
+                    ],
+                    "example": "atomic"
+                }
+            }
+        },
+        "v2.BatchDeleteResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v2.BatchDeleteItem"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/v2.BatchSummary"
+                }
+            }
+        },
+        "v2.BatchSummary": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "v2.ComplaintBatchItem": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.Complaint"
+                },
+                "error": {
+                    "$ref": "#/definitions/v2.ErrorBody"
+                },
+                "index": {
+                    "description": "リクエストのitemsでの位置",
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "description": "この項目を1件ずつ登録した場合のHTTPステータス",
+                    "type": "integer",
+                    "example": 201
+                }
+            }
+        },
+        "v2.ComplaintBatchRequest": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Complaint"
+                    }
+                },
+                "mode": {
+                    "description": "atomic(省略時): 1件でも失敗すれば何も登録しない。bestEffort: 失敗した項目を除いて登録する",
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "bestEffort"
+                    ],
+                    "example": "atomic"
+                }
+            }
+        },
+        "v2.ComplaintBatchResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v2.ComplaintBatchItem"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/v2.BatchSummary"
+                }
+            }
+        },
+        "v2.ComplaintList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Complaint"
+                    }
+                },
+                "page": {
+                    "$ref": "#/definitions/v2.Page"
+                }
+            }
+        },
+        "v2.ComplaintResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.Complaint"
+                }
+            }
+        },
+        "v2.ErrorBody": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Not Found"
+                }
+            }
+        },
+        "v2.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/v2.ErrorBody"
+                }
+            }
+        },
+        "v2.Page": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "nextOffset": {
+                    "description": "次のページのoffset。次のページがない場合はnull",
+                    "type": "integer",
+                    "example": 20
+                },
+                "offset": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        }
+    }
+}`
+
+// SwaggerInfov2 holds exported Swagger Info so clients can modify it
+var SwaggerInfov2 = &swag.Spec{
+	Version:          "0.0.1",
+	Host:             "",
+	BasePath:         "/v2",
+	Schemes:          []string{},
+	Title:            "gin-swagger guchitter v2",
+	Description:      "はじめてのswagger(v2)。一覧はページング付きのエンベロープで返す",
+	InfoInstanceName: "v2",
+	SwaggerTemplate:  docTemplatev2,
+}
+
+func init() {
+	swag.Register(SwaggerInfov2.InstanceName(), SwaggerInfov2)
+}
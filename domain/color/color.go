@@ -0,0 +1,115 @@
+// Package color はCSSの色の指定の解釈と、WCAGのコントラスト比の計算を提供する
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RGB はsRGBの色
+type RGB struct {
+	R, G, B uint8
+}
+
+var (
+	White = RGB{255, 255, 255}
+	Black = RGB{0, 0, 0}
+)
+
+// Parse はCSSの色の指定を解釈する。大文字・小文字と前後の空白は区別しない
+// 受け付けるのは#rgb, #rrggbb, rgb(r, g, b)(0〜255または%、空白区切りも可)、CSSの色名(red, rebeccapurpleなど)
+// 透明度を含む指定(#rrggbbaa, rgba(), transparent)は受け付けない
+func Parse(s string) (RGB, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(v, "#"):
+		return parseHex(v[1:], s)
+	case strings.HasPrefix(v, "rgb(") && strings.HasSuffix(v, ")"):
+		return parseRGBFunc(v[len("rgb("):len(v)-1], s)
+	}
+	if c, ok := names[v]; ok {
+		return c, nil
+	}
+	return RGB{}, fmt.Errorf("unknown color %q", s)
+}
+
+func parseHex(hex, original string) (RGB, error) {
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return RGB{}, fmt.Errorf("invalid hex color %q", original)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid hex color %q", original)
+	}
+	return RGB{uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+}
+
+// parseRGBFunc はrgb()の括弧の中を解釈する。カンマ区切りと空白区切りのどちらも受け付ける
+func parseRGBFunc(args, original string) (RGB, error) {
+	var parts []string
+	if strings.Contains(args, ",") {
+		parts = strings.Split(args, ",")
+	} else {
+		parts = strings.Fields(args)
+	}
+	if len(parts) != 3 {
+		return RGB{}, fmt.Errorf("invalid rgb() color %q: must have 3 values", original)
+	}
+
+	var channels [3]uint8
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		scale := 1.0
+		if strings.HasSuffix(p, "%") {
+			p, scale = strings.TrimSuffix(p, "%"), 255.0/100
+		}
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil || math.IsNaN(f) {
+			return RGB{}, fmt.Errorf("invalid rgb() color %q", original)
+		}
+		// CSSと同じく範囲外の値は丸める
+		channels[i] = uint8(math.Round(math.Max(0, math.Min(255, f*scale))))
+	}
+	return RGB{channels[0], channels[1], channels[2]}, nil
+}
+
+// Hex は"#rrggbb"の形式で返す
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Luminance はWCAGの相対輝度(0〜1)を返す
+// https://www.w3.org/TR/WCAG21/#dfn-relative-luminance
+func (c RGB) Luminance() float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+// Contrast はa, bのWCAGのコントラスト比(1〜21)を返す。順序によらない
+// https://www.w3.org/TR/WCAG21/#dfn-contrast-ratio
+func Contrast(a, b RGB) float64 {
+	la, lb := a.Luminance(), b.Luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// Mix はaにbをt(0〜1)の割合で混ぜた色を返す。tが0ならa、1ならb
+func Mix(a, b RGB, t float64) RGB {
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+	return RGB{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B)}
+}
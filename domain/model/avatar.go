@@ -1,10 +1,20 @@
 package model
 
+import "time"
+
 // アバター
 type Avatar struct {
 	AvatarId   int    `json:"avatarId" example:"1234567890" gorm:"primaryKey"`
 	AvatarName string `json:"avatarName" example:"Nino"`
 	AvatarText string `json:"avatarText" example:"なのよ"`
 	ImageUrl   string `json:"imageUrl" example:"https://hoge.com/fuga"`
-	Color      string `json:"color" example:"#f6f6f6"`
+	// #rrggbbに正規化して保存する。登録時はrgb()やCSSの色名も受け付ける
+	Color string `json:"color" example:"#f6f6f6"`
+	// Colorから求めた配色。色がなければ省く
+	Palette *Palette `json:"palette,omitempty" gorm:"-"`
+	// アップロードした画像のファイル名(画像のハッシュと拡張子)。画像がなければ空。APIでは返さない
+	ImageFile string `json:"-"`
+	// 登録日時・更新日時はサーバー側で付与する
+	CreatedAt  time.Time `json:"createdAt" example:"2022-11-27T00:00:00+09:00"`
+	LastUpdate time.Time `json:"lastUpdate" example:"2022-11-27T00:00:00+09:00" gorm:"autoUpdateTime"`
 }
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // ぐち
 type Complaint struct {
 	// ID, CreatedAt, UpdatedAt, DeletedAt が付与される
@@ -9,4 +11,13 @@ type Complaint struct {
 	ComplaintId   int    `json:"complaintId" example:"56" gorm:"primaryKey"`
 	ComplaintText string `json:"complaintText" example:"勘弁してくれ!"`
 	AvatarId      int    `json:"avatarId" example:"1"`
+	// 登録日時・更新日時はサーバー側で付与する
+	CreatedAt  time.Time `json:"createdAt" example:"2022-11-27T00:00:00+09:00"`
+	LastUpdate time.Time `json:"lastUpdate" example:"2022-11-27T00:00:00+09:00" gorm:"autoUpdateTime"`
+	// 登録時に算出される感情スコア。埋め込みなのでカラムはnegativity, anger, sadness
+	Sentiment
+	// 登録時に算出される重複判定用の指紋
+	Fingerprint
+	// このぐちにまとめた重複・類似の投稿の数("+N more")。0の場合は返さない
+	DuplicateCount int `json:"duplicateCount,omitempty" example:"2"`
 }
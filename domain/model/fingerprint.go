@@ -0,0 +1,18 @@
+package model
+
+import "math/bits"
+
+// ぐちのテキストの指紋。重複・類似の投稿の判定に使う
+// 埋め込みなのでカラムはtext_hash, sim_hash。APIでは返さない
+type Fingerprint struct {
+	// 正規化したテキストのハッシュ。一致すれば同じテキストとみなす
+	TextHash string `json:"-"`
+	// 正規化したテキストのSimHash(64bit)。ビットの違いが少ないほど似ている
+	// DBで扱えるよう符号付きで保持する
+	SimHash int64 `json:"-"`
+}
+
+// Distance はSimHashの異なるビットの数(ハミング距離)を返す
+func (f Fingerprint) Distance(other Fingerprint) int {
+	return bits.OnesCount64(uint64(f.SimHash ^ other.SimHash))
+}
@@ -0,0 +1,18 @@
+package model
+
+// アバターの色から求めた配色。フロントエンドはこのまま使う
+type Palette struct {
+	// 正規化したアバターの色
+	Base string `json:"base" example:"#1e90ff"`
+	// Baseを白に寄せた色と黒に寄せた色。背景やホバーなどに使う
+	Light string `json:"light" example:"#78bcff"`
+	Dark  string `json:"dark" example:"#125699"`
+	// Baseの上に置く文字の色(#000000か#ffffff)と、そのコントラスト比
+	TextColor    string  `json:"textColor" example:"#000000"`
+	TextContrast float64 `json:"textContrast" example:"6.48"`
+	// TextContrastのWCAGの達成基準。AAA(7以上), AA(4.5以上), AA-large(3以上、大きな文字のみ), fail
+	TextLevel string `json:"textLevel" example:"AA" enums:"AAA,AA,AA-large,fail"`
+	// フロントエンドの背景色とBaseのコントラスト比と、WCAGの非テキストの基準(3以上)を満たすか
+	BackgroundContrast   float64 `json:"backgroundContrast" example:"3.23"`
+	BackgroundAccessible bool    `json:"backgroundAccessible" example:"true"`
+}
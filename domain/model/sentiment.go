@@ -0,0 +1,9 @@
+package model
+
+// 感情スコア
+// 各値は0〜1の範囲で、1に近いほど感情が強い
+type Sentiment struct {
+	Negativity float64 `json:"negativity" example:"0.75"`
+	Anger      float64 `json:"anger" example:"0.6"`
+	Sadness    float64 `json:"sadness" example:"0.2"`
+}
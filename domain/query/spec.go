@@ -0,0 +1,104 @@
+package query
+
+import "fmt"
+
+// 比較演算子
+type Op string
+
+const (
+	OpEq  Op = "eq"
+	OpNe  Op = "ne"
+	OpGt  Op = "gt"
+	OpGte Op = "gte"
+	OpLt  Op = "lt"
+	OpLte Op = "lte"
+	OpIn  Op = "in"
+)
+
+// フィールドの値の型
+type Kind int
+
+const (
+	KindInt Kind = iota
+	KindFloat
+	KindString
+	KindTime
+)
+
+// 絞り込み条件1件
+// ValueはKindに応じてint, float64, string, time.Timeのいずれか(OpInの場合はそのスライス)
+type Condition struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+// 一覧取得の問い合わせ内容
+// Conditionsは全てAND条件として扱う
+// Limitが0の場合は件数を制限しない
+type Spec struct {
+	Conditions []Condition
+	Limit      int
+	Offset     int
+}
+
+// Page はLimit, Offsetを設定したSpecを返す
+func (s Spec) Page(limit, offset int) Spec {
+	s.Limit = limit
+	s.Offset = offset
+	return s
+}
+
+// Where は条件を追加したSpecを返す
+func (s Spec) Where(field string, op Op, value interface{}) Spec {
+	conditions := make([]Condition, len(s.Conditions), len(s.Conditions)+1)
+	copy(conditions, s.Conditions)
+	s.Conditions = append(conditions, Condition{Field: field, Op: op, Value: value})
+	return s
+}
+
+// 絞り込みに使えるフィールドと型の一覧
+// フィールド名はAPIのJSONと同じキャメルケース
+type Schema map[string]Kind
+
+var ComplaintSchema = Schema{
+	"complaintId": KindInt,
+	"avatarId":    KindInt,
+	"negativity":  KindFloat,
+	"anger":       KindFloat,
+	"sadness":     KindFloat,
+	"createdAt":   KindTime,
+	"lastUpdate":  KindTime,
+}
+
+var AvatarSchema = Schema{
+	"avatarId":   KindInt,
+	"avatarName": KindString,
+	"color":      KindString,
+	"createdAt":  KindTime,
+	"lastUpdate": KindTime,
+}
+
+// Allows はfieldに対してopが使えるかを返す
+// 文字列は大小比較をさせない
+func (s Schema) Allows(field string, op Op) error {
+	kind, ok := s[field]
+	if !ok {
+		return fmt.Errorf("unknown filter field %q", field)
+	}
+	switch op {
+	case OpEq, OpNe:
+		return nil
+	case OpIn:
+		if kind == KindInt || kind == KindString {
+			return nil
+		}
+	case OpGt, OpGte, OpLt, OpLte:
+		if kind != KindString {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown filter operator %q", op)
+	}
+	return fmt.Errorf("operator %q is not supported for field %q", op, field)
+}
@@ -1,11 +1,23 @@
 package repository
 
-import "github.com/backend-guchitter-app/domain/model"
+import (
+	"context"
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
+)
 
 type AvatarRepository interface {
-	FindAll() ([]*model.Avatar, error)
-	FindByAvatarId(id int) (*model.Avatar, error)
-	Create(avatar model.Avatar) (*model.Avatar, error)
-	FindBetweenTimestamp(from string, to string) ([]*model.Avatar, error)
-	DeleteByAvatarId(id int) error
+	FindAll(ctx context.Context) ([]*model.Avatar, error)
+	FindByAvatarId(ctx context.Context, id int) (*model.Avatar, error)
+	Create(ctx context.Context, avatar model.Avatar) (*model.Avatar, error)
+	Find(ctx context.Context, spec query.Spec) ([]*model.Avatar, error)
+	// Each はspecの条件に一致するAvatarを主キー順に1件ずつfnに渡す。全件をメモリに読み込まない
+	// fnがエラーを返した場合はそこで止め、そのエラーを返す
+	Each(ctx context.Context, spec query.Spec, fn func(*model.Avatar) error) error
+	DeleteByAvatarId(ctx context.Context, id int) error
+	// UpdateImage はアバターの画像のファイル名とURLを更新し、更新後のAvatarを返す。ない場合はnil
+	UpdateImage(ctx context.Context, id int, imageFile string, imageUrl string) (*model.Avatar, error)
+	// CreateBatch はavatarsを1つのトランザクションで登録し、同じ順で返す
+	// 1件でも失敗した場合は何も登録しない
+	CreateBatch(ctx context.Context, avatars []model.Avatar) ([]*model.Avatar, error)
 }
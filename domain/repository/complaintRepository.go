@@ -1,11 +1,26 @@
 package repository
 
-import "github.com/backend-guchitter-app/domain/model"
+import (
+	"context"
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
+)
 
 type ComplaintRepository interface {
-	FindAll() ([]*model.Complaint, error)
-	FindByAvatarId(id int) (*model.Complaint, error)
-	Create(complaint model.Complaint) (*model.Complaint, error)
-	FindBetweenTimestamp(from string, to string) ([]*model.Complaint, error)
-	DeleteByComplaintId(id int) error
+	FindAll(ctx context.Context) ([]*model.Complaint, error)
+	FindByAvatarId(ctx context.Context, id int) (*model.Complaint, error)
+	Create(ctx context.Context, complaint model.Complaint) (*model.Complaint, error)
+	Find(ctx context.Context, spec query.Spec) ([]*model.Complaint, error)
+	// Each はspecの条件に一致するComplaintを主キー順に1件ずつfnに渡す。全件をメモリに読み込まない
+	// fnがエラーを返した場合はそこで止め、そのエラーを返す
+	Each(ctx context.Context, spec query.Spec, fn func(*model.Complaint) error) error
+	DeleteByComplaintId(ctx context.Context, id int) error
+	// IncrementDuplicateCount はidのComplaintのDuplicateCountを1増やし、更新後のComplaintを返す
+	// 見つからない場合はnilを返す
+	IncrementDuplicateCount(ctx context.Context, id int) (*model.Complaint, error)
+	// CreateBatch はcomplaintsを1つのトランザクションで登録し、同じ順で返す
+	// 1件でも失敗した場合は何も登録しない
+	CreateBatch(ctx context.Context, complaints []model.Complaint) ([]*model.Complaint, error)
+	// DeleteByComplaintIds はidsのComplaintをまとめて削除する。存在しないIDは無視する
+	DeleteByComplaintIds(ctx context.Context, ids []int) error
 }
@@ -0,0 +1,528 @@
+// Package repositorytest はリポジトリの実装が満たすべき振る舞い(契約)のテストを提供する
+//
+// MySQL, SQLite, インメモリの各実装は、テストから以下のように呼び出して同じ契約を検証する
+//
+//	func TestComplaintRepository(t *testing.T) {
+//		repositorytest.ComplaintRepositoryContract(t, func(t *testing.T) repository.ComplaintRepository {
+//			return inmemory.NewComplaintRepository()
+//		})
+//	}
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
+	"github.com/backend-guchitter-app/domain/repository"
+)
+
+// 契約のテストで使うコンテキスト。キャンセルの検証では個別に作る
+var ctx = context.Background()
+
+// ComplaintRepositoryContract はComplaintRepositoryの契約を検証する
+// newRepoはサブテストごとに呼ばれ、空のリポジトリを返すこと
+func ComplaintRepositoryContract(t *testing.T, newRepo func(t *testing.T) repository.ComplaintRepository) {
+	t.Run("Create assigns id and timestamps", func(t *testing.T) {
+		repo := newRepo(t)
+		before := time.Now().Add(-time.Second)
+
+		created, err := repo.Create(ctx, model.Complaint{ComplaintText: "勘弁してくれ!", AvatarId: 1})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if created.ComplaintId == 0 {
+			t.Errorf("Create() did not assign ComplaintId")
+		}
+		if created.CreatedAt.Before(before) || created.LastUpdate.Before(before) {
+			t.Errorf("Create() timestamps = %v, %v; want after %v", created.CreatedAt, created.LastUpdate, before)
+		}
+
+		second, err := repo.Create(ctx, model.Complaint{ComplaintText: "もう無理", AvatarId: 1})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if second.ComplaintId <= created.ComplaintId {
+			t.Errorf("Create() ids = %d, %d; want increasing", created.ComplaintId, second.ComplaintId)
+		}
+	})
+
+	t.Run("Create keeps sentiment", func(t *testing.T) {
+		repo := newRepo(t)
+		sentiment := model.Sentiment{Negativity: 0.5, Anger: 0.25, Sadness: 0.125}
+
+		created := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "むかつく", AvatarId: 1, Sentiment: sentiment})
+
+		found, err := repo.Find(ctx, query.Spec{}.Where("complaintId", query.OpEq, created.ComplaintId))
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		if len(found) != 1 || found[0].Sentiment != sentiment {
+			t.Errorf("Find() = %+v; want sentiment %+v", found, sentiment)
+		}
+	})
+
+	t.Run("FindAll returns every complaint in id order", func(t *testing.T) {
+		repo := newRepo(t)
+		if all, err := repo.FindAll(ctx); err != nil || len(all) != 0 {
+			t.Fatalf("FindAll() on empty repository = %v, %v; want empty", all, err)
+		}
+
+		first := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1})
+		second := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "b", AvatarId: 2})
+
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll() error = %v", err)
+		}
+		assertComplaintIds(t, all, first.ComplaintId, second.ComplaintId)
+	})
+
+	t.Run("FindByAvatarId", func(t *testing.T) {
+		repo := newRepo(t)
+		first := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 7})
+		mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "b", AvatarId: 7})
+
+		found, err := repo.FindByAvatarId(ctx, 7)
+		if err != nil {
+			t.Fatalf("FindByAvatarId() error = %v", err)
+		}
+		if found == nil || found.ComplaintId != first.ComplaintId {
+			t.Errorf("FindByAvatarId(7) = %+v; want complaint %d", found, first.ComplaintId)
+		}
+
+		missing, err := repo.FindByAvatarId(ctx, 999)
+		if err != nil || missing != nil {
+			t.Errorf("FindByAvatarId(999) = %+v, %v; want nil, nil", missing, err)
+		}
+	})
+
+	t.Run("Find applies conditions", func(t *testing.T) {
+		repo := newRepo(t)
+		calm := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1, Sentiment: model.Sentiment{Anger: 0.1}})
+		angry := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "b", AvatarId: 2, Sentiment: model.Sentiment{Anger: 0.9}})
+		other := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "c", AvatarId: 3, Sentiment: model.Sentiment{Anger: 0.5}})
+
+		cases := []struct {
+			name string
+			spec query.Spec
+			want []int
+		}{
+			{"eq", query.Spec{}.Where("avatarId", query.OpEq, 2), []int{angry.ComplaintId}},
+			{"ne", query.Spec{}.Where("avatarId", query.OpNe, 2), []int{calm.ComplaintId, other.ComplaintId}},
+			{"gte", query.Spec{}.Where("anger", query.OpGte, 0.5), []int{angry.ComplaintId, other.ComplaintId}},
+			{"lt", query.Spec{}.Where("anger", query.OpLt, 0.5), []int{calm.ComplaintId}},
+			{"in", query.Spec{}.Where("avatarId", query.OpIn, []int{1, 3}), []int{calm.ComplaintId, other.ComplaintId}},
+			{"and", query.Spec{}.Where("anger", query.OpGt, 0.2).Where("avatarId", query.OpLte, 2), []int{angry.ComplaintId}},
+			{"time range", query.Spec{}.
+				Where("lastUpdate", query.OpGte, time.Now().Add(-time.Hour)).
+				Where("lastUpdate", query.OpLte, time.Now().Add(time.Hour)), []int{calm.ComplaintId, angry.ComplaintId, other.ComplaintId}},
+			{"future", query.Spec{}.Where("createdAt", query.OpGt, time.Now().Add(time.Hour)), []int{}},
+			{"limit", query.Spec{}.Page(2, 0), []int{calm.ComplaintId, angry.ComplaintId}},
+			{"limit and offset", query.Spec{}.Page(2, 2), []int{other.ComplaintId}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				found, err := repo.Find(ctx, tc.spec)
+				if err != nil {
+					t.Fatalf("Find() error = %v", err)
+				}
+				assertComplaintIds(t, found, tc.want...)
+			})
+		}
+	})
+
+	t.Run("Find rejects unknown fields", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.Find(ctx, query.Spec{}.Where("complaint_text; DROP TABLE complaints", query.OpEq, "x")); err == nil {
+			t.Errorf("Find() with unknown field returned no error")
+		}
+	})
+
+	t.Run("DeleteByComplaintId", func(t *testing.T) {
+		repo := newRepo(t)
+		deleted := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1})
+		kept := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "b", AvatarId: 1})
+
+		if err := repo.DeleteByComplaintId(ctx, deleted.ComplaintId); err != nil {
+			t.Fatalf("DeleteByComplaintId() error = %v", err)
+		}
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll() error = %v", err)
+		}
+		assertComplaintIds(t, all, kept.ComplaintId)
+
+		// 存在しないIDの削除はエラーにしない
+		if err := repo.DeleteByComplaintId(ctx, 999); err != nil {
+			t.Errorf("DeleteByComplaintId(999) error = %v; want nil", err)
+		}
+	})
+
+	t.Run("Create keeps fingerprint", func(t *testing.T) {
+		repo := newRepo(t)
+		fingerprint := model.Fingerprint{TextHash: "abc", SimHash: -42}
+		created := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1, Fingerprint: fingerprint})
+
+		found, err := repo.Find(ctx, query.Spec{}.Where("complaintId", query.OpEq, created.ComplaintId))
+		if err != nil {
+			t.Fatalf("Find() error = %v", err)
+		}
+		if len(found) != 1 || found[0].Fingerprint != fingerprint {
+			t.Errorf("Find() = %+v; want fingerprint %+v", found, fingerprint)
+		}
+	})
+
+	t.Run("IncrementDuplicateCount", func(t *testing.T) {
+		repo := newRepo(t)
+		original := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1})
+
+		for want := 1; want <= 2; want++ {
+			updated, err := repo.IncrementDuplicateCount(ctx, original.ComplaintId)
+			if err != nil {
+				t.Fatalf("IncrementDuplicateCount() error = %v", err)
+			}
+			if updated == nil || updated.ComplaintId != original.ComplaintId || updated.DuplicateCount != want {
+				t.Errorf("IncrementDuplicateCount() = %+v; want complaint %d with count %d", updated, original.ComplaintId, want)
+			}
+		}
+
+		missing, err := repo.IncrementDuplicateCount(ctx, 999)
+		if err != nil || missing != nil {
+			t.Errorf("IncrementDuplicateCount(999) = %+v, %v; want nil, nil", missing, err)
+		}
+	})
+
+	t.Run("CreateBatch assigns ids in order", func(t *testing.T) {
+		repo := newRepo(t)
+		existing := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1})
+
+		input := []model.Complaint{
+			{ComplaintText: "b", AvatarId: 1},
+			{ComplaintText: "c", AvatarId: 2, Sentiment: model.Sentiment{Anger: 0.5}},
+		}
+		created, err := repo.CreateBatch(ctx, input)
+		if err != nil {
+			t.Fatalf("CreateBatch() error = %v", err)
+		}
+		if len(created) != 2 || created[0].ComplaintText != "b" || created[1].ComplaintText != "c" || created[1].Anger != 0.5 {
+			t.Fatalf("CreateBatch() = %+v; want b, c in order", created)
+		}
+		if created[0].ComplaintId <= existing.ComplaintId || created[1].ComplaintId <= created[0].ComplaintId {
+			t.Errorf("CreateBatch() ids = %d, %d; want increasing after %d", created[0].ComplaintId, created[1].ComplaintId, existing.ComplaintId)
+		}
+		if created[0].CreatedAt.IsZero() {
+			t.Errorf("CreateBatch() did not assign CreatedAt")
+		}
+		if input[0].ComplaintId != 0 {
+			t.Errorf("CreateBatch() modified the input")
+		}
+
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll() error = %v", err)
+		}
+		assertComplaintIds(t, all, existing.ComplaintId, created[0].ComplaintId, created[1].ComplaintId)
+	})
+
+	t.Run("CreateBatch creates nothing on error", func(t *testing.T) {
+		repo := newRepo(t)
+		existing := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1})
+
+		// 2件目の主キーが重複する
+		_, err := repo.CreateBatch(ctx, []model.Complaint{
+			{ComplaintText: "b", AvatarId: 1},
+			{ComplaintId: existing.ComplaintId, ComplaintText: "c", AvatarId: 1},
+		})
+		if err == nil {
+			t.Fatalf("CreateBatch() with a duplicate primary key error = nil; want an error")
+		}
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll() error = %v", err)
+		}
+		assertComplaintIds(t, all, existing.ComplaintId)
+	})
+
+	t.Run("DeleteByComplaintIds", func(t *testing.T) {
+		repo := newRepo(t)
+		first := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1})
+		second := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "b", AvatarId: 1})
+		third := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "c", AvatarId: 1})
+
+		// 存在しないIDは無視する
+		if err := repo.DeleteByComplaintIds(ctx, []int{first.ComplaintId, third.ComplaintId, 999}); err != nil {
+			t.Fatalf("DeleteByComplaintIds() error = %v", err)
+		}
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll() error = %v", err)
+		}
+		assertComplaintIds(t, all, second.ComplaintId)
+	})
+
+	t.Run("Each passes matching complaints in id order", func(t *testing.T) {
+		repo := newRepo(t)
+		sentiment := model.Sentiment{Negativity: 0.5, Anger: 0.25, Sadness: 0.125}
+		first := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1, Sentiment: sentiment})
+		mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "b", AvatarId: 2})
+		third := mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "c", AvatarId: 1})
+
+		var got []*model.Complaint
+		err := repo.Each(ctx, query.Spec{}.Where("avatarId", query.OpEq, 1), func(c *model.Complaint) error {
+			got = append(got, c)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Each() error = %v", err)
+		}
+		assertComplaintIds(t, got, first.ComplaintId, third.ComplaintId)
+		if got[0].ComplaintText != "a" || got[0].Sentiment != sentiment {
+			t.Errorf("Each() first = %+v; want text and sentiment of %+v", got[0], first)
+		}
+	})
+
+	t.Run("Each stops at the error of fn", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "a", AvatarId: 1})
+		mustCreateComplaint(t, repo, model.Complaint{ComplaintText: "b", AvatarId: 1})
+		stop := errors.New("stop")
+
+		calls := 0
+		err := repo.Each(ctx, query.Spec{}, func(c *model.Complaint) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("Each() = %v after %d calls; want %v after 1 call", err, calls, stop)
+		}
+	})
+
+	t.Run("canceled context is an error", func(t *testing.T) {
+		repo := newRepo(t)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, err := repo.Find(canceled, query.Spec{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("Find() error = %v; want context.Canceled", err)
+		}
+		if _, err := repo.Create(canceled, model.Complaint{ComplaintText: "勘弁してくれ!", AvatarId: 1}); err == nil {
+			t.Errorf("Create() error = nil; want an error")
+		}
+		if all, err := repo.FindAll(ctx); err != nil || len(all) != 0 {
+			t.Errorf("FindAll() after canceled Create = %d complaints, %v; want none", len(all), err)
+		}
+	})
+}
+
+// AvatarRepositoryContract はAvatarRepositoryの契約を検証する
+// newRepoはサブテストごとに呼ばれ、空のリポジトリを返すこと
+func AvatarRepositoryContract(t *testing.T, newRepo func(t *testing.T) repository.AvatarRepository) {
+	t.Run("Create assigns id and timestamps", func(t *testing.T) {
+		repo := newRepo(t)
+		before := time.Now().Add(-time.Second)
+
+		created, err := repo.Create(ctx, model.Avatar{AvatarName: "Nino", AvatarText: "なのよ", Color: "#f6f6f6"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if created.AvatarId == 0 {
+			t.Errorf("Create() did not assign AvatarId")
+		}
+		if created.CreatedAt.Before(before) || created.LastUpdate.Before(before) {
+			t.Errorf("Create() timestamps = %v, %v; want after %v", created.CreatedAt, created.LastUpdate, before)
+		}
+	})
+
+	t.Run("FindByAvatarId", func(t *testing.T) {
+		repo := newRepo(t)
+		created := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Nino", AvatarText: "なのよ"})
+
+		found, err := repo.FindByAvatarId(ctx, created.AvatarId)
+		if err != nil {
+			t.Fatalf("FindByAvatarId() error = %v", err)
+		}
+		if found == nil || found.AvatarName != "Nino" || found.AvatarText != "なのよ" {
+			t.Errorf("FindByAvatarId() = %+v; want Nino", found)
+		}
+
+		missing, err := repo.FindByAvatarId(ctx, created.AvatarId+100)
+		if err != nil || missing != nil {
+			t.Errorf("FindByAvatarId(missing) = %+v, %v; want nil, nil", missing, err)
+		}
+	})
+
+	t.Run("Find applies conditions", func(t *testing.T) {
+		repo := newRepo(t)
+		nino := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Nino", AvatarText: "なのよ", Color: "#f6f6f6"})
+		miku := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Miku", AvatarText: "ですっ", Color: "#a7d8de"})
+
+		cases := []struct {
+			name string
+			spec query.Spec
+			want []int
+		}{
+			{"all", query.Spec{}, []int{nino.AvatarId, miku.AvatarId}},
+			{"eq string", query.Spec{}.Where("avatarName", query.OpEq, "Miku"), []int{miku.AvatarId}},
+			{"in string", query.Spec{}.Where("color", query.OpIn, []string{"#f6f6f6", "#000000"}), []int{nino.AvatarId}},
+			{"gt id", query.Spec{}.Where("avatarId", query.OpGt, nino.AvatarId), []int{miku.AvatarId}},
+			{"limit", query.Spec{}.Page(1, 1), []int{miku.AvatarId}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				found, err := repo.Find(ctx, tc.spec)
+				if err != nil {
+					t.Fatalf("Find() error = %v", err)
+				}
+				ids := make([]int, len(found))
+				for i, a := range found {
+					ids[i] = a.AvatarId
+				}
+				assertIds(t, ids, tc.want)
+			})
+		}
+	})
+
+	t.Run("Each passes matching avatars in id order", func(t *testing.T) {
+		repo := newRepo(t)
+		nino := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Nino", AvatarText: "なのよ", Color: "#f6f6f6"})
+		miku := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Miku", AvatarText: "ですっ", Color: "#a7d8de"})
+
+		var ids []int
+		err := repo.Each(ctx, query.Spec{}, func(a *model.Avatar) error {
+			ids = append(ids, a.AvatarId)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Each() error = %v", err)
+		}
+		assertIds(t, ids, []int{nino.AvatarId, miku.AvatarId})
+	})
+
+	t.Run("DeleteByAvatarId", func(t *testing.T) {
+		repo := newRepo(t)
+		created := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Nino", AvatarText: "なのよ"})
+
+		if err := repo.DeleteByAvatarId(ctx, created.AvatarId); err != nil {
+			t.Fatalf("DeleteByAvatarId() error = %v", err)
+		}
+		if found, err := repo.FindByAvatarId(ctx, created.AvatarId); err != nil || found != nil {
+			t.Errorf("FindByAvatarId() after delete = %+v, %v; want nil, nil", found, err)
+		}
+		if err := repo.DeleteByAvatarId(ctx, created.AvatarId); err != nil {
+			t.Errorf("DeleteByAvatarId() twice error = %v; want nil", err)
+		}
+	})
+
+	t.Run("UpdateImage", func(t *testing.T) {
+		repo := newRepo(t)
+		created := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Nino", ImageUrl: "https://hoge.com/nino"})
+
+		updated, err := repo.UpdateImage(ctx, created.AvatarId, "3fa9c1d2e4b5f6a7.png", "/v1/avatars/1/image?v=3fa9c1d2e4b5f6a7")
+		if err != nil {
+			t.Fatalf("UpdateImage() error = %v", err)
+		}
+		if updated == nil || updated.ImageFile != "3fa9c1d2e4b5f6a7.png" || updated.ImageUrl != "/v1/avatars/1/image?v=3fa9c1d2e4b5f6a7" || updated.AvatarName != "Nino" {
+			t.Fatalf("UpdateImage() = %+v; want the new image and the other fields kept", updated)
+		}
+		found, err := repo.FindByAvatarId(ctx, created.AvatarId)
+		if err != nil || found == nil || found.ImageFile != updated.ImageFile {
+			t.Errorf("FindByAvatarId() after UpdateImage = %+v, %v; want ImageFile %q", found, err, updated.ImageFile)
+		}
+
+		if missing, err := repo.UpdateImage(ctx, created.AvatarId+100, "x.png", "/x"); err != nil || missing != nil {
+			t.Errorf("UpdateImage() of a missing avatar = %+v, %v; want nil, nil", missing, err)
+		}
+	})
+
+	t.Run("CreateBatch", func(t *testing.T) {
+		repo := newRepo(t)
+		existing := mustCreateAvatar(t, repo, model.Avatar{AvatarName: "Nino", AvatarText: "なのよ"})
+
+		created, err := repo.CreateBatch(ctx, []model.Avatar{
+			{AvatarName: "Miku", AvatarText: "ですっ"},
+			{AvatarName: "Ichika", AvatarText: "だよね"},
+		})
+		if err != nil {
+			t.Fatalf("CreateBatch() error = %v", err)
+		}
+		if len(created) != 2 || created[0].AvatarName != "Miku" || created[1].AvatarName != "Ichika" {
+			t.Fatalf("CreateBatch() = %+v; want Miku, Ichika in order", created)
+		}
+		if created[0].AvatarId <= existing.AvatarId || created[1].AvatarId <= created[0].AvatarId {
+			t.Errorf("CreateBatch() ids = %d, %d; want increasing after %d", created[0].AvatarId, created[1].AvatarId, existing.AvatarId)
+		}
+
+		// 主キーが重複する場合は何も登録しない
+		if _, err := repo.CreateBatch(ctx, []model.Avatar{{AvatarName: "Yotsuba"}, {AvatarId: existing.AvatarId, AvatarName: "Itsuki"}}); err == nil {
+			t.Errorf("CreateBatch() with a duplicate primary key error = nil; want an error")
+		}
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll() error = %v", err)
+		}
+		if len(all) != 3 {
+			t.Errorf("FindAll() after failed CreateBatch = %d avatars; want 3", len(all))
+		}
+	})
+
+	t.Run("canceled context is an error", func(t *testing.T) {
+		repo := newRepo(t)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		if _, err := repo.Find(canceled, query.Spec{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("Find() error = %v; want context.Canceled", err)
+		}
+		if _, err := repo.Create(canceled, model.Avatar{AvatarName: "Nino"}); err == nil {
+			t.Errorf("Create() error = nil; want an error")
+		}
+		if all, err := repo.FindAll(ctx); err != nil || len(all) != 0 {
+			t.Errorf("FindAll() after canceled Create = %d avatars, %v; want none", len(all), err)
+		}
+	})
+}
+
+func mustCreateComplaint(t *testing.T, repo repository.ComplaintRepository, complaint model.Complaint) *model.Complaint {
+	t.Helper()
+	created, err := repo.Create(ctx, complaint)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return created
+}
+
+func mustCreateAvatar(t *testing.T, repo repository.AvatarRepository, avatar model.Avatar) *model.Avatar {
+	t.Helper()
+	created, err := repo.Create(ctx, avatar)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return created
+}
+
+func assertComplaintIds(t *testing.T, complaints []*model.Complaint, want ...int) {
+	t.Helper()
+	ids := make([]int, len(complaints))
+	for i, c := range complaints {
+		ids[i] = c.ComplaintId
+	}
+	assertIds(t, ids, want)
+}
+
+func assertIds(t *testing.T, got, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("ids = %v; want %v", got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("ids = %v; want %v", got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrBlobNotFound はBlobStoreにキーがないときのエラー
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Blob はBlobStoreから読んだファイル。Bodyは呼び出し側で閉じる
+type Blob struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// BlobStore はアバターの画像などのファイルをキーで保存する
+// キーは"avatars/1/medium/3fa9c1d2e4b5f6a7.png"のような"/"区切りの相対パスで、拡張子を付ける
+type BlobStore interface {
+	// Put はkeyにbodyを保存する。同じkeyがあれば置き換える
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	// Get はkeyのファイルを返す。ない場合はErrBlobNotFound
+	Get(ctx context.Context, key string) (*Blob, error)
+	// Delete はkeyのファイルを削除する。ない場合も成功とする
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,8 @@
+package service
+
+import "github.com/backend-guchitter-app/domain/model"
+
+// Fingerprinter はぐちのテキストから重複・類似の判定に使う指紋を算出する
+type Fingerprinter interface {
+	Fingerprint(text string) model.Fingerprint
+}
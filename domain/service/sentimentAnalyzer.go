@@ -0,0 +1,8 @@
+package service
+
+import "github.com/backend-guchitter-app/domain/model"
+
+// SentimentAnalyzer はぐちのテキストから感情スコアを算出する
+type SentimentAnalyzer interface {
+	Analyze(text string) model.Sentiment
+}
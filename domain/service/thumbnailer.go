@@ -0,0 +1,26 @@
+package service
+
+import "errors"
+
+var (
+	// ErrUnsupportedImage はPNG, JPEG, WebP以外のファイル
+	ErrUnsupportedImage = errors.New("image must be PNG, JPEG or WebP")
+	// ErrInvalidImage はデコードできない、または大きすぎる画像
+	ErrInvalidImage = errors.New("invalid image")
+)
+
+// Image はエンコードした画像
+type Image struct {
+	Body        []byte
+	ContentType string
+	// ".png"などの拡張子
+	Ext string
+}
+
+// Thumbnailer はアップロードされた画像から縮小した画像を作る
+type Thumbnailer interface {
+	// Thumbnails はbodyの形式をマジックバイトで判定してデコードし、中央を正方形に切り抜いて
+	// sizesの各辺の長さ(px)に縮小した画像を同じ順で返す。元の画像より大きくはせず、全て同じ形式でエンコードする
+	// 形式が対応していなければErrUnsupportedImage、デコードできなければErrInvalidImageを返す
+	Thumbnails(body []byte, sizes []int) ([]Image, error)
+}
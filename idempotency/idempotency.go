@@ -0,0 +1,79 @@
+// Package idempotency はIdempotency-Keyヘッダによる再送の検出に使う、保存したレスポンスの型とStoreを定義する
+//
+// 同じキーの最初のリクエストがキーを予約して処理し、レスポンスを保存する
+// 再送されたリクエストは処理せず、保存したレスポンスを返す
+//
+// プロセス内の実装はinfrastructure/inmemory、DBの実装はinfrastructure/persistenceにある
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/backend-guchitter-app/logging"
+	"github.com/bloom42/rz-go"
+)
+
+// Record は予約したキーと、処理が終わった場合はそのレスポンス
+type Record struct {
+	// クライアント、ルート、Idempotency-Keyから作ったハッシュ
+	Key string `gorm:"column:idempotency_key;primaryKey;size:64"`
+	// リクエストのボディのハッシュ。同じキーで異なるボディが送られたことの検出に使う
+	RequestHash string `gorm:"size:64;not null"`
+	// レスポンスを保存したか。falseの場合は処理中
+	Completed   bool   `gorm:"not null"`
+	Status      int    `gorm:"not null"`
+	ContentType string `gorm:"size:255;not null"`
+	Body        []byte
+	CreatedAt   time.Time
+	// 処理中の場合は予約の期限、処理が終わった場合は保存の期限。過ぎたものはないものとして扱う
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// TableName はGORMのテーブル名。MySQLではdb/migrationsで作成する
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+// Response は保存するレスポンス
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Store はキーの予約とレスポンスを保存する
+// 複数のリクエストが同時に同じキーを予約しても、成功するのは1つだけであること
+type Store interface {
+	// Begin はkeyをlockTimeoutの間予約する
+	// 予約できた場合はnil、期限内のRecordが既にある場合はそのRecordを返す
+	Begin(ctx context.Context, key, requestHash string, lockTimeout time.Duration) (*Record, error)
+	// Complete は予約したkeyにレスポンスを保存し、ttlの間保持する
+	Complete(ctx context.Context, key string, response Response, ttl time.Duration) error
+	// Release は処理が終わっていないkeyの予約を取り消す。同じキーで再試行できるようになる
+	Release(ctx context.Context, key string) error
+	// DeleteExpired はnowまでに期限が切れたRecordを削除し、削除した件数を返す
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Sweep はctxがキャンセルされるまで、intervalごとに期限切れのRecordを削除する
+// lifecycle.Workerで登録する
+func Sweep(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := store.DeleteExpired(ctx, now)
+			if err != nil {
+				logging.Log.Error("Failed at DeleteExpired()", rz.Err(err))
+				continue
+			}
+			if deleted > 0 {
+				logging.Log.Debug("expired idempotency keys deleted", rz.Int64("deleted", deleted))
+			}
+		}
+	}
+}
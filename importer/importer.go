@@ -0,0 +1,216 @@
+// Package importer はCSVまたはJSON Linesのファイルからぐちとアバターを取り込む
+//
+// 各行はPOST /complaints, POST /avatarsと同じく検証し(usecaseのCreateBatch)、
+// Options.BatchSize行ごとに1つのトランザクションで登録する
+// 検証で失敗した行は登録せず、行番号とともにResult.Errorsで返す
+//
+// 途中でDBのエラーなどで止まった場合は、Progress.LineをOptions.ResumeAfterに渡すと続きから再開できる
+// HTTPのエンドポイントはinterface/handlerのImportHandler、コマンドはgo run ./db importから使う
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/backend-guchitter-app/usecase"
+)
+
+// ファイルの形式
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// DefaultBatchSize はOptions.BatchSizeを指定しない場合に1つのトランザクションで登録する行数
+const DefaultBatchSize = 500
+
+// MaxErrors はResult.Errorsに含める行のエラーの上限。超えた分は数だけ返す
+const MaxErrors = 1000
+
+// ErrFormat はファイル全体を読めないときのエラー。必須の列がない、CSVの引用符が閉じていないなど
+var ErrFormat = errors.New("invalid import file")
+
+// 取り込みの指定
+type Options struct {
+	// FormatCSVまたはFormatJSONL
+	Format string
+	// trueの場合は検証のみ行い、登録しない
+	// バッチをまたいだ重複・類似の投稿は、前のバッチを登録しないため検出できない
+	DryRun bool
+	// この行までは登録済みとして読み飛ばす。前回のProgress.Lineを渡すと続きから再開できる
+	ResumeAfter int
+	// 1つのトランザクションで登録する行数。0の場合はDefaultBatchSize
+	BatchSize int
+	// バッチを登録するたびに呼ばれる。エラーを返すとそこで止める。nilの場合は呼ばない
+	OnProgress func(Progress) error
+}
+
+// 取り込みの進み具合
+type Progress struct {
+	// 登録(DryRunでは検証)を終えた最後の行。CSVの列名の行は1行目
+	Line int `json:"line"`
+	// 登録できた(DryRunでは登録できる)行数
+	Succeeded int `json:"succeeded"`
+	// 検証で失敗した行数
+	Failed int `json:"failed"`
+	// ResumeAfterで読み飛ばした行数
+	Skipped int `json:"skipped"`
+}
+
+// 行のエラー
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// 取り込みの結果
+type Result struct {
+	DryRun bool `json:"dryRun"`
+	Progress
+	Errors []LineError `json:"errors"`
+	// MaxErrorsを超えてErrorsに含めなかったエラーの数
+	ErrorsOmitted int `json:"errorsOmitted,omitempty"`
+}
+
+func (r *Result) addError(line int, err error) {
+	r.Failed++
+	if len(r.Errors) >= MaxErrors {
+		r.ErrorsOmitted++
+		return
+	}
+	r.Errors = append(r.Errors, LineError{Line: line, Message: err.Error()})
+}
+
+// Target は取り込む対象ごとの行の読み方と登録の方法
+type Target[T any] struct {
+	// CSVの必須の列
+	columns []string
+	// CSVの1行(列名から値)を読む
+	fromCSV func(record map[string]string) (T, error)
+	// JSON Linesの1行を読む
+	fromJSON func(line []byte) (T, error)
+	// 登録せずに検証し、行ごとのエラーを返す
+	check func(ctx context.Context, values []T) ([]error, error)
+	// 登録し、行ごとのエラーを返す
+	create func(ctx context.Context, values []T) ([]error, error)
+}
+
+// ValidateOptions はoptsの形式とバッチの行数を確認する
+func ValidateOptions(opts Options) error {
+	if opts.Format != FormatCSV && opts.Format != FormatJSONL {
+		return fmt.Errorf("invalid format %q: must be %s or %s", opts.Format, FormatCSV, FormatJSONL)
+	}
+	if opts.BatchSize < 0 || opts.BatchSize > usecase.MaxBatchSize {
+		return fmt.Errorf("batch size must be between 1 and %d, got %d", usecase.MaxBatchSize, opts.BatchSize)
+	}
+	if opts.ResumeAfter < 0 {
+		return fmt.Errorf("resume line must not be negative, got %d", opts.ResumeAfter)
+	}
+	return nil
+}
+
+// 読み込んだ1行
+type row[T any] struct {
+	line  int
+	value T
+	// 行を読めなかった場合のエラー。Resultの行のエラーにする
+	err error
+}
+
+// Run はrをoptsの形式で読み、targetに取り込む
+// 行のエラーはResultに含め、DBのエラーなどで止まった場合はそこまでのResultとエラーを返す
+func Run[T any](ctx context.Context, r io.Reader, target Target[T], opts Options) (*Result, error) {
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	result := &Result{DryRun: opts.DryRun, Errors: []LineError{}}
+	next, err := newRowReader(r, opts.Format, target)
+	if err != nil {
+		return result, err
+	}
+
+	batch := make([]row[T], 0, opts.BatchSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		if row.line <= opts.ResumeAfter {
+			result.Skipped++
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == opts.BatchSize {
+			if err := flush(ctx, target, opts, batch, result); err != nil {
+				return result, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(ctx, target, opts, batch, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// flush は読めた行をまとめて検証・登録し、resultに反映する
+func flush[T any](ctx context.Context, target Target[T], opts Options, batch []row[T], result *Result) error {
+	values := make([]T, 0, len(batch))
+	lines := make([]int, 0, len(batch))
+	for _, row := range batch {
+		if row.err != nil {
+			continue
+		}
+		values = append(values, row.value)
+		lines = append(lines, row.line)
+	}
+
+	var errs []error
+	if len(values) > 0 {
+		var err error
+		if opts.DryRun {
+			errs, err = target.check(ctx, values)
+		} else {
+			errs, err = target.create(ctx, values)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// 行番号の順にエラーを並べる
+	k := 0
+	for _, row := range batch {
+		if row.err != nil {
+			result.addError(row.line, row.err)
+			continue
+		}
+		if errs[k] != nil {
+			result.addError(row.line, errs[k])
+		} else {
+			result.Succeeded++
+		}
+		k++
+	}
+	result.Line = batch[len(batch)-1].line
+
+	if opts.OnProgress != nil {
+		return opts.OnProgress(result.Progress)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package importer_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/importer"
+	"github.com/backend-guchitter-app/infrastructure/fingerprint"
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
+	"github.com/backend-guchitter-app/infrastructure/sentiment"
+	"github.com/backend-guchitter-app/usecase"
+)
+
+func newComplaintTarget() importer.Target[model.Complaint] {
+	cu := usecase.NewComplaintUseCase(inmemory.NewComplaintRepository(), sentiment.NewLexiconAnalyzer(), fingerprint.NewSimHashFingerprinter(),
+		usecase.DuplicatePolicy{Action: usecase.DuplicateActionOff})
+	return importer.Complaints(cu)
+}
+
+// 引用符の誤りはファイル全体のエラーにし、読めなかった行の位置で止める
+func TestRunMalformedCSV(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		body     string
+		wantLine string
+	}{
+		{name: "first row", body: "complaintText,avatarId\na\"b,1\n", wantLine: "line 2"},
+		{name: "later row", body: "complaintText,avatarId\nつかれた,1\na\"b,1\n", wantLine: "line 3"},
+		{name: "unterminated quote", body: "complaintText,avatarId\n\"つかれた,1\n", wantLine: "line 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := importer.Run(context.Background(), strings.NewReader(tc.body), newComplaintTarget(), importer.Options{Format: importer.FormatCSV})
+			if !errors.Is(err, importer.ErrFormat) {
+				t.Fatalf("err = %v, want ErrFormat", err)
+			}
+			if !strings.Contains(err.Error(), tc.wantLine) {
+				t.Errorf("err = %q, want it to contain %q", err, tc.wantLine)
+			}
+			if result == nil || result.Succeeded != 0 {
+				t.Errorf("result = %+v, want nothing imported", result)
+			}
+		})
+	}
+}
+
+// 列の数の誤りはその行のみのエラーにして続ける
+func TestRunFieldCountError(t *testing.T) {
+	body := "complaintText,avatarId\nつかれた\nねむい,1\n"
+	result, err := importer.Run(context.Background(), strings.NewReader(body), newComplaintTarget(), importer.Options{Format: importer.FormatCSV})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Succeeded != 1 || result.Failed != 1 || len(result.Errors) != 1 || result.Errors[0].Line != 2 {
+		t.Errorf("result = %+v, want line 2 failed and line 3 imported", result)
+	}
+}
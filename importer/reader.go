@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ファイルの先頭のBOM。/exportのCSVに付くため、取り除いて読む
+const utf8BOM = "\ufeff"
+
+// newRowReader はrから1行ずつ読む関数を返す。読み終えるとio.EOFを返す
+func newRowReader[T any](r io.Reader, format string, target Target[T]) (func() (row[T], error), error) {
+	if format == FormatJSONL {
+		return jsonlRows(r, target), nil
+	}
+	return csvRows(r, target)
+}
+
+// csvRows は1行目を列名として読み、以降の行を列名から値のmapにしてtarget.fromCSVに渡す
+// 行番号はレコードの始まりの行。値に改行を含むレコードは複数行になる
+func csvRows[T any](r io.Reader, target Target[T]) (func() (row[T], error), error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: no header line", ErrFormat)
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.TrimSpace(name)
+	}
+	columns[0] = strings.TrimPrefix(columns[0], utf8BOM)
+	for _, required := range target.columns {
+		if !contains(columns, required) {
+			return nil, fmt.Errorf("%w: missing column %q", ErrFormat, required)
+		}
+	}
+
+	return func() (row[T], error) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return row[T]{}, io.EOF
+		}
+		if err != nil {
+			// 列の数の誤りはその行のみのエラーにする。それ以外は以降の行の区切りが分からないため止める
+			// FieldPosは読めなかったレコードには使えないため、行番号はParseErrorから取る
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				return row[T]{line: parseErr.StartLine, err: fmt.Errorf("expected %d columns, got %d", len(columns), len(record))}, nil
+			}
+			return row[T]{}, csvError(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]string, len(columns))
+		for i, name := range columns {
+			values[name] = unescapeCSVText(record[i])
+		}
+		value, err := target.fromCSV(values)
+		return row[T]{line: line, value: value, err: err}, nil
+	}, nil
+}
+
+// csvError はCSVの形式の誤りをErrFormatにする。ボディの読み込みのエラーはそのまま返す
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%w: %s", ErrFormat, err)
+	}
+	return err
+}
+
+// jsonlRows は空行を除く1行ずつをtarget.fromJSONに渡す
+func jsonlRows[T any](r io.Reader, target Target[T]) func() (row[T], error) {
+	reader := bufio.NewReader(r)
+	line := 0
+	return func() (row[T], error) {
+		for {
+			b, err := reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return row[T]{}, err
+			}
+			if len(b) == 0 && err == io.EOF {
+				return row[T]{}, io.EOF
+			}
+			line++
+			if line == 1 {
+				b = bytes.TrimPrefix(b, []byte(utf8BOM))
+			}
+			b = bytes.TrimSpace(b)
+			if len(b) == 0 {
+				continue
+			}
+			value, decodeErr := target.fromJSON(b)
+			return row[T]{line: line, value: value, err: decodeErr}, nil
+		}
+	}
+}
+
+// unescapeCSVText は/exportがExcelの数式にならないよう先頭に付けた'を取り除く
+func unescapeCSVText(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func contains(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/usecase"
+)
+
+// 取り込むぐちの項目。IDや日時、感情スコアなどの他の列は無視する
+type complaintRow struct {
+	ComplaintText string `json:"complaintText"`
+	AvatarId      int    `json:"avatarId"`
+}
+
+// 取り込むアバターの項目。IDや日時などの他の列は無視する
+type avatarRow struct {
+	AvatarName string `json:"avatarName"`
+	AvatarText string `json:"avatarText"`
+	ImageUrl   string `json:"imageUrl"`
+	Color      string `json:"color"`
+}
+
+// Complaints はぐちをcuで検証・登録するTarget
+// CSVはcomplaintText, avatarIdの列が必須。avatarIdはこのDBのアバターのID
+func Complaints(cu usecase.ComplaintUseCase) Target[model.Complaint] {
+	return Target[model.Complaint]{
+		columns: []string{"complaintText", "avatarId"},
+		fromCSV: func(record map[string]string) (model.Complaint, error) {
+			avatarId, err := strconv.Atoi(strings.TrimSpace(record["avatarId"]))
+			if err != nil {
+				return model.Complaint{}, &usecase.ValidationError{Field: "avatarId", Message: "must be an integer"}
+			}
+			return model.Complaint{ComplaintText: record["complaintText"], AvatarId: avatarId}, nil
+		},
+		fromJSON: func(line []byte) (model.Complaint, error) {
+			var r complaintRow
+			if err := json.Unmarshal(line, &r); err != nil {
+				return model.Complaint{}, jsonRowError(err)
+			}
+			return model.Complaint{ComplaintText: r.ComplaintText, AvatarId: r.AvatarId}, nil
+		},
+		check: cu.CheckBatch,
+		create: func(ctx context.Context, complaints []model.Complaint) ([]error, error) {
+			items, err := cu.CreateBatch(ctx, complaints, usecase.BatchModeBestEffort)
+			if err != nil {
+				return nil, err
+			}
+			return itemErrors(items), nil
+		},
+	}
+}
+
+// Avatars はアバターをauで検証・登録するTarget
+// CSVはavatarNameの列が必須
+func Avatars(au usecase.AvatarUseCase) Target[model.Avatar] {
+	return Target[model.Avatar]{
+		columns: []string{"avatarName"},
+		fromCSV: func(record map[string]string) (model.Avatar, error) {
+			return model.Avatar{
+				AvatarName: record["avatarName"],
+				AvatarText: record["avatarText"],
+				ImageUrl:   record["imageUrl"],
+				Color:      record["color"],
+			}, nil
+		},
+		fromJSON: func(line []byte) (model.Avatar, error) {
+			var r avatarRow
+			if err := json.Unmarshal(line, &r); err != nil {
+				return model.Avatar{}, jsonRowError(err)
+			}
+			return model.Avatar{AvatarName: r.AvatarName, AvatarText: r.AvatarText, ImageUrl: r.ImageUrl, Color: r.Color}, nil
+		},
+		check: au.CheckBatch,
+		create: func(ctx context.Context, avatars []model.Avatar) ([]error, error) {
+			items, err := au.CreateBatch(ctx, avatars, usecase.BatchModeBestEffort)
+			if err != nil {
+				return nil, err
+			}
+			return itemErrors(items), nil
+		},
+	}
+}
+
+// jsonRowError はJSONの1行を読めなかった理由を返す。型の誤りは項目の検証と同じ形のエラーにする
+func jsonRowError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		message := "must be a " + typeErr.Type.Kind().String()
+		if typeErr.Type.Kind() == reflect.Int {
+			message = "must be an integer"
+		}
+		return &usecase.ValidationError{Field: typeErr.Field, Message: message}
+	}
+	return fmt.Errorf("invalid JSON: %w", err)
+}
+
+func itemErrors[T any](items []usecase.BatchItem[T]) []error {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = item.Err
+	}
+	return errs
+}
@@ -0,0 +1,71 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/backend-guchitter-app/domain/service"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "avatars/1/small/9f69616111ea6f99.png"
+
+	if err := store.Put(ctx, key, []byte("png"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "uploads", "avatars", "1", "small", "9f69616111ea6f99.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v; want 0644", info.Mode().Perm())
+	}
+
+	blob, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(blob.Body)
+	blob.Body.Close()
+	if string(body) != "png" || blob.ContentType != "image/png" || blob.Size != 3 {
+		t.Errorf("blob = %q %s %d; want png image/png 3", body, blob.ContentType, blob.Size)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, service.ErrBlobNotFound) {
+		t.Errorf("Get after Delete err = %v; want ErrBlobNotFound", err)
+	}
+	// 存在しないキーのDeleteも成功とする
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete missing err = %v; want nil", err)
+	}
+}
+
+// dirの外を指すキーは読み書きしない
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, key := range []string{"", "../secret", "/etc/passwd", "avatars/../../secret", "avatars//1", ".."} {
+		if err := store.Put(ctx, key, []byte("x"), "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded; want error", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil || errors.Is(err, service.ErrBlobNotFound) {
+			t.Errorf("Get(%q) err = %v; want invalid key", key, err)
+		}
+	}
+}
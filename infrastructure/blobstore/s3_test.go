@@ -0,0 +1,221 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/service"
+)
+
+const (
+	testAccessKeyID     = "AKIDEXAMPLE"
+	testSecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// 署名はAWSのSDK(aws-sdk-go-v2のv4.Signer、DisableURIPathEscaping)で同じリクエストに署名した値と比べる
+func TestS3Sign(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     S3Options
+		method   string
+		key      string
+		body     string
+		wantURL  string
+		wantAuth string
+	}{
+		{
+			name:     "path style",
+			opts:     S3Options{Endpoint: "http://localhost:9000", Region: "us-east-1", Bucket: "avatars", PathStyle: true},
+			method:   http.MethodPut,
+			key:      "avatars/1/medium/9f69616111ea6f99.png",
+			body:     "hello",
+			wantURL:  "http://localhost:9000/avatars/avatars/1/medium/9f69616111ea6f99.png",
+			wantAuth: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240102/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=90e5c290f18d571a9291550872155158142ae65ae5f91bea637d96954496bb8d",
+		},
+		{
+			name:     "virtual host with escaped key",
+			opts:     S3Options{Region: "ap-northeast-1", Bucket: "avatars"},
+			method:   http.MethodGet,
+			key:      "odd key/日本 (1)+x.png",
+			wantURL:  "https://avatars.s3.ap-northeast-1.amazonaws.com/odd%20key/%E6%97%A5%E6%9C%AC%20%281%29%2Bx.png",
+			wantAuth: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240102/ap-northeast-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=fceaa55da6c11ab30a5c650a6e322d3c69374fe86944032751b66af853dc98b1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.AccessKeyID, tc.opts.SecretAccessKey = testAccessKeyID, testSecretAccessKey
+			store, err := NewS3Store(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := store.(*s3Store)
+			s.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+			req, err := s.newRequest(context.Background(), tc.method, tc.key, []byte(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := req.URL.String(); got != tc.wantURL {
+				t.Errorf("url = %s; want %s", got, tc.wantURL)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20240102T030405Z" {
+				t.Errorf("X-Amz-Date = %s; want 20240102T030405Z", got)
+			}
+			if got := req.Header.Get("Authorization"); got != tc.wantAuth {
+				t.Errorf("Authorization =\n  %s\nwant\n  %s", got, tc.wantAuth)
+			}
+		})
+	}
+}
+
+// fakeS3 はS3の代わりにオブジェクトをメモリに保存するサーバー
+// delete404がtrueの場合、存在しないキーのDeleteに404を返す(S3は204を返す)
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string]fakeObject
+	requests  []string
+	delete404 bool
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// オブジェクトはバケットを含めて区別するため、ホストとパスで保存する
+	name := r.Host + r.URL.EscapedPath()
+	f.requests = append(f.requests, r.Method+" "+name)
+
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) ||
+		!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+testAccessKeyID+"/") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>The request signature we calculated does not match</Message></Error>")
+		return
+	}
+
+	object, ok := f.objects[name]
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[name] = fakeObject{body: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Write(object.body)
+	case http.MethodDelete:
+		delete(f.objects, name)
+		if !ok && f.delete404 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// newFakeS3Store はfakeS3に接続するBlobStoreを返す
+// 仮想ホスト形式ではバケットをホスト名に含めるため、ホスト名によらずテストのサーバーに接続する
+func newFakeS3Store(t *testing.T, fake *fakeS3, pathStyle bool) service.BlobStore {
+	t.Helper()
+	fake.objects = map[string]fakeObject{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}}
+	store, err := NewS3Store(S3Options{
+		Endpoint:        "http://s3.test",
+		Region:          "us-east-1",
+		Bucket:          "avatars",
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: testSecretAccessKey,
+		PathStyle:       pathStyle,
+		Client:          client,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3Store(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		pathStyle   bool
+		wantRequest string
+	}{
+		{name: "path style", pathStyle: true, wantRequest: "PUT s3.test/avatars/avatars/1/small/a%20b.png"},
+		{name: "virtual host", pathStyle: false, wantRequest: "PUT avatars.s3.test/avatars/1/small/a%20b.png"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeS3{}
+			store := newFakeS3Store(t, fake, tc.pathStyle)
+			ctx := context.Background()
+			key := "avatars/1/small/a b.png"
+
+			if err := store.Put(ctx, key, []byte("png"), "image/png"); err != nil {
+				t.Fatal(err)
+			}
+			if fake.requests[0] != tc.wantRequest {
+				t.Errorf("request = %s; want %s", fake.requests[0], tc.wantRequest)
+			}
+			blob, err := store.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(blob.Body)
+			blob.Body.Close()
+			if string(body) != "png" || blob.ContentType != "image/png" || blob.Size != 3 {
+				t.Errorf("blob = %q %s %d; want png image/png 3", body, blob.ContentType, blob.Size)
+			}
+
+			if err := store.Delete(ctx, key); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(ctx, key); !errors.Is(err, service.ErrBlobNotFound) {
+				t.Errorf("Get after Delete err = %v; want ErrBlobNotFound", err)
+			}
+		})
+	}
+}
+
+// 存在しないキーのDeleteは、204を返すストレージでも404を返すストレージでも成功とする
+func TestS3StoreDeleteMissing(t *testing.T) {
+	for _, delete404 := range []bool{false, true} {
+		fake := &fakeS3{delete404: delete404}
+		store := newFakeS3Store(t, fake, true)
+		if err := store.Delete(context.Background(), "avatars/1/small/missing.png"); err != nil {
+			t.Errorf("Delete missing (404=%v) err = %v; want nil", delete404, err)
+		}
+	}
+}
+
+// S3のエラーのレスポンスはCodeとMessageを含むエラーにする
+func TestS3StoreErrorResponse(t *testing.T) {
+	fake := &fakeS3{}
+	store := newFakeS3Store(t, fake, true)
+	store.(*s3Store).opts.AccessKeyID = "WRONG"
+
+	err := store.Put(context.Background(), "avatars/1/small/a.png", []byte("png"), "image/png")
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden: SignatureDoesNotMatch") {
+		t.Errorf("err = %v; want SignatureDoesNotMatch", err)
+	}
+}
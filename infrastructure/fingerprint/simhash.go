@@ -0,0 +1,96 @@
+// Package fingerprint はテキストを正規化し、文字n-gramのSimHashで指紋を算出する
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/service"
+	"golang.org/x/text/unicode/norm"
+)
+
+// 文字n-gram(シングル)の文字数
+// 日本語は単語の区切りがないため、単語ではなく文字で区切る。ぐちは短いので2文字にする
+const shingleSize = 2
+
+type simHashFingerprinter struct{}
+
+// NewSimHashFingerprinter はSimHashで指紋を算出するFingerprinterを返す
+func NewSimHashFingerprinter() service.Fingerprinter {
+	return simHashFingerprinter{}
+}
+
+func (simHashFingerprinter) Fingerprint(text string) model.Fingerprint {
+	normalized := Normalize(text)
+	// 記号だけのテキストは正規化すると空になるため、元のテキストで判定する
+	if normalized == "" {
+		normalized = text
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	return model.Fingerprint{
+		TextHash: hex.EncodeToString(sum[:]),
+		SimHash:  int64(simHash(shingles(normalized))),
+	}
+}
+
+// Normalize は表記ゆれを吸収したテキストを返す
+// 全角・半角をそろえ(NFKC)、小文字・ひらがなにし、空白・句読点・記号を取り除く
+func Normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(norm.NFKC.String(text)) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		// カタカナ(ァ〜ヶ)はひらがなに寄せる。長音符などはそのまま
+		if r >= 'ァ' && r <= 'ヶ' {
+			r -= 'ァ' - 'ぁ'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// shingles はtextを1文字ずつずらしたshingleSize文字の部分文字列に分ける
+// shingleSizeより短い場合はtext全体を1つとする
+func shingles(text string) []string {
+	runes := []rune(text)
+	if len(runes) <= shingleSize {
+		return []string{text}
+	}
+	result := make([]string, 0, len(runes)-shingleSize+1)
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		result = append(result, string(runes[i:i+shingleSize]))
+	}
+	return result
+}
+
+// simHash は各shingleのハッシュのビットごとに多数決を取った64bitの値を返す
+// 似たテキストほど共通のshingleが多く、異なるビットが少なくなる
+func simHash(shingles []string) uint64 {
+	var weights [64]int
+	for _, s := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		v := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if v&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var result uint64
+	for bit, w := range weights {
+		if w > 0 {
+			result |= 1 << uint(bit)
+		}
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+// Package imaging はアップロードされた画像の形式の判定と縮小を行う
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+
+	"github.com/backend-guchitter-app/domain/service"
+)
+
+// デコードする画像の縦横の上限(px)。小さなファイルで巨大な画像を展開させる攻撃を防ぐ
+const MaxDimension = 4096
+
+// 縮小した画像をJPEGでエンコードする場合の品質
+const jpegQuality = 85
+
+// 対応する形式と、ファイルの先頭のマジックバイトによる判定
+var formats = []struct {
+	name   string
+	match  func(b []byte) bool
+	decode func(b []byte) (image.Image, error)
+	config func(b []byte) (image.Config, error)
+}{
+	{
+		name:   "png",
+		match:  func(b []byte) bool { return bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) },
+		decode: func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) },
+		config: func(b []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(b)) },
+	},
+	{
+		name:   "jpeg",
+		match:  func(b []byte) bool { return bytes.HasPrefix(b, []byte("\xff\xd8\xff")) },
+		decode: func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) },
+		config: func(b []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(b)) },
+	},
+	{
+		// RIFFのチャンクの種類がWEBP
+		name:   "webp",
+		match:  func(b []byte) bool { return len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WEBP" },
+		decode: func(b []byte) (image.Image, error) { return webp.Decode(bytes.NewReader(b)) },
+		config: func(b []byte) (image.Config, error) { return webp.DecodeConfig(bytes.NewReader(b)) },
+	},
+}
+
+type thumbnailer struct{}
+
+// NewThumbnailer はPNG, JPEG, WebPを受け付け、透過を含む画像はPNG、それ以外はJPEGで縮小した画像を返すThumbnailerを返す
+// 再エンコードするため、元の画像のExifなどのメタデータは残らない
+func NewThumbnailer() service.Thumbnailer {
+	return thumbnailer{}
+}
+
+func (thumbnailer) Thumbnails(body []byte, sizes []int) ([]service.Image, error) {
+	for _, f := range formats {
+		if f.match(body) {
+			return thumbnails(body, sizes, f.decode, f.config)
+		}
+	}
+	return nil, service.ErrUnsupportedImage
+}
+
+func thumbnails(body []byte, sizes []int, decode func([]byte) (image.Image, error), config func([]byte) (image.Config, error)) ([]service.Image, error) {
+	// 画素を展開する前に大きさを確認する
+	cfg, err := config(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", service.ErrInvalidImage, err)
+	}
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, fmt.Errorf("%w: %dx%d must be at most %dx%d", service.ErrInvalidImage, cfg.Width, cfg.Height, MaxDimension, MaxDimension)
+	}
+	src, err := decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", service.ErrInvalidImage, err)
+	}
+
+	square := centerSquare(src.Bounds())
+	opaque := isOpaque(src)
+	images := make([]service.Image, 0, len(sizes))
+	for _, size := range sizes {
+		if side := square.Dx(); size > side {
+			size = side
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, square, draw.Src, nil)
+
+		img, err := encode(dst, opaque)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// centerSquare はrの中央の正方形を返す
+func centerSquare(r image.Rectangle) image.Rectangle {
+	side := r.Dx()
+	if r.Dy() < side {
+		side = r.Dy()
+	}
+	min := r.Min.Add(image.Pt((r.Dx()-side)/2, (r.Dy()-side)/2))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(side, side))}
+}
+
+// isOpaque は画像が透過を含まないかを返す。判定できない型は透過を含むとみなす
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+func encode(img image.Image, opaque bool) (service.Image, error) {
+	var buf bytes.Buffer
+	if opaque {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return service.Image{}, err
+		}
+		return service.Image{Body: buf.Bytes(), ContentType: "image/jpeg", Ext: ".jpg"}, nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return service.Image{}, err
+	}
+	return service.Image{Body: buf.Bytes(), ContentType: "image/png", Ext: ".png"}, nil
+}
@@ -0,0 +1,177 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
+	"github.com/backend-guchitter-app/domain/repository"
+)
+
+var avatarFields = fields[model.Avatar]{
+	"avatarId":   func(a *model.Avatar) interface{} { return a.AvatarId },
+	"avatarName": func(a *model.Avatar) interface{} { return a.AvatarName },
+	"color":      func(a *model.Avatar) interface{} { return a.Color },
+	"createdAt":  func(a *model.Avatar) interface{} { return a.CreatedAt },
+	"lastUpdate": func(a *model.Avatar) interface{} { return a.LastUpdate },
+}
+
+// avatarRepository はAvatarをメモリ上に保持するリポジトリ
+// テストやDBなしでのローカル開発用。プロセスを終了すると消える
+// DBの実装と同じく、キャンセル・期限切れのコンテキストではctx.Err()を返す
+type avatarRepository struct {
+	mu     sync.RWMutex
+	nextId int
+	// 主キー順に並べて保持する
+	avatars []*model.Avatar
+}
+
+func NewAvatarRepository() repository.AvatarRepository {
+	return &avatarRepository{
+		nextId: 1,
+	}
+}
+
+func (ar *avatarRepository) FindAll(ctx context.Context) ([]*model.Avatar, error) {
+	return ar.Find(ctx, query.Spec{})
+}
+
+func (ar *avatarRepository) FindByAvatarId(ctx context.Context, id int) (*model.Avatar, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	for _, a := range ar.avatars {
+		if a.AvatarId == id {
+			found := *a
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ar *avatarRepository) Create(ctx context.Context, avatar model.Avatar) (*model.Avatar, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	return ar.insert(avatar)
+}
+
+func (ar *avatarRepository) CreateBatch(ctx context.Context, avatars []model.Avatar) ([]*model.Avatar, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	// 途中で失敗した場合に何も登録しないよう、コピーに加えてから置き換える
+	saved, nextId := ar.avatars, ar.nextId
+	ar.avatars = append([]*model.Avatar(nil), ar.avatars...)
+	created := make([]*model.Avatar, 0, len(avatars))
+	for _, avatar := range avatars {
+		a, err := ar.insert(avatar)
+		if err != nil {
+			ar.avatars, ar.nextId = saved, nextId
+			return nil, err
+		}
+		created = append(created, a)
+	}
+	return created, nil
+}
+
+// insert はGORMと同じく、IDと日時が未設定なら採番してar.avatarsに加える
+// 呼び出し側でロックすること
+func (ar *avatarRepository) insert(avatar model.Avatar) (*model.Avatar, error) {
+	if avatar.AvatarId == 0 {
+		avatar.AvatarId = ar.nextId
+	}
+	now := time.Now()
+	if avatar.CreatedAt.IsZero() {
+		avatar.CreatedAt = now
+	}
+	if avatar.LastUpdate.IsZero() {
+		avatar.LastUpdate = now
+	}
+
+	stored := avatar
+	avatars, err := insertSorted(ar.avatars, &stored, func(a *model.Avatar) int { return a.AvatarId })
+	if err != nil {
+		return nil, err
+	}
+	ar.avatars = avatars
+	if avatar.AvatarId >= ar.nextId {
+		ar.nextId = avatar.AvatarId + 1
+	}
+	return &avatar, nil
+}
+
+func (ar *avatarRepository) Find(ctx context.Context, spec query.Spec) ([]*model.Avatar, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	matched, err := filter(ar.avatars, spec, avatarFields)
+	if err != nil {
+		return nil, err
+	}
+	return copyAll(matched), nil
+}
+
+// Each はFindの結果を1件ずつfnに渡す。fnの実行中はロックを持たない
+func (ar *avatarRepository) Each(ctx context.Context, spec query.Spec, fn func(*model.Avatar) error) error {
+	matched, err := ar.Find(ctx, spec)
+	if err != nil {
+		return err
+	}
+	return each(ctx, matched, fn)
+}
+
+func (ar *avatarRepository) DeleteByAvatarId(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	for i, a := range ar.avatars {
+		if a.AvatarId == id {
+			ar.avatars = append(ar.avatars[:i], ar.avatars[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (ar *avatarRepository) UpdateImage(ctx context.Context, id int, imageFile string, imageUrl string) (*model.Avatar, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	for _, a := range ar.avatars {
+		if a.AvatarId == id {
+			a.ImageFile = imageFile
+			a.ImageUrl = imageUrl
+			a.LastUpdate = time.Now()
+			updated := *a
+			return &updated, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,69 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/backend-guchitter-app/domain/service"
+)
+
+type storedBlob struct {
+	body        []byte
+	contentType string
+}
+
+type blobStore struct {
+	mu    sync.RWMutex
+	blobs map[string]storedBlob
+}
+
+// NewBlobStore はプロセス内のメモリにファイルを保存するBlobStoreを返す
+// テストやDBなしでのローカル開発用。プロセスを終了すると消える
+func NewBlobStore() service.BlobStore {
+	return &blobStore{
+		blobs: map[string]storedBlob{},
+	}
+}
+
+func (s *blobStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 呼び出し側がbodyを書き換えても影響しないようコピーする
+	s.blobs[key] = storedBlob{body: append([]byte(nil), body...), contentType: contentType}
+	return nil
+}
+
+func (s *blobStore) Get(ctx context.Context, key string) (*service.Blob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.blobs[key]
+	if !ok {
+		return nil, service.ErrBlobNotFound
+	}
+	return &service.Blob{
+		Body:        io.NopCloser(bytes.NewReader(b.body)),
+		ContentType: b.contentType,
+		Size:        int64(len(b.body)),
+	}, nil
+}
+
+func (s *blobStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.blobs, key)
+	return nil
+}
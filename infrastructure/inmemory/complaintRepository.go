@@ -0,0 +1,201 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
+	"github.com/backend-guchitter-app/domain/repository"
+)
+
+var complaintFields = fields[model.Complaint]{
+	"complaintId": func(c *model.Complaint) interface{} { return c.ComplaintId },
+	"avatarId":    func(c *model.Complaint) interface{} { return c.AvatarId },
+	"negativity":  func(c *model.Complaint) interface{} { return c.Negativity },
+	"anger":       func(c *model.Complaint) interface{} { return c.Anger },
+	"sadness":     func(c *model.Complaint) interface{} { return c.Sadness },
+	"createdAt":   func(c *model.Complaint) interface{} { return c.CreatedAt },
+	"lastUpdate":  func(c *model.Complaint) interface{} { return c.LastUpdate },
+}
+
+// complaintRepository はComplaintをメモリ上に保持するリポジトリ
+// テストやDBなしでのローカル開発用。プロセスを終了すると消える
+// DBの実装と同じく、キャンセル・期限切れのコンテキストではctx.Err()を返す
+type complaintRepository struct {
+	mu     sync.RWMutex
+	nextId int
+	// 主キー順に並べて保持する
+	complaints []*model.Complaint
+}
+
+func NewComplaintRepository() repository.ComplaintRepository {
+	return &complaintRepository{
+		nextId: 1,
+	}
+}
+
+func (cr *complaintRepository) FindAll(ctx context.Context) ([]*model.Complaint, error) {
+	return cr.Find(ctx, query.Spec{})
+}
+
+func (cr *complaintRepository) FindByAvatarId(ctx context.Context, id int) (*model.Complaint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	for _, c := range cr.complaints {
+		if c.AvatarId == id {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (cr *complaintRepository) Create(ctx context.Context, complaint model.Complaint) (*model.Complaint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	return cr.insert(complaint)
+}
+
+func (cr *complaintRepository) CreateBatch(ctx context.Context, complaints []model.Complaint) ([]*model.Complaint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	// 途中で失敗した場合に何も登録しないよう、コピーに加えてから置き換える
+	saved, nextId := cr.complaints, cr.nextId
+	cr.complaints = append([]*model.Complaint(nil), cr.complaints...)
+	created := make([]*model.Complaint, 0, len(complaints))
+	for _, complaint := range complaints {
+		c, err := cr.insert(complaint)
+		if err != nil {
+			cr.complaints, cr.nextId = saved, nextId
+			return nil, err
+		}
+		created = append(created, c)
+	}
+	return created, nil
+}
+
+// insert はGORMと同じく、IDと日時が未設定なら採番してcr.complaintsに加える
+// 呼び出し側でロックすること
+func (cr *complaintRepository) insert(complaint model.Complaint) (*model.Complaint, error) {
+	if complaint.ComplaintId == 0 {
+		complaint.ComplaintId = cr.nextId
+	}
+	now := time.Now()
+	if complaint.CreatedAt.IsZero() {
+		complaint.CreatedAt = now
+	}
+	if complaint.LastUpdate.IsZero() {
+		complaint.LastUpdate = now
+	}
+
+	stored := complaint
+	complaints, err := insertSorted(cr.complaints, &stored, func(c *model.Complaint) int { return c.ComplaintId })
+	if err != nil {
+		return nil, err
+	}
+	cr.complaints = complaints
+	if complaint.ComplaintId >= cr.nextId {
+		cr.nextId = complaint.ComplaintId + 1
+	}
+	return &complaint, nil
+}
+
+func (cr *complaintRepository) Find(ctx context.Context, spec query.Spec) ([]*model.Complaint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	matched, err := filter(cr.complaints, spec, complaintFields)
+	if err != nil {
+		return nil, err
+	}
+	return copyAll(matched), nil
+}
+
+// Each はFindの結果を1件ずつfnに渡す。fnの実行中はロックを持たない
+func (cr *complaintRepository) Each(ctx context.Context, spec query.Spec, fn func(*model.Complaint) error) error {
+	matched, err := cr.Find(ctx, spec)
+	if err != nil {
+		return err
+	}
+	return each(ctx, matched, fn)
+}
+
+func (cr *complaintRepository) DeleteByComplaintId(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for i, c := range cr.complaints {
+		if c.ComplaintId == id {
+			cr.complaints = append(cr.complaints[:i], cr.complaints[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (cr *complaintRepository) IncrementDuplicateCount(ctx context.Context, id int) (*model.Complaint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for _, c := range cr.complaints {
+		if c.ComplaintId == id {
+			// GORMのautoUpdateTimeと同じく更新日時も更新する
+			c.DuplicateCount++
+			c.LastUpdate = time.Now()
+			updated := *c
+			return &updated, nil
+		}
+	}
+	return nil, nil
+}
+
+func (cr *complaintRepository) DeleteByComplaintIds(ctx context.Context, ids []int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	targets := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		targets[id] = true
+	}
+	kept := cr.complaints[:0]
+	for _, c := range cr.complaints {
+		if !targets[c.ComplaintId] {
+			kept = append(kept, c)
+		}
+	}
+	cr.complaints = kept
+	return nil
+}
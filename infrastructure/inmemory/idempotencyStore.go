@@ -0,0 +1,94 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/backend-guchitter-app/idempotency"
+)
+
+type idempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+	now     func() time.Time
+}
+
+// NewIdempotencyStore はプロセス内のメモリにレスポンスを保存するStoreを返す
+// プロセスごとに保存されるため、複数のプロセスで動かす場合は別のプロセスへの再送を検出できない
+func NewIdempotencyStore() idempotency.Store {
+	return &idempotencyStore{
+		records: map[string]*idempotency.Record{},
+		now:     time.Now,
+	}
+}
+
+func (s *idempotencyStore) Begin(ctx context.Context, key, requestHash string, lockTimeout time.Duration) (*idempotency.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if r, ok := s.records[key]; ok && r.ExpiresAt.After(now) {
+		existing := *r
+		return &existing, nil
+	}
+	s.records[key] = &idempotency.Record{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(lockTimeout),
+	}
+	return nil, nil
+}
+
+func (s *idempotencyStore) Complete(ctx context.Context, key string, response idempotency.Response, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok || r.Completed {
+		return nil
+	}
+	r.Completed = true
+	r.Status = response.Status
+	r.ContentType = response.ContentType
+	r.Body = append([]byte(nil), response.Body...)
+	r.ExpiresAt = s.now().Add(ttl)
+	return nil
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok && !r.Completed {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+func (s *idempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, r := range s.records {
+		if !r.ExpiresAt.After(now) {
+			delete(s.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,93 @@
+package inmemory
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/backend-guchitter-app/ratelimit"
+)
+
+// 満杯に戻ったバケットを削除する間隔
+const rateLimitSweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	// tokensを計算した時刻
+	updatedAt time.Time
+	limit     ratelimit.Limit
+}
+
+// refill はnowまでに補充されるトークンを加える
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Requests), b.tokens+elapsed*b.limit.Rate())
+		b.updatedAt = now
+	}
+}
+
+type rateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewRateLimitStore はプロセス内のメモリにバケットを保存するStoreを返す
+// プロセスごとに制限されるため、複数のプロセスで動かす場合は全体の上限がプロセス数倍になる
+func NewRateLimitStore() ratelimit.Store {
+	return &rateLimitStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *rateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, n int) (ratelimit.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return ratelimit.Result{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), updatedAt: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+
+	rate := limit.Rate()
+	result := ratelimit.Result{Limit: limit.Requests}
+	if b.tokens >= float64(n) {
+		b.tokens -= float64(n)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((float64(n) - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsToDuration((float64(limit.Requests) - b.tokens) / rate)
+	return result, nil
+}
+
+// sweep は満杯に戻ったバケットを削除する。満杯のバケットはないのと同じため、結果は変わらない
+func (s *rateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Requests) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
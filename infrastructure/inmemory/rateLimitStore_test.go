@@ -0,0 +1,40 @@
+package inmemory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
+	"github.com/backend-guchitter-app/ratelimit"
+)
+
+func TestRateLimitStoreTakeN(t *testing.T) {
+	store := inmemory.NewRateLimitStore()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 10, Period: time.Hour}
+
+	tests := []struct {
+		n             int
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{n: 6, wantAllowed: true, wantRemaining: 4},
+		// 足りない場合は取り出さない
+		{n: 5, wantAllowed: false, wantRemaining: 4},
+		{n: 4, wantAllowed: true, wantRemaining: 0},
+		{n: 1, wantAllowed: false, wantRemaining: 0},
+	}
+	for _, tt := range tests {
+		result, err := store.Take(ctx, "key", limit, tt.n)
+		if err != nil {
+			t.Fatalf("Take(%d) error = %v", tt.n, err)
+		}
+		if result.Allowed != tt.wantAllowed || result.Remaining != tt.wantRemaining {
+			t.Errorf("Take(%d) = allowed %v, remaining %d; want allowed %v, remaining %d", tt.n, result.Allowed, result.Remaining, tt.wantAllowed, tt.wantRemaining)
+		}
+		if !result.Allowed && result.RetryAfter <= 0 {
+			t.Errorf("Take(%d) RetryAfter = %v; want > 0", tt.n, result.RetryAfter)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/backend-guchitter-app/domain/repository"
+	"github.com/backend-guchitter-app/domain/repository/repositorytest"
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
+)
+
+func TestComplaintRepository(t *testing.T) {
+	repositorytest.ComplaintRepositoryContract(t, func(t *testing.T) repository.ComplaintRepository {
+		return inmemory.NewComplaintRepository()
+	})
+}
+
+func TestAvatarRepository(t *testing.T) {
+	repositorytest.AvatarRepositoryContract(t, func(t *testing.T) repository.AvatarRepository {
+		return inmemory.NewAvatarRepository()
+	})
+}
@@ -0,0 +1,197 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/query"
+)
+
+// フィールド名(APIのキャメルケース)から値を取り出す関数の一覧
+type fields[T any] map[string]func(*T) interface{}
+
+// filter はspecの条件に一致する要素をitemsの順に返す
+// ページングもspecに従う
+func filter[T any](items []*T, spec query.Spec, getters fields[T]) ([]*T, error) {
+	// 要素が0件でもDBと同じく不正なフィールドはエラーにする
+	for _, cond := range spec.Conditions {
+		if _, ok := getters[cond.Field]; !ok {
+			return nil, fmt.Errorf("unknown filter field %q", cond.Field)
+		}
+	}
+
+	matched := []*T{}
+	for _, item := range items {
+		ok, err := matches(item, spec, getters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, item)
+		}
+	}
+
+	if spec.Offset > 0 {
+		if spec.Offset >= len(matched) {
+			return []*T{}, nil
+		}
+		matched = matched[spec.Offset:]
+	}
+	if spec.Limit > 0 && spec.Limit < len(matched) {
+		matched = matched[:spec.Limit]
+	}
+	return matched, nil
+}
+
+func matches[T any](item *T, spec query.Spec, getters fields[T]) (bool, error) {
+	for _, cond := range spec.Conditions {
+		get, ok := getters[cond.Field]
+		if !ok {
+			return false, fmt.Errorf("unknown filter field %q", cond.Field)
+		}
+		ok, err := evaluate(get(item), cond.Op, cond.Value)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func evaluate(actual interface{}, op query.Op, expected interface{}) (bool, error) {
+	if op == query.OpIn {
+		switch values := expected.(type) {
+		case []int:
+			for _, v := range values {
+				if c, err := compare(actual, v); err == nil && c == 0 {
+					return true, nil
+				}
+			}
+			return false, nil
+		case []string:
+			for _, v := range values {
+				if c, err := compare(actual, v); err == nil && c == 0 {
+					return true, nil
+				}
+			}
+			return false, nil
+		default:
+			return false, fmt.Errorf("operator in requires a list, got %T", expected)
+		}
+	}
+
+	c, err := compare(actual, expected)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case query.OpEq:
+		return c == 0, nil
+	case query.OpNe:
+		return c != 0, nil
+	case query.OpGt:
+		return c > 0, nil
+	case query.OpGte:
+		return c >= 0, nil
+	case query.OpLt:
+		return c < 0, nil
+	case query.OpLte:
+		return c <= 0, nil
+	default:
+		return false, fmt.Errorf("unknown filter operator %q", op)
+	}
+}
+
+// compare はaとbを比較し、a<bなら負、a==bなら0、a>bなら正を返す
+// 数値はintとfloat64を区別せずに比較する
+func compare(a, b interface{}) (int, error) {
+	switch av := a.(type) {
+	case int:
+		return compare(float64(av), b)
+	case float64:
+		var bv float64
+		switch v := b.(type) {
+		case int:
+			bv = float64(v)
+		case float64:
+			bv = v
+		default:
+			return 0, fmt.Errorf("cannot compare number with %T", b)
+		}
+		switch {
+		case av < bv:
+			return -1, nil
+		case av > bv:
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, fmt.Errorf("cannot compare string with %T", b)
+		}
+		switch {
+		case av < bv:
+			return -1, nil
+		case av > bv:
+			return 1, nil
+		}
+		return 0, nil
+	case time.Time:
+		bv, ok := b.(time.Time)
+		if !ok {
+			return 0, fmt.Errorf("cannot compare time with %T", b)
+		}
+		switch {
+		case av.Before(bv):
+			return -1, nil
+		case av.After(bv):
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unsupported field type %T", a)
+	}
+}
+
+// insertSorted はitemを主キー順の位置に挿入する
+// 同じ主キーの要素がある場合は、DBの一意制約違反と同様にエラーを返す
+func insertSorted[T any](items []*T, item *T, id func(*T) int) ([]*T, error) {
+	for i, existing := range items {
+		switch {
+		case id(existing) == id(item):
+			return items, fmt.Errorf("duplicate primary key %d", id(item))
+		case id(existing) > id(item):
+			items = append(items[:i+1], items[i:]...)
+			items[i] = item
+			return items, nil
+		}
+	}
+	return append(items, item), nil
+}
+
+// copyAll は呼び出し側の変更が保持している値に影響しないよう、要素をコピーして返す
+func copyAll[T any](items []*T) []*T {
+	copied := make([]*T, len(items))
+	for i, item := range items {
+		v := *item
+		copied[i] = &v
+	}
+	return copied
+}
+
+// each はitemsを順にfnに渡す。DBのクエリと同じく、途中でctxが終了すればそのエラーを返す
+func each[T any](ctx context.Context, items []*T, fn func(*T) error) error {
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package persistence
 
 import (
+	"context"
 	"errors"
 
 	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
 	"github.com/backend-guchitter-app/domain/repository"
 	"github.com/backend-guchitter-app/logging"
 	"github.com/bloom42/rz-go"
@@ -21,8 +23,8 @@ func NewAvatarPersistence(conn *gorm.DB) repository.AvatarRepository {
 	}
 }
 
-func (cp *avatarPersistence) FindAll() (avatarList []*model.Avatar, err error) {
-	db := cp.Conn
+func (cp *avatarPersistence) FindAll(ctx context.Context) (avatarList []*model.Avatar, err error) {
+	db := cp.Conn.WithContext(ctx)
 
 	if err := db.Find(&avatarList).Error; err != nil {
 		return nil, err
@@ -31,8 +33,8 @@ func (cp *avatarPersistence) FindAll() (avatarList []*model.Avatar, err error) {
 	return avatarList, nil
 }
 
-func (cp *avatarPersistence) FindByAvatarId(id int) (avatar *model.Avatar, err error) {
-	db := cp.Conn
+func (cp *avatarPersistence) FindByAvatarId(ctx context.Context, id int) (avatar *model.Avatar, err error) {
+	db := cp.Conn.WithContext(ctx)
 
 	// Typeormみたくカラム名をキャメルケース(avatarId)にするとエラー
 	err = db.First(&avatar, "avatar_id = ?", id).Error
@@ -45,8 +47,8 @@ func (cp *avatarPersistence) FindByAvatarId(id int) (avatar *model.Avatar, err e
 	return avatar, nil
 }
 
-func (cp *avatarPersistence) Create(avatar model.Avatar) (*model.Avatar, error) {
-	db := cp.Conn
+func (cp *avatarPersistence) Create(ctx context.Context, avatar model.Avatar) (*model.Avatar, error) {
+	db := cp.Conn.WithContext(ctx)
 
 	result := db.Create(&avatar)
 
@@ -54,31 +56,38 @@ func (cp *avatarPersistence) Create(avatar model.Avatar) (*model.Avatar, error)
 		return nil, result.Error
 	}
 
-	logging.Log.Debug("avatar", rz.Any("avatar", avatar))
+	logging.FromContext(ctx).Debug("avatar", rz.Any("avatar", avatar))
 
 	return &avatar, nil
 }
 
-func (cp *avatarPersistence) FindBetweenTimestamp(from, to string) (avatarList []*model.Avatar, err error) {
-	db := cp.Conn
-
-	chain := db.Where("")
-	if from != "" {
-		chain.Where("last_update >= ?", from)
-	}
-	if to != "" {
-		chain.Where("last_update <= ?", to)
+// Find はspecの条件に一致するAvatarを返す
+func (cp *avatarPersistence) Find(ctx context.Context, spec query.Spec) (avatarList []*model.Avatar, err error) {
+	db, err := applySpec(cp.Conn.WithContext(ctx), spec, avatarColumns)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := chain.Find(&avatarList).Error; err != nil {
+	// ページングで結果が揺れないよう主キー順に並べる
+	if err := db.Order("avatar_id").Find(&avatarList).Error; err != nil {
 		return nil, err
 	}
 
 	return avatarList, nil
 }
 
-func (cp *avatarPersistence) DeleteByAvatarId(id int) error {
-	db := cp.Conn
+// Each はspecの条件に一致するAvatarを主キー順に1件ずつfnに渡す。ページングもspecに従う
+func (cp *avatarPersistence) Each(ctx context.Context, spec query.Spec, fn func(*model.Avatar) error) error {
+	db, err := applySpec(cp.Conn.WithContext(ctx), spec, avatarColumns)
+	if err != nil {
+		return err
+	}
+
+	return eachRow(db.Order("avatar_id"), fn)
+}
+
+func (cp *avatarPersistence) DeleteByAvatarId(ctx context.Context, id int) error {
+	db := cp.Conn.WithContext(ctx)
 
 	if err := db.
 		Clauses(clause.Returning{}).
@@ -89,3 +98,41 @@ func (cp *avatarPersistence) DeleteByAvatarId(id int) error {
 
 	return nil
 }
+
+// UpdateImage はimage_fileとimage_urlを更新する。last_updateはGORMが更新する
+func (cp *avatarPersistence) UpdateImage(ctx context.Context, id int, imageFile string, imageUrl string) (*model.Avatar, error) {
+	db := cp.Conn.WithContext(ctx)
+
+	result := db.Model(&model.Avatar{}).
+		Where("avatar_id = ?", id).
+		Updates(map[string]interface{}{"image_file": imageFile, "image_url": imageUrl})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return cp.FindByAvatarId(ctx, id)
+}
+
+func (cp *avatarPersistence) CreateBatch(ctx context.Context, avatars []model.Avatar) ([]*model.Avatar, error) {
+	db := cp.Conn.WithContext(ctx)
+
+	// 呼び出し側のスライスに採番したIDを書き込まないようコピーする
+	created := append([]model.Avatar(nil), avatars...)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&created, createBatchSize).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	avatarList := make([]*model.Avatar, len(created))
+	for i := range created {
+		avatarList[i] = &created[i]
+	}
+	logging.FromContext(ctx).Debug("avatars", rz.Int("count", len(avatarList)))
+
+	return avatarList, nil
+}
@@ -1,9 +1,11 @@
 package persistence
 
 import (
+	"context"
 	"errors"
 
 	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
 	"github.com/backend-guchitter-app/domain/repository"
 	"github.com/backend-guchitter-app/logging"
 	"github.com/bloom42/rz-go"
@@ -11,6 +13,10 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// CreateInBatchesで1回のINSERTにまとめる件数
+// MySQLのプレースホルダ数の上限(65535)を超えないよう、カラム数×件数を抑える
+const createBatchSize = 100
+
 type complaintPersistence struct {
 	Conn *gorm.DB
 }
@@ -21,8 +27,8 @@ func NewComplaintPersistence(conn *gorm.DB) repository.ComplaintRepository {
 	}
 }
 
-func (cp *complaintPersistence) FindAll() (complaintList []*model.Complaint, err error) {
-	db := cp.Conn
+func (cp *complaintPersistence) FindAll(ctx context.Context) (complaintList []*model.Complaint, err error) {
+	db := cp.Conn.WithContext(ctx)
 
 	if err := db.Find(&complaintList).Error; err != nil {
 		return nil, err
@@ -31,8 +37,8 @@ func (cp *complaintPersistence) FindAll() (complaintList []*model.Complaint, err
 	return complaintList, nil
 }
 
-func (cp *complaintPersistence) FindByAvatarId(id int) (complaint *model.Complaint, err error) {
-	db := cp.Conn
+func (cp *complaintPersistence) FindByAvatarId(ctx context.Context, id int) (complaint *model.Complaint, err error) {
+	db := cp.Conn.WithContext(ctx)
 
 	// Typeormみたくカラム名をキャメルケース(avatarId)にするとエラー
 	err = db.First(&complaint, "avatar_id = ?", id).Error
@@ -45,8 +51,8 @@ func (cp *complaintPersistence) FindByAvatarId(id int) (complaint *model.Complai
 	return complaint, nil
 }
 
-func (cp *complaintPersistence) Create(complaint model.Complaint) (*model.Complaint, error) {
-	db := cp.Conn
+func (cp *complaintPersistence) Create(ctx context.Context, complaint model.Complaint) (*model.Complaint, error) {
+	db := cp.Conn.WithContext(ctx)
 
 	result := db.Create(&complaint)
 
@@ -54,31 +60,38 @@ func (cp *complaintPersistence) Create(complaint model.Complaint) (*model.Compla
 		return nil, result.Error
 	}
 
-	logging.Log.Debug("complaint", rz.Any("complaint", complaint))
+	logging.FromContext(ctx).Debug("complaint", rz.Any("complaint", complaint))
 
 	return &complaint, nil
 }
 
-func (cp *complaintPersistence) FindBetweenTimestamp(from, to string) (complaintList []*model.Complaint, err error) {
-	db := cp.Conn
-
-	chain := db.Where("")
-	if from != "" {
-		chain.Where("last_update >= ?", from)
-	}
-	if to != "" {
-		chain.Where("last_update <= ?", to)
+// Find はspecの条件に一致するComplaintを返す
+func (cp *complaintPersistence) Find(ctx context.Context, spec query.Spec) (complaintList []*model.Complaint, err error) {
+	db, err := applySpec(cp.Conn.WithContext(ctx), spec, complaintColumns)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := chain.Find(&complaintList).Error; err != nil {
+	// ページングで結果が揺れないよう主キー順に並べる
+	if err := db.Order("complaint_id").Find(&complaintList).Error; err != nil {
 		return nil, err
 	}
 
 	return complaintList, nil
 }
 
-func (cp *complaintPersistence) DeleteByComplaintId(id int) error {
-	db := cp.Conn
+// Each はspecの条件に一致するComplaintを主キー順に1件ずつfnに渡す。ページングもspecに従う
+func (cp *complaintPersistence) Each(ctx context.Context, spec query.Spec, fn func(*model.Complaint) error) error {
+	db, err := applySpec(cp.Conn.WithContext(ctx), spec, complaintColumns)
+	if err != nil {
+		return err
+	}
+
+	return eachRow(db.Order("complaint_id"), fn)
+}
+
+func (cp *complaintPersistence) DeleteByComplaintId(ctx context.Context, id int) error {
+	db := cp.Conn.WithContext(ctx)
 
 	if err := db.
 		Clauses(clause.Returning{}).
@@ -89,3 +102,56 @@ func (cp *complaintPersistence) DeleteByComplaintId(id int) error {
 
 	return nil
 }
+
+func (cp *complaintPersistence) IncrementDuplicateCount(ctx context.Context, id int) (complaint *model.Complaint, err error) {
+	db := cp.Conn.WithContext(ctx)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 同時にまとめられても数え漏れないよう、DB側で加算する。last_updateも更新される
+		result := tx.Model(&model.Complaint{}).
+			Where("complaint_id = ?", id).
+			Update("duplicate_count", gorm.Expr("duplicate_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return tx.First(&complaint, "complaint_id = ?", id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return complaint, nil
+}
+
+func (cp *complaintPersistence) CreateBatch(ctx context.Context, complaints []model.Complaint) ([]*model.Complaint, error) {
+	db := cp.Conn.WithContext(ctx)
+
+	// 呼び出し側のスライスに採番したIDを書き込まないようコピーする
+	created := append([]model.Complaint(nil), complaints...)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&created, createBatchSize).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	complaintList := make([]*model.Complaint, len(created))
+	for i := range created {
+		complaintList[i] = &created[i]
+	}
+	logging.FromContext(ctx).Debug("complaints", rz.Int("count", len(complaintList)))
+
+	return complaintList, nil
+}
+
+func (cp *complaintPersistence) DeleteByComplaintIds(ctx context.Context, ids []int) error {
+	db := cp.Conn.WithContext(ctx)
+
+	// 1つのDELETE文で削除するため、途中で失敗しても一部だけ削除されることはない
+	return db.
+		Where("complaint_id IN ?", ids).
+		Delete(&model.Complaint{}).Error
+}
@@ -0,0 +1,46 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Ping はコネクションプールからDBに接続できることを確認する
+func Ping(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MigrationsApplied はschema_migrationsのバージョンがlatest以上で、dirtyでないことを確認する
+// DBのバージョンがlatestより新しい場合は、新しいバージョンのデプロイ中とみなして問題なしとする
+func MigrationsApplied(db *gorm.DB, latest uint) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var (
+			version uint
+			dirty   bool
+		)
+		err := db.WithContext(ctx).Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Row().Scan(&version, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no migrations applied, latest is %d", latest)
+		}
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version < latest {
+			return fmt.Errorf("pending migrations: applied %d, latest is %d", version, latest)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/backend-guchitter-app/idempotency"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 期限切れのRecordを削除して予約し直す回数の上限
+const idempotencyBeginAttempts = 3
+
+type idempotencyStore struct {
+	Conn *gorm.DB
+}
+
+// NewIdempotencyStore はidempotency_keysテーブルにレスポンスを保存するStoreを返す
+// 予約は主キーの一意制約で行うため、複数のプロセスで同じキーを予約しても成功するのは1つだけになる
+func NewIdempotencyStore(conn *gorm.DB) idempotency.Store {
+	return &idempotencyStore{
+		Conn: conn,
+	}
+}
+
+func (s *idempotencyStore) Begin(ctx context.Context, key, requestHash string, lockTimeout time.Duration) (*idempotency.Record, error) {
+	db := s.Conn.WithContext(ctx)
+
+	for attempt := 0; attempt < idempotencyBeginAttempts; attempt++ {
+		now := time.Now()
+		// 既にあればINSERTせず、RowsAffectedが0になる
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&idempotency.Record{
+			Key:         key,
+			RequestHash: requestHash,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(lockTimeout),
+		})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil, nil
+		}
+
+		var existing idempotency.Record
+		err := db.First(&existing, "idempotency_key = ?", key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// INSERTの後に予約が取り消された
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if existing.ExpiresAt.After(now) {
+			return &existing, nil
+		}
+
+		// 期限切れなら削除して予約し直す。他のリクエストが先に予約し直した場合は、期限内なので削除されない
+		if err := db.Where("idempotency_key = ? AND expires_at <= ?", key, now).Delete(&idempotency.Record{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("idempotency key %s: could not be reserved after %d attempts", key, idempotencyBeginAttempts)
+}
+
+func (s *idempotencyStore) Complete(ctx context.Context, key string, response idempotency.Response, ttl time.Duration) error {
+	db := s.Conn.WithContext(ctx)
+
+	return db.Model(&idempotency.Record{}).
+		Where("idempotency_key = ? AND completed = ?", key, false).
+		Updates(map[string]interface{}{
+			"completed":    true,
+			"status":       response.Status,
+			"content_type": response.ContentType,
+			"body":         response.Body,
+			"expires_at":   time.Now().Add(ttl),
+		}).Error
+}
+
+func (s *idempotencyStore) Release(ctx context.Context, key string) error {
+	db := s.Conn.WithContext(ctx)
+
+	return db.
+		Where("idempotency_key = ? AND completed = ?", key, false).
+		Delete(&idempotency.Record{}).Error
+}
+
+func (s *idempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	db := s.Conn.WithContext(ctx)
+
+	result := db.Where("expires_at <= ?", now).Delete(&idempotency.Record{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
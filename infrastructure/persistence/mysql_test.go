@@ -0,0 +1,98 @@
+package persistence_test
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate"
+	migratemysql "github.com/golang-migrate/migrate/database/mysql"
+	_ "github.com/golang-migrate/migrate/source/file"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/backend-guchitter-app/domain/repository"
+	"github.com/backend-guchitter-app/domain/repository/repositorytest"
+	"github.com/backend-guchitter-app/infrastructure/persistence"
+	"github.com/backend-guchitter-app/logging"
+)
+
+// MySQLで契約を検証するときの接続文字列。未設定の場合はMySQLのテストをスキップする
+// テーブルを空にするため、テスト専用のDBを指定する
+// 例: guchitter:guchitter@tcp(localhost:3306)/guchitter_test?charset=utf8mb4&parseTime=True&loc=Local&multiStatements=true
+const mysqlDSNEnv = "guchitter_TEST_MYSQL_DSN"
+
+// db/migrationsのパス。テストはパッケージのディレクトリで実行される
+const migrationsURL = "file://../../db/migrations"
+
+func TestComplaintPersistenceMySQL(t *testing.T) {
+	db := newMySQL(t)
+	repositorytest.ComplaintRepositoryContract(t, func(t *testing.T) repository.ComplaintRepository {
+		truncate(t, db)
+		return persistence.NewComplaintPersistence(db)
+	})
+}
+
+func TestAvatarPersistenceMySQL(t *testing.T) {
+	db := newMySQL(t)
+	repositorytest.AvatarRepositoryContract(t, func(t *testing.T) repository.AvatarRepository {
+		truncate(t, db)
+		return persistence.NewAvatarPersistence(db)
+	})
+}
+
+// newMySQL はguchitter_TEST_MYSQL_DSNのMySQLにdb/migrationsを適用して接続する
+func newMySQL(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(mysqlDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", mysqlDSNEnv)
+	}
+	migrateUp(t, dsn)
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logging.NewGormLogger(logging.DefaultOptions())})
+	if err != nil {
+		t.Fatalf("connect to MySQL: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// migrateUp はdb/migrationsを最新のバージョンまで適用する。go run ./db upと同じ
+func migrateUp(t *testing.T, dsn string) {
+	t.Helper()
+	// テストで使う接続とは分け、適用が終われば閉じる
+	sqlDB, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open(): %v", err)
+	}
+	defer sqlDB.Close()
+	driver, err := migratemysql.WithInstance(sqlDB, &migratemysql.Config{})
+	if err != nil {
+		t.Fatalf("mysql.WithInstance(): %v", err)
+	}
+	m, err := migrate.NewWithDatabaseInstance(migrationsURL, "mysql", driver)
+	if err != nil {
+		t.Fatalf("migrate.NewWithDatabaseInstance(): %v", err)
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrate up: %v (fix a dirty version with go run ./db force)", err)
+	}
+}
+
+// truncate はサブテストごとにテーブルを空にし、IDを1から振り直す
+func truncate(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, table := range []string{"complaints", "avatar", "idempotency_keys"} {
+		if err := db.Exec("TRUNCATE TABLE " + table).Error; err != nil {
+			t.Fatalf("truncate %s: %v", table, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package persistence
+
+import (
+	"fmt"
+
+	"github.com/backend-guchitter-app/domain/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// APIのフィールド名とカラム名の対応
+// ここにないフィールドはSQLに渡さない
+var complaintColumns = map[string]string{
+	"complaintId": "complaint_id",
+	"avatarId":    "avatar_id",
+	"negativity":  "negativity",
+	"anger":       "anger",
+	"sadness":     "sadness",
+	"createdAt":   "created_at",
+	"lastUpdate":  "last_update",
+}
+
+var avatarColumns = map[string]string{
+	"avatarId":   "avatar_id",
+	"avatarName": "avatar_name",
+	"color":      "color",
+	"createdAt":  "created_at",
+	"lastUpdate": "last_update",
+}
+
+// applySpec はspecの条件とページングをGORMのclauseに変換してdbに追加する
+// カラム名はcolumnsのホワイトリストから引き、値は全てプレースホルダで渡す
+func applySpec(db *gorm.DB, spec query.Spec, columns map[string]string) (*gorm.DB, error) {
+	for _, cond := range spec.Conditions {
+		name, ok := columns[cond.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter field %q", cond.Field)
+		}
+		column := clause.Column{Name: name}
+
+		var expr clause.Expression
+		switch cond.Op {
+		case query.OpEq:
+			expr = clause.Eq{Column: column, Value: cond.Value}
+		case query.OpNe:
+			expr = clause.Neq{Column: column, Value: cond.Value}
+		case query.OpGt:
+			expr = clause.Gt{Column: column, Value: cond.Value}
+		case query.OpGte:
+			expr = clause.Gte{Column: column, Value: cond.Value}
+		case query.OpLt:
+			expr = clause.Lt{Column: column, Value: cond.Value}
+		case query.OpLte:
+			expr = clause.Lte{Column: column, Value: cond.Value}
+		case query.OpIn:
+			values, err := toInterfaceSlice(cond.Value)
+			if err != nil {
+				return nil, err
+			}
+			expr = clause.IN{Column: column, Values: values}
+		default:
+			return nil, fmt.Errorf("unknown filter operator %q", cond.Op)
+		}
+		db = db.Where(expr)
+	}
+
+	if spec.Limit > 0 {
+		db = db.Limit(spec.Limit)
+	}
+	if spec.Offset > 0 {
+		db = db.Offset(spec.Offset)
+	}
+	return db, nil
+}
+
+func toInterfaceSlice(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case []int:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = v[i]
+		}
+		return values, nil
+	case []string:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = v[i]
+		}
+		return values, nil
+	case []interface{}:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("operator in requires a list, got %T", value)
+	}
+}
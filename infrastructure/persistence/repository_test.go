@@ -0,0 +1,40 @@
+package persistence_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/backend-guchitter-app/config"
+	"github.com/backend-guchitter-app/domain/repository"
+	"github.com/backend-guchitter-app/domain/repository/repositorytest"
+	"github.com/backend-guchitter-app/infrastructure/persistence"
+)
+
+// SQLiteはモデルから作ったテーブルで契約を検証する。db/migrationsのMySQLはmysql_test.goで検証する
+func TestComplaintPersistence(t *testing.T) {
+	repositorytest.ComplaintRepositoryContract(t, func(t *testing.T) repository.ComplaintRepository {
+		return persistence.NewComplaintPersistence(newSQLite(t))
+	})
+}
+
+func TestAvatarPersistence(t *testing.T) {
+	repositorytest.AvatarRepositoryContract(t, func(t *testing.T) repository.AvatarRepository {
+		return persistence.NewAvatarPersistence(newSQLite(t))
+	})
+}
+
+// newSQLite はテストごとに空のインメモリのSQLiteを返す
+func newSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db := config.ConnectSQLite(fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
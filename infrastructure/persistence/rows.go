@@ -0,0 +1,27 @@
+package persistence
+
+import (
+	"gorm.io/gorm"
+)
+
+// eachRow はdbのクエリの結果を1行ずつTに読み込んでfnに渡す
+// Rows()で読むため、結果の全件をメモリに載せない。fnが返るまでDBの接続を1本使い続ける
+func eachRow[T any](db *gorm.DB, fn func(*T) error) error {
+	var model T
+	rows, err := db.Model(&model).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
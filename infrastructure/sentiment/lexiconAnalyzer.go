@@ -0,0 +1,136 @@
+package sentiment
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/service"
+)
+
+// 同梱の日本語極性辞書
+// 形式: 語<TAB>極性(-1〜1)<TAB>怒り(0〜1)<TAB>悲しみ(0〜1)
+//
+//go:embed lexicon.tsv
+var bundledLexicon string
+
+// 否定表現。辞書語の直後に続く場合は極性を反転する
+var negations = []string{"ない", "なかっ", "ません", "ず"}
+
+// 辞書の1エントリ
+type entry struct {
+	polarity float64
+	anger    float64
+	sadness  float64
+}
+
+type lexiconAnalyzer struct {
+	tokenizer *tokenizer
+}
+
+// NewLexiconAnalyzer は同梱の極性辞書を使うSentimentAnalyzerを返す
+// 辞書はバイナリに埋め込まれているため、外部サービスやファイルに依存しない
+func NewLexiconAnalyzer() service.SentimentAnalyzer {
+	dict, err := parseLexicon(bundledLexicon)
+	if err != nil {
+		// 同梱辞書が壊れているのはビルドの問題なので起動時に落とす
+		panic(err)
+	}
+	return &lexiconAnalyzer{
+		tokenizer: newTokenizer(dict),
+	}
+}
+
+// Analyze はtextを形態素に分割し、辞書にヒットした語のスコアを平均する
+// ヒットしない場合は全て0を返す
+func (la *lexiconAnalyzer) Analyze(text string) model.Sentiment {
+	tokens := la.tokenizer.tokenize(text)
+
+	var negativity, anger, sadness float64
+	hits := 0
+	for i, tk := range tokens {
+		if tk.entry == nil {
+			continue
+		}
+		hits++
+
+		e := *tk.entry
+		if i+1 < len(tokens) && isNegation(tokens[i+1].surface) {
+			// 「悲しくない」「むかつかない」等は極性を反転し、感情は打ち消す
+			e = entry{polarity: -e.polarity}
+		}
+		negativity += -e.polarity
+		anger += e.anger
+		sadness += e.sadness
+	}
+
+	if hits == 0 {
+		return model.Sentiment{}
+	}
+
+	n := float64(hits)
+	return model.Sentiment{
+		Negativity: round(clamp(negativity / n)),
+		Anger:      round(clamp(anger / n)),
+		Sadness:    round(clamp(sadness / n)),
+	}
+}
+
+// isNegation は活用語尾(く, か, じゃ等)を2文字まで読み飛ばして否定表現が続くかを判定する
+func isNegation(surface string) bool {
+	runes := []rune(surface)
+	for skip := 0; skip <= 2 && skip < len(runes); skip++ {
+		rest := string(runes[skip:])
+		for _, neg := range negations {
+			if strings.HasPrefix(rest, neg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseLexicon(src string) (map[string]*entry, error) {
+	dict := map[string]*entry{}
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		cols := strings.Split(text, "\t")
+		if len(cols) != 4 {
+			return nil, fmt.Errorf("lexicon line %d: expected 4 columns, got %d", line, len(cols))
+		}
+		values := make([]float64, 3)
+		for i, col := range cols[1:] {
+			v, err := strconv.ParseFloat(col, 64)
+			if err != nil {
+				return nil, fmt.Errorf("lexicon line %d: %w", line, err)
+			}
+			values[i] = v
+		}
+		dict[strings.ToLower(cols[0])] = &entry{
+			polarity: values[0],
+			anger:    values[1],
+			sadness:  values[2],
+		}
+	}
+	return dict, scanner.Err()
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// DBのdecimal(4,3)に合わせて小数第3位で丸める
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
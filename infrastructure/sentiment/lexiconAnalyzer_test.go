@@ -0,0 +1,86 @@
+package sentiment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/backend-guchitter-app/domain/model"
+)
+
+func TestAnalyze(t *testing.T) {
+	analyzer := NewLexiconAnalyzer()
+	tests := []struct {
+		name string
+		text string
+		want model.Sentiment
+	}{
+		{name: "negative", text: "悲しい", want: model.Sentiment{Negativity: 0.8, Anger: 0.1, Sadness: 1.0}},
+		{name: "negated", text: "悲しくない", want: model.Sentiment{}},
+		{name: "negated in past tense", text: "悲しくなかった", want: model.Sentiment{}},
+		{name: "katakana", text: "イライラする", want: model.Sentiment{Negativity: 0.8, Anger: 0.9, Sadness: 0.1}},
+		{name: "katakana inside hiragana", text: "ほんとにムカつく", want: model.Sentiment{Negativity: 0.9, Anger: 1.0, Sadness: 0.1}},
+		// 肯定と否定の語の平均。極性は打ち消し合い、感情は半分になる
+		{name: "mixed", text: "楽しいけど悲しい", want: model.Sentiment{Negativity: 0, Anger: 0.05, Sadness: 0.5}},
+		{name: "positive", text: "最高!", want: model.Sentiment{}},
+		{name: "no hit", text: "駅まで歩いた", want: model.Sentiment{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzer.Analyze(tt.text); got != tt.want {
+				t.Errorf("Analyze(%q) = %+v; want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLexicon(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    map[string]entry
+		wantErr string
+	}{
+		{
+			name: "valid",
+			src:  "# 語\t極性\t怒り\t悲しみ\n\n悲し\t-0.8\t0.1\t1.0\nOK\t0.5\t0\t0\n",
+			want: map[string]entry{"悲し": {polarity: -0.8, anger: 0.1, sadness: 1.0}, "ok": {polarity: 0.5}},
+		},
+		{name: "missing column", src: "悲し\t-0.8\t0.1\t1.0\nムカつ\t-0.9\t1.0\n", wantErr: "lexicon line 2: expected 4 columns, got 3"},
+		{name: "separated by spaces", src: "悲し -0.8 0.1 1.0\n", wantErr: "lexicon line 1: expected 4 columns, got 1"},
+		{name: "not a number", src: "# comment\n悲し\t-0.8\thigh\t1.0\n", wantErr: "lexicon line 2: strconv.ParseFloat: parsing \"high\": invalid syntax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dict, err := parseLexicon(tt.src)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseLexicon() error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLexicon() error = %v", err)
+			}
+			if len(dict) != len(tt.want) {
+				t.Fatalf("parseLexicon() has %d entries; want %d", len(dict), len(tt.want))
+			}
+			for word, want := range tt.want {
+				if got := dict[word]; got == nil || *got != want {
+					t.Errorf("parseLexicon()[%q] = %+v; want %+v", word, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBundledLexicon(t *testing.T) {
+	dict, err := parseLexicon(bundledLexicon)
+	if err != nil {
+		t.Fatalf("bundled lexicon: %v", err)
+	}
+	for word, e := range dict {
+		if word != strings.TrimSpace(word) || e.polarity < -1 || e.polarity > 1 || e.anger < 0 || e.anger > 1 || e.sadness < 0 || e.sadness > 1 {
+			t.Errorf("bundled lexicon entry %q = %+v is out of range", word, *e)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package sentiment
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 文字種
+type charClass int
+
+const (
+	classOther charClass = iota
+	classKanji
+	classHiragana
+	classKatakana
+	classAlnum
+)
+
+// token は形態素(もどき)
+// entryが非nilの場合は辞書にヒットした語
+type token struct {
+	surface string
+	entry   *entry
+}
+
+// tokenizer は辞書の最長一致と文字種の切れ目による簡易形態素解析器
+// MeCab等の外部辞書に依存せず、オフラインで動作する
+type tokenizer struct {
+	dict map[string]*entry
+	// 辞書中の最長語の文字数
+	maxLen int
+}
+
+func newTokenizer(dict map[string]*entry) *tokenizer {
+	maxLen := 0
+	for word := range dict {
+		if n := len([]rune(word)); n > maxLen {
+			maxLen = n
+		}
+	}
+	return &tokenizer{dict: dict, maxLen: maxLen}
+}
+
+// tokenize は textを形態素に分割する
+// 各位置で辞書の最長一致を試み、ヒットしなければ同じ文字種が続く範囲を1語とする
+func (t *tokenizer) tokenize(text string) []token {
+	runes := []rune(strings.ToLower(text))
+	tokens := []token{}
+
+	for i := 0; i < len(runes); {
+		if isSeparator(runes[i]) {
+			i++
+			continue
+		}
+
+		if n, e := t.longestMatch(runes[i:]); e != nil {
+			tokens = append(tokens, token{surface: string(runes[i : i+n]), entry: e})
+			i += n
+			continue
+		}
+
+		// 未知語: 同じ文字種が続く間を1語とする
+		// ただし途中から辞書語が始まる場合はそこで区切る
+		class := classOf(runes[i])
+		j := i + 1
+		for j < len(runes) && classOf(runes[j]) == class && !isSeparator(runes[j]) {
+			if n, _ := t.longestMatch(runes[j:]); n > 0 {
+				break
+			}
+			j++
+		}
+		tokens = append(tokens, token{surface: string(runes[i:j])})
+		i = j
+	}
+
+	return tokens
+}
+
+// longestMatch は runesの先頭から辞書に一致する最長の語を返す
+func (t *tokenizer) longestMatch(runes []rune) (int, *entry) {
+	n := t.maxLen
+	if len(runes) < n {
+		n = len(runes)
+	}
+	for ; n > 0; n-- {
+		if e, ok := t.dict[string(runes[:n])]; ok {
+			return n, e
+		}
+	}
+	return 0, nil
+}
+
+func classOf(r rune) charClass {
+	switch {
+	case unicode.Is(unicode.Han, r) || r == '々':
+		return classKanji
+	case unicode.Is(unicode.Hiragana, r):
+		return classHiragana
+	case unicode.Is(unicode.Katakana, r) || r == 'ー':
+		return classKatakana
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		return classAlnum
+	default:
+		return classOther
+	}
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"strconv"
 
 	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
 	"github.com/backend-guchitter-app/logging"
 	"github.com/backend-guchitter-app/usecase"
 	"github.com/bloom42/rz-go"
@@ -19,32 +21,49 @@ type AvatarHandler interface {
 	Create(c *gin.Context)
 	FindBetweenTimestamp(c *gin.Context)
 	DeleteByAvatarId(c *gin.Context)
+	UploadImage(c *gin.Context)
+	Image(c *gin.Context)
 }
 
 type avatarHandler struct {
 	avatarUseCase usecase.AvatarUseCase
+	images        AvatarImagePolicy
 }
 
 // NewAvatarHandler is the initializer.
-func NewAvatarHandler(cu usecase.AvatarUseCase) AvatarHandler {
+func NewAvatarHandler(cu usecase.AvatarUseCase, images AvatarImagePolicy) AvatarHandler {
 	return &avatarHandler{
 		avatarUseCase: cu,
+		images:        images,
 	}
 }
 
 // Index
 // @Summary Avatarsを全件取得
+// @Description filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in
+// @Description fieldはavatarId, avatarName, color, createdAt, lastUpdate
 // @Tags Avatars
 // @Produce json
+// @Param filter[avatarName] query string false "アバター名"
+// @Param filter[lastUpdate][gte] query string false "更新日時の下限(例: -24h)"
+// @Param filter[lastUpdate][lte] query string false "更新日時の上限"
+// @Param tz query string false "Asia/Tokyo"
 // @Success 200 {array} model.Avatar
 // @Failure 400
 // @Failure 500
 // @Router /avatars [get]
 func (ch avatarHandler) Index(c *gin.Context) {
-	avatars, err := ch.avatarUseCase.FindAll()
+	spec, err := ParseFilterQuery(c, query.AvatarSchema, time.Now())
 	if err != nil {
-		logging.Log.Error("Failed at FindAll()", rz.Err(err))
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	avatars, err := ch.avatarUseCase.Find(c.Request.Context(), spec)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at Find()", rz.Err(err))
+		respondError(c, err)
+		return
 	}
 	c.IndentedJSON(http.StatusOK, avatars)
 }
@@ -60,12 +79,14 @@ func (ch avatarHandler) Index(c *gin.Context) {
 // @Router /avatars/{id} [get]
 func (ch avatarHandler) Search(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	avatar, err := ch.avatarUseCase.FindByAvatarId(id)
+	avatar, err := ch.avatarUseCase.FindByAvatarId(c.Request.Context(), id)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		respondError(c, err)
+		return
 	}
 	if avatar == nil {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Not Found"})
+		return
 	}
 	c.IndentedJSON(http.StatusOK, avatar)
 }
@@ -75,8 +96,10 @@ func (ch avatarHandler) Search(c *gin.Context) {
 // @Tags Avatars
 // @Produce json
 // @Param body body model.Avatar false "Avatar"
+// @Param Idempotency-Key header string false "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す"
 // @Success 200 {object} model.Avatar "登録したAvatar"
 // @Failure 400
+// @Failure 422 "同じIdempotency-Keyを異なるボディで使った"
 // @Failure 500
 // @Router /avatars [post]
 func (ch avatarHandler) Create(c *gin.Context) {
@@ -87,32 +110,44 @@ func (ch avatarHandler) Create(c *gin.Context) {
 	if err := c.BindJSON(&newAvatar); err != nil {
 		return
 	}
-	result, err := ch.avatarUseCase.Create(*newAvatar)
+	result, err := ch.avatarUseCase.Create(c.Request.Context(), *newAvatar)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		respondError(c, err)
+		return
 	}
 	c.IndentedJSON(http.StatusOK, result)
 }
 
 // FindBetweenTimestamp
-// @Summary 更新日時がfrom, toの間のAvatarを返す
+// @Summary 更新日時がfrom, toの間のAvatarを返す(非推奨)
+// @Deprecated
+// @Description GET /avatars?filter[lastUpdate][gte]=...&filter[lastUpdate][lte]=... を使うこと
+// @Description from, toはRFC 3339、タイムゾーンなしの日時(tzで解釈)、相対指定(now, today, yesterday, -24h, -7d)を受け付ける
 // @Tags Avatars
 // @Produce json
 // @Param from query string false "2022-11-27 0:00:00"
 // @Param to query string false "2022-11-28 0:00:00"
+// @Param tz query string false "Asia/Tokyo"
 // @Success 200 {array} model.Avatar
 // @Failure 400
+// @Failure 404
 // @Failure 500
 // @Router /avatars/between-time [get]
 func (ch avatarHandler) FindBetweenTimestamp(c *gin.Context) {
-	from := c.Query("from")
-	to := c.Query("to")
-	avatarList, err := ch.avatarUseCase.FindBetweenTimestamp(from, to)
+	from, to, err := parseTimeRangeQuery(c, time.Now())
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	avatarList, err := ch.avatarUseCase.FindBetweenTimestamp(c.Request.Context(), from, to)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at FindBetweenTimestamp()", rz.Err(err))
+		respondError(c, err)
+		return
 	}
 	if len(avatarList) == 0 {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Not Found"})
+		return
 	}
 	c.IndentedJSON(http.StatusOK, avatarList)
 }
@@ -128,9 +163,75 @@ func (ch avatarHandler) FindBetweenTimestamp(c *gin.Context) {
 // @Router /avatars/{id} [delete]
 func (ch avatarHandler) DeleteByAvatarId(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	err := ch.avatarUseCase.DeleteByAvatarId(id)
+	err := ch.avatarUseCase.DeleteByAvatarId(c.Request.Context(), id)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		respondError(c, err)
+		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// UploadImage
+// @Summary Avatarの画像をアップロードする
+// @Description PNG, JPEG, WebPを受け付け、形式はファイルの中身で判定する。中央を正方形に切り抜き、small(64px), medium(256px), large(512px)に縮小して保存する
+// @Description imageUrlは GET /avatars/{id}/image?v=<画像のハッシュ> になる
+// @Tags Avatars
+// @Accept mpfd
+// @Produce json
+// @Param id path int true "アバターID"
+// @Param image formData file true "画像(PNG, JPEG, WebP。上限はguchitter_IMAGE_MAX_BYTES、デフォルトは5MB)"
+// @Success 200 {object} model.Avatar "画像を更新したAvatar"
+// @Failure 400
+// @Failure 404
+// @Failure 413
+// @Failure 415
+// @Failure 500
+// @Router /avatars/{id}/image [post]
+func (ch avatarHandler) UploadImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "id must be an integer"})
+		return
+	}
+	body, status, err := ReadImageUpload(c, ch.images)
+	if err != nil {
+		c.IndentedJSON(status, gin.H{"message": err.Error()})
+		return
+	}
+	avatar, err := ch.avatarUseCase.UploadImage(c.Request.Context(), id, body, c.Request.URL.Path)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at UploadImage()", rz.Err(err))
+		respondError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, avatar)
+}
+
+// Image
+// @Summary Avatarのアップロードした画像を返す
+// @Description ?v=が現在の画像のハッシュと一致する場合は期限なくキャッシュでき、それ以外はETagで確認させる
+// @Tags Avatars
+// @Produce png,jpeg
+// @Param id path int true "アバターID"
+// @Param size query string false "small, medium, large" default(medium)
+// @Param v query string false "画像のハッシュ(imageUrlに含まれる)"
+// @Param If-None-Match header string false "前回のETag"
+// @Success 200 {file} binary
+// @Success 304
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /avatars/{id}/image [get]
+func (ch avatarHandler) Image(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "id must be an integer"})
+		return
+	}
+	image, err := ch.avatarUseCase.Image(c.Request.Context(), id, AvatarImageSize(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	ServeAvatarImage(c, image)
+}
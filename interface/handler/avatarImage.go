@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAvatarImageMaxBytes はAvatarImagePolicy.MaxBytesが0の場合にアップロードできる画像のバイト数の上限
+const DefaultAvatarImageMaxBytes = 5 << 20
+
+// multipart/form-dataの画像以外の部分(境界やヘッダ)に許すバイト数
+const multipartOverhead = 64 << 10
+
+// アップロードした画像の?v=付きのURLに付けるCache-Control。画像を変えるとURLが変わるので、期限なくキャッシュできる
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// ?v=のないURLや古いURLに付けるCache-Control。キャッシュしてもよいが、使う前にETagで確認させる
+const revalidateCacheControl = "public, no-cache"
+
+// アバターの画像のアップロードの上限
+type AvatarImagePolicy struct {
+	// 画像のバイト数の上限。0の場合はDefaultAvatarImageMaxBytes
+	MaxBytes int
+}
+
+func (p AvatarImagePolicy) maxBytes() int {
+	if p.MaxBytes > 0 {
+		return p.MaxBytes
+	}
+	return DefaultAvatarImageMaxBytes
+}
+
+// ReadImageUpload はmultipart/form-dataのimageのファイルを読む
+// 失敗した場合は返すステータスとエラーを返す。上限を超える場合は413、imageがない場合は400
+// 形式はファイル名やContent-Typeでなく、ユースケースで中身から判定する
+func ReadImageUpload(c *gin.Context, policy AvatarImagePolicy) ([]byte, int, error) {
+	limit := policy.maxBytes()
+	tooLarge := fmt.Errorf("image must be at most %d bytes", limit)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(limit)+multipartOverhead)
+
+	file, header, err := c.Request.FormFile("image")
+	if c.Request.MultipartForm != nil {
+		defer c.Request.MultipartForm.RemoveAll()
+	}
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return nil, http.StatusRequestEntityTooLarge, tooLarge
+	case errors.Is(err, http.ErrMissingFile):
+		return nil, http.StatusBadRequest, errors.New("image is required")
+	case err != nil:
+		return nil, http.StatusBadRequest, fmt.Errorf("image must be sent as multipart/form-data: %v", err)
+	}
+	defer file.Close()
+	if header.Size > int64(limit) {
+		return nil, http.StatusRequestEntityTooLarge, tooLarge
+	}
+
+	body, err := io.ReadAll(file)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return body, http.StatusOK, nil
+}
+
+// ServeAvatarImage はimageを返す。ETagが一致すれば304を返す
+// ?v=が現在の画像のバージョンと一致するURLは期限なく、それ以外は毎回確認させてキャッシュさせる
+func ServeAvatarImage(c *gin.Context, image *usecase.AvatarImage) {
+	defer image.Body.Close()
+
+	etag := `"` + image.Version + `"`
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if c.Query("v") == image.Version {
+		c.Header("Cache-Control", immutableCacheControl)
+	} else {
+		c.Header("Cache-Control", revalidateCacheControl)
+	}
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.DataFromReader(http.StatusOK, image.Size, image.ContentType, image.Body, nil)
+}
+
+// etagMatches はIf-None-Matchのいずれかのタグがetagと一致するかを返す。弱いタグ(W/)も一致とみなす
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// AvatarImageSize はクエリパラメータsizeを返す。省略時はmedium
+func AvatarImageSize(c *gin.Context) string {
+	return c.DefaultQuery("size", usecase.AvatarImageMedium)
+}
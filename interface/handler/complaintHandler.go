@@ -2,10 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"strconv"
 
 	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
 	"github.com/backend-guchitter-app/logging"
 	"github.com/backend-guchitter-app/usecase"
 	"github.com/bloom42/rz-go"
@@ -34,17 +36,40 @@ func NewComplaintHandler(cu usecase.ComplaintUseCase) ComplaintHandler {
 
 // Index
 // @Summary Complaintsを全件取得
+// @Description filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in
+// @Description fieldはcomplaintId, avatarId, negativity, anger, sadness, createdAt, lastUpdate
+// @Description minAngerはfilter[anger][gte]の省略形
 // @Tags Complaints
 // @Produce json
+// @Param filter[avatarId] query int false "アバターID"
+// @Param filter[lastUpdate][gte] query string false "更新日時の下限(例: -24h)"
+// @Param filter[lastUpdate][lte] query string false "更新日時の上限"
+// @Param minAnger query number false "怒りスコアの下限(0〜1)"
+// @Param tz query string false "Asia/Tokyo"
 // @Success 200 {array} model.Complaint
 // @Failure 400
 // @Failure 500
 // @Router /complaints [get]
 func (ch complaintHandler) Index(c *gin.Context) {
-	complaints, err := ch.complaintUseCase.FindAll()
+	spec, err := ParseFilterQuery(c, query.ComplaintSchema, time.Now())
 	if err != nil {
-		logging.Log.Error("Failed at FindAll()", rz.Err(err))
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if minAngerParam, ok := c.GetQuery("minAnger"); ok {
+		minAnger, err := strconv.ParseFloat(minAngerParam, 64)
+		if err != nil || minAnger < 0 || minAnger > 1 {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "minAnger must be a number between 0 and 1"})
+			return
+		}
+		spec = spec.Where("anger", query.OpGte, minAnger)
+	}
+
+	complaints, err := ch.complaintUseCase.Find(c.Request.Context(), spec)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at Find()", rz.Err(err))
+		respondError(c, err)
+		return
 	}
 	c.IndentedJSON(http.StatusOK, complaints)
 }
@@ -60,12 +85,14 @@ func (ch complaintHandler) Index(c *gin.Context) {
 // @Router /complaints/{id} [get]
 func (ch complaintHandler) Search(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	complaint, err := ch.complaintUseCase.FindByAvatarId(id)
+	complaint, err := ch.complaintUseCase.FindByAvatarId(c.Request.Context(), id)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		respondError(c, err)
+		return
 	}
 	if complaint == nil {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Not Found"})
+		return
 	}
 	c.IndentedJSON(http.StatusOK, complaint)
 }
@@ -74,9 +101,13 @@ func (ch complaintHandler) Search(c *gin.Context) {
 // @Summary Complaintを一件登録する
 // @Tags Complaints
 // @Produce json
+// @Description 同じアバターの最近の投稿と同じ・似たテキストの場合、設定に応じて409を返すか、元のComplaintのduplicateCountを増やして返す
 // @Param body body model.Complaint false "Complaint"
+// @Param Idempotency-Key header string false "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す"
 // @Success 200 {object} model.Complaint "登録したComplaint"
 // @Failure 400
+// @Failure 409
+// @Failure 422 "同じIdempotency-Keyを異なるボディで使った"
 // @Failure 500
 // @Router /complaints [post]
 func (ch complaintHandler) Create(c *gin.Context) {
@@ -87,32 +118,44 @@ func (ch complaintHandler) Create(c *gin.Context) {
 	if err := c.BindJSON(&newComplaint); err != nil {
 		return
 	}
-	result, err := ch.complaintUseCase.Create(*newComplaint)
+	result, _, err := ch.complaintUseCase.Create(c.Request.Context(), *newComplaint)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		respondError(c, err)
+		return
 	}
 	c.IndentedJSON(http.StatusOK, result)
 }
 
 // FindBetweenTimestamp
-// @Summary 更新日時がfrom, toの間のComplaintを返す
+// @Summary 更新日時がfrom, toの間のComplaintを返す(非推奨)
+// @Deprecated
+// @Description GET /complaints?filter[lastUpdate][gte]=...&filter[lastUpdate][lte]=... を使うこと
+// @Description from, toはRFC 3339、タイムゾーンなしの日時(tzで解釈)、相対指定(now, today, yesterday, -24h, -7d)を受け付ける
 // @Tags Complaints
 // @Produce json
 // @Param from query string false "2022-11-27 0:00:00"
 // @Param to query string false "2022-11-28 0:00:00"
+// @Param tz query string false "Asia/Tokyo"
 // @Success 200 {array} model.Complaint
 // @Failure 400
+// @Failure 404
 // @Failure 500
 // @Router /complaints/between-time [get]
 func (ch complaintHandler) FindBetweenTimestamp(c *gin.Context) {
-	from := c.Query("from")
-	to := c.Query("to")
-	complaintList, err := ch.complaintUseCase.FindBetweenTimestamp(from, to)
+	from, to, err := parseTimeRangeQuery(c, time.Now())
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	complaintList, err := ch.complaintUseCase.FindBetweenTimestamp(c.Request.Context(), from, to)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at FindBetweenTimestamp()", rz.Err(err))
+		respondError(c, err)
+		return
 	}
 	if len(complaintList) == 0 {
 		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Not Found"})
+		return
 	}
 	c.IndentedJSON(http.StatusOK, complaintList)
 }
@@ -128,9 +171,10 @@ func (ch complaintHandler) FindBetweenTimestamp(c *gin.Context) {
 // @Router /complaints/{id} [delete]
 func (ch complaintHandler) DeleteByComplaintId(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	err := ch.complaintUseCase.DeleteByComplaintId(id)
+	err := ch.complaintUseCase.DeleteByComplaintId(c.Request.Context(), id)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal Server Error"})
+		respondError(c, err)
+		return
 	}
 	c.Status(http.StatusNoContent)
 }
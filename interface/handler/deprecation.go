@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 非推奨のエンドポイントの扱い
+type DeprecationPolicy struct {
+	// 後継エンドポイントのパス
+	Successor string
+	// 後継が同じパスの別バージョンの場合のプレフィックス(例: /v1)
+	// Successorが空の場合に、リクエストのパスの前に付けて後継とする
+	VersionPrefix string
+	// 廃止予定日時。ゼロ値の場合はSunsetヘッダを返さない
+	Sunset time.Time
+}
+
+// Deprecated は非推奨のエンドポイントに付けるミドルウェア
+// Deprecationヘッダ、後継エンドポイントを示すLinkヘッダ、廃止予定日時のSunsetヘッダを返す
+// ルートグループとルートの両方に付けた場合、Linkヘッダは両方の後継を含む
+func Deprecated(policy DeprecationPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+
+		successor := policy.Successor
+		if successor == "" && policy.VersionPrefix != "" {
+			successor = policy.VersionPrefix + c.Request.URL.Path
+		}
+		if successor != "" {
+			c.Writer.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		}
+
+		if !policy.Sunset.IsZero() {
+			c.Header("Sunset", policy.Sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/bloom42/rz-go"
+	"github.com/gin-gonic/gin"
+)
+
+// 書き出しの形式。jsonlとndjsonは中身が同じで、Content-Typeと拡張子のみ異なる
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatJSONL  = "jsonl"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// この行数ごとにクライアントへ送る
+const exportFlushRows = 500
+
+// ExcelがUTF-8と判定できるよう、CSVの先頭に付ける
+const utf8BOM = "\ufeff"
+
+var exportContentTypes = map[string]string{
+	ExportFormatCSV:    "text/csv; charset=utf-8",
+	ExportFormatJSONL:  "application/jsonl",
+	ExportFormatNDJSON: "application/x-ndjson",
+}
+
+type ExportHandler interface {
+	Complaints(c *gin.Context)
+	Avatars(c *gin.Context)
+}
+
+type exportHandler struct {
+	complaintUseCase usecase.ComplaintUseCase
+	avatarUseCase    usecase.AvatarUseCase
+}
+
+func NewExportHandler(cu usecase.ComplaintUseCase, au usecase.AvatarUseCase) ExportHandler {
+	return &exportHandler{
+		complaintUseCase: cu,
+		avatarUseCase:    au,
+	}
+}
+
+var complaintCSVHeader = []string{"complaintId", "complaintText", "avatarId", "createdAt", "lastUpdate", "negativity", "anger", "sadness", "duplicateCount"}
+
+func complaintCSVRecord(c *model.Complaint, loc *time.Location) []string {
+	return []string{
+		strconv.Itoa(c.ComplaintId),
+		csvText(c.ComplaintText),
+		strconv.Itoa(c.AvatarId),
+		c.CreatedAt.In(loc).Format(time.RFC3339),
+		c.LastUpdate.In(loc).Format(time.RFC3339),
+		strconv.FormatFloat(c.Negativity, 'f', -1, 64),
+		strconv.FormatFloat(c.Anger, 'f', -1, 64),
+		strconv.FormatFloat(c.Sadness, 'f', -1, 64),
+		strconv.Itoa(c.DuplicateCount),
+	}
+}
+
+var avatarCSVHeader = []string{"avatarId", "avatarName", "avatarText", "imageUrl", "color", "createdAt", "lastUpdate"}
+
+func avatarCSVRecord(a *model.Avatar, loc *time.Location) []string {
+	return []string{
+		strconv.Itoa(a.AvatarId),
+		csvText(a.AvatarName),
+		csvText(a.AvatarText),
+		csvText(a.ImageUrl),
+		csvText(a.Color),
+		a.CreatedAt.In(loc).Format(time.RFC3339),
+		a.LastUpdate.In(loc).Format(time.RFC3339),
+	}
+}
+
+// Complaints はComplaintsを主キー順にCSVまたはJSON Linesで書き出す
+// GET /export/complaints?format=csv|jsonl|ndjson&from=&to=&tz=&bom=
+func (eh exportHandler) Complaints(c *gin.Context) {
+	exportRows(c, "complaints", complaintCSVHeader, complaintCSVRecord, eh.complaintUseCase.Export)
+}
+
+// Avatars はAvatarsを主キー順にCSVまたはJSON Linesで書き出す
+// GET /export/avatars?format=csv|jsonl|ndjson&from=&to=&tz=&bom=
+func (eh exportHandler) Avatars(c *gin.Context) {
+	exportRows(c, "avatars", avatarCSVHeader, avatarCSVRecord, eh.avatarUseCase.Export)
+}
+
+// 書き出しのクエリパラメータ
+type exportOptions struct {
+	format string
+	// 登録日時の範囲。ゼロ値は制限なし
+	from, to time.Time
+	// CSVの日時とファイル名のタイムゾーン
+	loc *time.Location
+	// CSVの先頭にBOMを付けるか
+	bom bool
+}
+
+// parseExportQuery はクエリパラメータformat, from, to, tz, bomを解釈する
+// formatのデフォルトはcsv、bomのデフォルトはtrue
+func parseExportQuery(c *gin.Context, now time.Time) (exportOptions, error) {
+	opts := exportOptions{format: c.DefaultQuery("format", ExportFormatCSV), bom: true}
+	if _, ok := exportContentTypes[opts.format]; !ok {
+		return opts, fmt.Errorf("invalid format %q: must be %s, %s or %s", opts.format, ExportFormatCSV, ExportFormatJSONL, ExportFormatNDJSON)
+	}
+	if v, ok := c.GetQuery("bom"); ok {
+		bom, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid bom %q", v)
+		}
+		opts.bom = bom
+	}
+	loc, err := parseTzQuery(c)
+	if err != nil {
+		return opts, err
+	}
+	opts.loc = loc
+	if opts.from, opts.to, err = parseTimeRangeQuery(c, now); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// exportRows はexportが渡す値を1件ずつレスポンスに書き出す
+// レスポンスはバッファしてから送るので、送り始める前のエラーは通常のエラーレスポンスにする
+// 書き出し始めた後のエラーはステータスを変えられないため、ログに出してそこで打ち切る
+func exportRows[T any](
+	c *gin.Context,
+	name string,
+	header []string,
+	record func(*T, *time.Location) []string,
+	export func(ctx context.Context, from time.Time, to time.Time, fn func(*T) error) error,
+) {
+	opts, err := parseExportQuery(c, time.Now())
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	w := &exportWriter{c: c, name: name, opts: opts, header: header}
+	err = export(ctx, opts.from, opts.to, func(v *T) error {
+		if opts.format == ExportFormatCSV {
+			return w.writeCSV(record(v, opts.loc))
+		}
+		return w.writeJSON(v)
+	})
+	if err == nil {
+		err = w.close()
+	}
+	if err == nil {
+		return
+	}
+
+	if !c.Writer.Written() {
+		// バッファに残っている行は捨てる
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		logging.FromContext(ctx).Error("Failed at Export()", rz.Err(err))
+		respondError(c, err)
+		return
+	}
+	logging.FromContext(ctx).Error("export aborted", rz.Err(err), rz.Int("rows", w.rows))
+	c.Error(err)
+}
+
+// exportWriter はバッファしながらレスポンスに書き出し、exportFlushRows行ごとにクライアントへ送る
+type exportWriter struct {
+	c      *gin.Context
+	name   string
+	opts   exportOptions
+	header []string
+
+	started bool
+	rows    int
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+// start はヘッダと、CSVの場合はBOMと列名の行を書く
+func (w *exportWriter) start() error {
+	w.started = true
+	filename := fmt.Sprintf("%s-%s.%s", w.name, time.Now().In(w.opts.loc).Format("20060102-150405"), w.opts.format)
+	w.c.Header("Content-Type", exportContentTypes[w.opts.format])
+	w.c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.c.Header("Cache-Control", "no-store")
+	w.c.Header("X-Content-Type-Options", "nosniff")
+	w.c.Status(http.StatusOK)
+
+	w.buf = bufio.NewWriter(w.c.Writer)
+	if w.opts.format != ExportFormatCSV {
+		w.json = json.NewEncoder(w.buf)
+		w.json.SetEscapeHTML(false)
+		return nil
+	}
+	if w.opts.bom {
+		if _, err := w.buf.WriteString(utf8BOM); err != nil {
+			return err
+		}
+	}
+	// RFC 4180に合わせて改行はCRLF
+	w.csv = csv.NewWriter(w.buf)
+	w.csv.UseCRLF = true
+	return w.csv.Write(w.header)
+}
+
+func (w *exportWriter) writeCSV(record []string) error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	if err := w.csv.Write(record); err != nil {
+		return err
+	}
+	return w.wrote()
+}
+
+func (w *exportWriter) writeJSON(v interface{}) error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	// Encodeは1件ごとに改行を付ける
+	if err := w.json.Encode(v); err != nil {
+		return err
+	}
+	return w.wrote()
+}
+
+func (w *exportWriter) wrote() error {
+	w.rows++
+	if w.rows%exportFlushRows == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+// close は残りを送る。1件もなかった場合もヘッダ(CSVの場合は列名の行)を書く
+func (w *exportWriter) close() error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// csvText はExcelで開いたときに数式として扱われないよう、=, +, -, @などで始まる値の先頭に'を付ける
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
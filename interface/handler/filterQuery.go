@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/query"
+	"github.com/gin-gonic/gin"
+)
+
+// filter[field] または filter[field][op]
+var filterKeyPattern = regexp.MustCompile(`^filter\[(\w+)\](?:\[(\w+)\])?$`)
+
+// ParseFilterQuery はクエリパラメータのfilter[...]をschemaに従って解釈し、Specに変換する
+//
+//	?filter[avatarId]=1                 avatarId = 1
+//	?filter[lastUpdate][gte]=-24h       lastUpdate >= 24時間前
+//	?filter[avatarId][in]=1,2,3         avatarId IN (1, 2, 3)
+//
+// 日時の値はparseTimeQueryと同じ形式を受け付け、tzクエリパラメータで解釈する
+func ParseFilterQuery(c *gin.Context, schema query.Schema, now time.Time) (query.Spec, error) {
+	spec := query.Spec{}
+
+	loc, err := parseTzQuery(c)
+	if err != nil {
+		return spec, err
+	}
+
+	params := c.Request.URL.Query()
+	// エラーメッセージと条件の順序を安定させるためキーをソートする
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter") {
+			continue
+		}
+		m := filterKeyPattern.FindStringSubmatch(key)
+		if m == nil {
+			return spec, fmt.Errorf("malformed filter parameter %q", key)
+		}
+		field, op := m[1], query.Op(m[2])
+		if op == "" {
+			op = query.OpEq
+		}
+		if err := schema.Allows(field, op); err != nil {
+			return spec, err
+		}
+
+		for _, raw := range params[key] {
+			value, err := parseFilterValue(schema[field], op, raw, loc, now)
+			if err != nil {
+				return spec, fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+			spec = spec.Where(field, op, value)
+		}
+	}
+
+	return spec, nil
+}
+
+func parseFilterValue(kind query.Kind, op query.Op, raw string, loc *time.Location, now time.Time) (interface{}, error) {
+	if op == query.OpIn {
+		parts := strings.Split(raw, ",")
+		switch kind {
+		case query.KindInt:
+			values := make([]int, 0, len(parts))
+			for _, part := range parts {
+				v, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					return nil, fmt.Errorf("%q is not an integer", part)
+				}
+				values = append(values, v)
+			}
+			return values, nil
+		default:
+			return parts, nil
+		}
+	}
+
+	switch kind {
+	case query.KindInt:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+		return v, nil
+	case query.KindFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return v, nil
+	case query.KindTime:
+		v, err := parseTimeQuery(raw, loc, now)
+		if err != nil {
+			return nil, err
+		}
+		if v.IsZero() {
+			return nil, fmt.Errorf("time must not be empty")
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
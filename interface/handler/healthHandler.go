@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkTimeout は依存先1件の確認にかける時間の上限
+const checkTimeout = 2 * time.Second
+
+// Readiness はリクエストを処理できる状態かの判定に使う情報
+type Readiness interface {
+	// Checks は確認する依存先の名前と確認の処理を返す
+	Checks() map[string]func(ctx context.Context) error
+	// Stopping は停止を始めると閉じられるチャネルを返す
+	Stopping() <-chan struct{}
+}
+
+// ビルド時に埋め込む情報
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion,omitempty"`
+}
+
+// /readyzのレスポンス
+type ReadyResponse struct {
+	Status string `json:"status"`
+	// 依存先ごとの結果。正常ならok、異常ならエラーの内容
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type HealthHandler interface {
+	Healthz(c *gin.Context)
+	Readyz(c *gin.Context)
+	Version(c *gin.Context)
+}
+
+type healthHandler struct {
+	readiness Readiness
+	build     BuildInfo
+}
+
+// NewHealthHandler is the initializer.
+// readinessがnilの場合、/readyzは常に200を返す
+func NewHealthHandler(readiness Readiness, build BuildInfo) HealthHandler {
+	return &healthHandler{
+		readiness: readiness,
+		build:     build,
+	}
+}
+
+// Healthz はプロセスが動いていれば200を返す。依存先は確認しない
+// Swaggerのドキュメントは/v1, /v2の下のエンドポイントのみのため、ヘルスチェックは載せない
+func (hh healthHandler) Healthz(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz は登録された依存先(DB接続、Migrationの適用状況など)を確認し、全て正常なら200を返す
+// 1件でも異常がある場合や、停止を始めた後は503を返す
+func (hh healthHandler) Readyz(c *gin.Context) {
+	if hh.readiness == nil {
+		c.IndentedJSON(http.StatusOK, ReadyResponse{Status: "ok"})
+		return
+	}
+	select {
+	case <-hh.readiness.Stopping():
+		c.IndentedJSON(http.StatusServiceUnavailable, ReadyResponse{Status: "shutting down"})
+		return
+	default:
+	}
+
+	results := runChecks(c.Request.Context(), hh.readiness.Checks())
+	status, code := "ok", http.StatusOK
+	for _, result := range results {
+		if result != "ok" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	c.IndentedJSON(code, ReadyResponse{Status: status, Checks: results})
+}
+
+// Version はビルドの情報を返す
+func (hh healthHandler) Version(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, hh.build)
+}
+
+// runChecks は依存先を並行して確認し、名前ごとの結果を返す
+func runChecks(ctx context.Context, checks map[string]func(ctx context.Context) error) map[string]string {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]string, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check func(ctx context.Context) error) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			if err := check(ctx); err != nil {
+				results[i] = err.Error()
+				return
+			}
+			results[i] = "ok"
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	out := make(map[string]string, len(names))
+	for i, name := range names {
+		out[name] = results[i]
+	}
+	return out
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/backend-guchitter-app/idempotency"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/bloom42/rz-go"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 再送を検出するためにクライアントが付けるヘッダ
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// 保存したレスポンスを返したことを示すヘッダ
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// Idempotency-Keyの長さの上限
+	maxIdempotencyKeyLength = 255
+	// 再送の判定のためにメモリに読み込むボディの上限。一括登録の上限件数(usecase.MaxBatchSize)の最大の長さのぐちが収まる
+	maxIdempotentBodyBytes = 2 << 20
+	// レスポンスの保存にかける時間の上限
+	idempotencySaveTimeout = 5 * time.Second
+)
+
+// Idempotency-Keyの扱い
+type IdempotencyPolicy struct {
+	// 処理が終わったレスポンスを保存する期間
+	TTL time.Duration
+	// 処理中のキーの予約の期限。処理中にプロセスが落ちた場合、この期間が過ぎると同じキーで再試行できる
+	// リクエストの処理時間の上限より長くすること
+	LockTimeout time.Duration
+	// リクエストの処理時間の上限。/importのようにLockTimeoutより長いルートでは、予約の期限をその上限に合わせて延ばす
+	Timeouts TimeoutPolicy
+}
+
+// lockTimeout はmethod, routeのリクエストのキーを予約する期間を返す
+// 処理中に予約が切れると再送も処理してしまうため、処理時間の上限とレスポンスの保存にかかる時間より短くしない
+func (p IdempotencyPolicy) lockTimeout(method, route string) time.Duration {
+	if d := p.Timeouts.For(method, route); d > 0 && d+idempotencySaveTimeout > p.LockTimeout {
+		return d + idempotencySaveTimeout
+	}
+	return p.LockTimeout
+}
+
+// Idempotency はIdempotency-Keyヘッダの付いたPOSTのリクエストを1回だけ処理するミドルウェア
+// 最初のリクエストのレスポンスを保存し、同じキーで再送されたリクエストには処理せずに保存したレスポンスを返す
+// ボディをメモリに読み込んで比べるため、JSONのボディの登録のルートにのみ付ける(取り込みや画像のアップロードには付けない)
+// キーはルートと、認証したユーザーまたはAPIキーごとに区別する。IPアドレスは再送の間に変わりうるため使わない
+//   - 同じキーで異なるボディが送られた場合は422
+//   - 同じキーのリクエストが処理中の場合は409とRetry-Afterヘッダ
+//   - ボディが2MBを超える場合は413
+//   - 5xxのレスポンスや、ハンドラが何も書かなかった場合(処理時間の上限による504)は保存せず、同じキーで再試行できる
+//
+// storeがnilの場合やヘッダのないリクエストはそのまま処理する
+func Idempotency(policy IdempotencyPolicy, store idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if store == nil || key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"message": fmt.Sprintf("request body with Idempotency-Key must be at most %d bytes", tooLarge.Limit)})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": http.StatusText(http.StatusBadRequest)})
+			return
+		}
+		// ハンドラがもう一度読めるように戻す
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scope := idempotencyScope(c.Request.Method, c.FullPath(), authIdentity(c), key)
+		requestHash := hashHex(body)
+		existing, err := store.Begin(ctx, scope, requestHash, policy.lockTimeout(c.Request.Method, c.FullPath()))
+		if err != nil {
+			// 再送を検出できないまま処理すると二重に登録しかねないため、再試行を促す
+			logging.FromContext(ctx).Error("Failed at Begin()", rz.Err(err))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"message": http.StatusText(http.StatusServiceUnavailable)})
+			return
+		}
+		if existing != nil {
+			replay(c, existing, requestHash)
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		// リクエストのコンテキストはクライアントの切断でキャンセルされるため、保存には別のコンテキストを使う
+		saveCtx, cancel := context.WithTimeout(logging.WithContext(context.Background(), *logging.FromContext(ctx)), idempotencySaveTimeout)
+		defer cancel()
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || !writer.Written() {
+			if err := store.Release(saveCtx, scope); err != nil {
+				logging.FromContext(ctx).Error("Failed at Release()", rz.Err(err))
+			}
+			return
+		}
+		response := idempotency.Response{
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		}
+		if err := store.Complete(saveCtx, scope, response, policy.TTL); err != nil {
+			logging.FromContext(ctx).Error("Failed at Complete()", rz.Err(err))
+		}
+	}
+}
+
+// replay は既にあるRecordに応じたレスポンスを返す
+func replay(c *gin.Context, existing *idempotency.Record, requestHash string) {
+	switch {
+	case existing.RequestHash != requestHash:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"message": "Idempotency-Key was already used with a different request body"})
+	case !existing.Completed:
+		c.Header("Retry-After", strconv.Itoa(1))
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": "a request with the same Idempotency-Key is in progress"})
+	default:
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(existing.Status, existing.ContentType, existing.Body)
+		c.Abort()
+	}
+}
+
+// idempotencyScope はキーを保存するときの識別子を返す
+// 他のユーザーや他のルートと同じキーを使っても、別のリクエストとして扱う。identityは認証していなければ空
+func idempotencyScope(method, route, identity, key string) string {
+	return hashHex([]byte(method + " " + route + " " + identity + " " + key))
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordingWriter はクライアントに書いたボディを保存用に控える
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,87 @@
+package handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
+	"github.com/backend-guchitter-app/interface/handler"
+)
+
+// newIdempotentRouter はIdempotencyを付けたPOST /itemsのルーターと、ハンドラを実行した回数を返す
+// ボディが"silent"の場合、ハンドラは何も書かない
+func newIdempotentRouter(t *testing.T) (*gin.Engine, *int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	router := gin.New()
+	policy := handler.IdempotencyPolicy{TTL: time.Hour, LockTimeout: time.Minute}
+	router.POST("/items", handler.Idempotency(policy, inmemory.NewIdempotencyStore()), func(c *gin.Context) {
+		calls++
+		body, _ := io.ReadAll(c.Request.Body)
+		if string(body) == "silent" {
+			return
+		}
+		c.String(http.StatusCreated, "created %d", calls)
+	})
+	return router, &calls
+}
+
+func postItem(router http.Handler, body, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set(handler.IdempotencyKeyHeader, "retry-key")
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// 回線が変わってIPアドレスが変わった再送も、保存したレスポンスを返す
+func TestIdempotencyReplaysAcrossClientIPs(t *testing.T) {
+	router, calls := newIdempotentRouter(t)
+	first := postItem(router, `{"text":"a"}`, "192.0.2.1:1234")
+	retry := postItem(router, `{"text":"a"}`, "198.51.100.7:5678")
+
+	if *calls != 1 {
+		t.Errorf("handler called %d times; want 1", *calls)
+	}
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %q; want %d %q", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if got := retry.Header().Get(handler.IdempotentReplayedHeader); got != "true" {
+		t.Errorf("%s = %q; want true", handler.IdempotentReplayedHeader, got)
+	}
+}
+
+// 上限を超えるボディはメモリに読み込まずに413を返す
+func TestIdempotencyRejectsLargeBody(t *testing.T) {
+	router, calls := newIdempotentRouter(t)
+	w := postItem(router, strings.Repeat("a", 2<<20+1), "192.0.2.1:1234")
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d; want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	if *calls != 0 {
+		t.Errorf("handler called %d times; want 0", *calls)
+	}
+}
+
+// ハンドラが何も書かなかった場合(処理時間の上限を過ぎた場合など)は保存せず、再送を処理する
+func TestIdempotencyDoesNotStoreUnwrittenResponse(t *testing.T) {
+	router, calls := newIdempotentRouter(t)
+	postItem(router, "silent", "192.0.2.1:1234")
+	retry := postItem(router, "silent", "192.0.2.1:1234")
+
+	if *calls != 2 {
+		t.Errorf("handler called %d times; want 2", *calls)
+	}
+	if got := retry.Header().Get(handler.IdempotentReplayedHeader); got != "" {
+		t.Errorf("%s = %q; want none", handler.IdempotentReplayedHeader, got)
+	}
+}
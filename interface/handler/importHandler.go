@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/backend-guchitter-app/importer"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/bloom42/rz-go"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBodyBytes は取り込むファイルのバイト数の上限
+const maxImportBodyBytes = 32 << 20
+
+type ImportHandler interface {
+	Complaints(c *gin.Context)
+	Avatars(c *gin.Context)
+}
+
+type importHandler struct {
+	complaintUseCase usecase.ComplaintUseCase
+	avatarUseCase    usecase.AvatarUseCase
+}
+
+func NewImportHandler(cu usecase.ComplaintUseCase, au usecase.AvatarUseCase) ImportHandler {
+	return &importHandler{
+		complaintUseCase: cu,
+		avatarUseCase:    au,
+	}
+}
+
+// Complaints はリクエストのボディのCSVまたはJSON Linesからぐちを取り込む
+// POST /import/complaints?format=csv|jsonl&dryRun=&resumeAfter=&batchSize=
+func (ih importHandler) Complaints(c *gin.Context) {
+	runImport(c, importer.Complaints(ih.complaintUseCase))
+}
+
+// Avatars はリクエストのボディのCSVまたはJSON Linesからアバターを取り込む
+// POST /import/avatars?format=csv|jsonl&dryRun=&resumeAfter=&batchSize=
+func (ih importHandler) Avatars(c *gin.Context) {
+	runImport(c, importer.Avatars(ih.avatarUseCase))
+}
+
+// parseImportQuery はクエリパラメータformat, dryRun, resumeAfter, batchSizeを解釈する
+// formatのデフォルトはcsv。/exportと同じくndjsonも受け付ける
+func parseImportQuery(c *gin.Context) (importer.Options, error) {
+	opts := importer.Options{Format: c.DefaultQuery("format", importer.FormatCSV)}
+	if opts.Format == ExportFormatNDJSON {
+		opts.Format = importer.FormatJSONL
+	}
+	if v, ok := c.GetQuery("dryRun"); ok {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid dryRun %q", v)
+		}
+		opts.DryRun = dryRun
+	}
+	if err := intQuery(c, "resumeAfter", &opts.ResumeAfter); err != nil {
+		return opts, err
+	}
+	if err := intQuery(c, "batchSize", &opts.BatchSize); err != nil {
+		return opts, err
+	}
+	return opts, importer.ValidateOptions(opts)
+}
+
+// intQuery はクエリパラメータnameがあれば整数としてdstに設定する
+func intQuery(c *gin.Context, name string, dst *int) error {
+	v, ok := c.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", name, v)
+	}
+	*dst = n
+	return nil
+}
+
+// runImport はボディをtargetに取り込み、結果を返す
+// 行のエラーがあっても最後まで読めれば200。ボディがmaxImportBodyBytesを超えた場合は413
+// 途中で止まった場合はmessageと、
+// 再開に使うそこまでの結果(result.lineをresumeAfterに渡す)を返す
+func runImport[T any](c *gin.Context, target importer.Target[T]) {
+	opts, err := parseImportQuery(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+	result, err := importer.Run(ctx, body, target, opts)
+	if err == nil {
+		c.IndentedJSON(http.StatusOK, result)
+		return
+	}
+
+	status, message := ErrorStatus(ctx, err)
+	var tooLarge *http.MaxBytesError
+	if errors.Is(err, importer.ErrFormat) {
+		status, message = http.StatusBadRequest, err.Error()
+	} else if errors.As(err, &tooLarge) {
+		status, message = http.StatusRequestEntityTooLarge, fmt.Sprintf("import file must be at most %d bytes", tooLarge.Limit)
+	} else {
+		logging.FromContext(ctx).Error("Failed at importer.Run()", rz.Err(err), rz.Int("line", result.Line))
+	}
+	c.IndentedJSON(status, gin.H{"message": message, "result": result})
+}
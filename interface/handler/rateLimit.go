@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/metrics"
+	"github.com/backend-guchitter-app/ratelimit"
+	"github.com/bloom42/rz-go"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 認証したユーザーのIDを設定するgin.Contextのキー
+	UserIdKey = "userId"
+	// 検証したAPIキーの識別子を設定するgin.Contextのキー
+	// ヘッダの値をそのまま使うと、キーを変えるだけで制限を回避できるため、検証済みのものだけを設定すること
+	APIKeyIdKey = "apiKeyId"
+
+	// 一括処理の項目数の上限を設定するgin.Contextのキー
+	rateLimitItemsKey = "rateLimitItems"
+)
+
+// リクエスト数の上限
+type RateLimitPolicy struct {
+	// ルートごとの指定がない場合の上限。ゼロ値の場合は制限なし
+	Default ratelimit.Limit
+	// ルートまたは操作ごとの上限。キーは"POST /v1/complaints"のようなメソッドとルートのパターン、
+	// または"complaints.create"のようなActionsの操作の名前。両方あればルートの上限を使う
+	Routes map[string]ratelimit.Limit
+	// 同じ操作の別名のルート(/v1, /v2, バージョンなし)を操作の名前にまとめる。キーはメソッドとルートのパターン
+	// 同じ操作のルートは1つの上限を共有して数えるため、別名を使い分けても上限を超えられない
+	Actions map[string]string
+	// 一括処理のルートまたは操作ごとの項目数の上限。キーはRoutesと同じ
+	// リクエスト数とは別に、ハンドラがTakeItemsで項目の数だけトークンを取り出す
+	Items map[string]ratelimit.Limit
+}
+
+// For はmethod, routeのリクエストに適用する上限を返す
+func (p RateLimitPolicy) For(method, route string) ratelimit.Limit {
+	if l, ok := p.lookup(p.Routes, method, route); ok {
+		return l
+	}
+	return p.Default
+}
+
+// ItemsFor はmethod, routeの一括処理の項目数の上限を返す。ゼロ値の場合は制限なし
+func (p RateLimitPolicy) ItemsFor(method, route string) ratelimit.Limit {
+	l, _ := p.lookup(p.Items, method, route)
+	return l
+}
+
+// lookup はlimitsからルート、操作の名前の順に上限を探す
+func (p RateLimitPolicy) lookup(limits map[string]ratelimit.Limit, method, route string) (ratelimit.Limit, bool) {
+	if l, ok := limits[method+" "+route]; ok {
+		return l, true
+	}
+	if action, ok := p.Actions[method+" "+route]; ok {
+		if l, ok := limits[action]; ok {
+			return l, true
+		}
+	}
+	return ratelimit.Limit{}, false
+}
+
+// bucket はmethod, routeのリクエストを数える単位を返す。操作の名前があればそれを、なければメソッドとルートのパターンを使う
+func (p RateLimitPolicy) bucket(method, route string) string {
+	if action, ok := p.Actions[method+" "+route]; ok {
+		return action
+	}
+	return method + " " + route
+}
+
+// RateLimit はクライアントごとのリクエスト数をトークンバケットで制限するミドルウェア
+// 上限のあるルートではRateLimit-Limit, RateLimit-Remaining, RateLimit-Resetヘッダを返し、
+// 超えた場合はRetry-Afterヘッダを付けて429を返す
+// 項目数の上限のあるルートでは、ハンドラがTakeItemsで使う上限を設定する
+// storeがエラーを返した場合は、制限せずにリクエストを処理する
+func RateLimit(policy RateLimitPolicy, store ratelimit.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if store == nil {
+			c.Next()
+			return
+		}
+		route := c.FullPath()
+		bucket := policy.bucket(c.Request.Method, route)
+		if items := policy.ItemsFor(c.Request.Method, route); items.Enabled() {
+			c.Set(rateLimitItemsKey, itemLimit{store: store, key: bucket + " items " + RateLimitKey(c), limit: items})
+		}
+		limit := policy.For(c.Request.Method, route)
+		if !limit.Enabled() {
+			c.Next()
+			return
+		}
+
+		key := bucket + " " + RateLimitKey(c)
+		result, err := store.Take(c.Request.Context(), key, limit, 1)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("Failed at Take()", rz.Err(err))
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			metrics.RateLimited.WithLabelValues(c.Request.Method, route).Inc()
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": http.StatusText(http.StatusTooManyRequests)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// itemLimit はリクエストに適用する一括処理の項目数の上限
+type itemLimit struct {
+	store ratelimit.Store
+	key   string
+	limit ratelimit.Limit
+}
+
+// TakeItems は一括処理の項目数nだけ、ルートの項目数の上限(RateLimitPolicy.Items)からトークンを取り出す
+// 1回のリクエストで多くの項目を送ってリクエスト数の上限をすり抜けられないよう、ハンドラがボディを読んだ後に呼ぶ
+// 上限を超える場合はRetry-Afterヘッダを設定してfalseを返す。429のレスポンスは呼び出し側が返す
+// 上限のないルートや、storeがエラーを返した場合はtrueを返す
+func TakeItems(c *gin.Context, n int) bool {
+	v, ok := c.Get(rateLimitItemsKey)
+	if !ok {
+		return true
+	}
+	items := v.(itemLimit)
+	result, err := items.store.Take(c.Request.Context(), items.key, items.limit, n)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at Take()", rz.Err(err))
+		return true
+	}
+	if !result.Allowed {
+		metrics.RateLimited.WithLabelValues(c.Request.Method, c.FullPath()).Inc()
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		return false
+	}
+	return true
+}
+
+// RateLimitKey はリクエスト数を数えるクライアントの識別子を返す
+// 認証したユーザー、検証したAPIキー、クライアントのIPアドレスの順に使う
+// IPアドレスは信頼するプロキシのX-Forwarded-Forのみを考慮する(gin.Engine.SetTrustedProxies)
+func RateLimitKey(c *gin.Context) string {
+	if identity := authIdentity(c); identity != "" {
+		return identity
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// authIdentity は認証したユーザーまたは検証したAPIキーの識別子を返す。どちらもなければ空
+func authIdentity(c *gin.Context) string {
+	if userId := c.GetString(UserIdKey); userId != "" {
+		return "user:" + userId
+	}
+	if apiKeyId := c.GetString(APIKeyIdKey); apiKeyId != "" {
+		return "apikey:" + apiKeyId
+	}
+	return ""
+}
+
+// ceilSeconds はdを秒に切り上げる。ヘッダの値は整数の秒のため
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
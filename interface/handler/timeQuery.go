@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// タイムゾーン未指定の日時を解釈するレイアウト
+var localTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTimeRangeQuery はクエリパラメータfrom, to, tzを解釈する
+// 未指定のfrom, toはゼロ値(範囲の制限なし)として返す
+func parseTimeRangeQuery(c *gin.Context, now time.Time) (from, to time.Time, err error) {
+	loc, err := parseTzQuery(c)
+	if err != nil {
+		return from, to, err
+	}
+
+	if from, err = parseTimeQuery(c.Query("from"), loc, now); err != nil {
+		return from, to, fmt.Errorf("invalid from: %w", err)
+	}
+	if to, err = parseTimeQuery(c.Query("to"), loc, now); err != nil {
+		return from, to, fmt.Errorf("invalid to: %w", err)
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return from, to, fmt.Errorf("from must not be after to")
+	}
+
+	return from, to, nil
+}
+
+// parseTzQuery はクエリパラメータtzのタイムゾーンを返す
+// 未指定の場合はサーバーのローカルタイムゾーン
+func parseTzQuery(c *gin.Context) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz %q", tz)
+	}
+	return loc, nil
+}
+
+// parseTimeQuery は日時を表す文字列をlocを基準に解釈する
+// 以下の形式を受け付ける
+//   - RFC 3339: 2022-11-27T00:00:00+09:00
+//   - タイムゾーンなし: 2022-11-27 0:00:00, 2022-11-27
+//   - 相対指定: now, today, yesterday, -24h, -7d
+func parseTimeQuery(value string, loc *time.Location, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	now = now.In(loc)
+	switch value {
+	case "now":
+		return now, nil
+	case "today":
+		return startOfDay(now), nil
+	case "yesterday":
+		return startOfDay(now).AddDate(0, 0, -1), nil
+	}
+
+	if value[0] == '-' || value[0] == '+' {
+		d, err := parseRelativeDuration(value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported time format %q", value)
+}
+
+// parseRelativeDuration はtime.ParseDurationの形式に加えて日単位(-7d)を受け付ける
+func parseRelativeDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("unsupported duration %q", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported duration %q", value)
+	}
+	return d, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/service"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// リクエストの処理時間の上限
+type TimeoutPolicy struct {
+	// ルートごとの指定がない場合の上限。0以下の場合は上限なし
+	Default time.Duration
+	// ルートごとの上限。キーは"GET /v1/complaints/:id"のようにメソッドとルートのパターン
+	Routes map[string]time.Duration
+}
+
+// For はmethod, routeのリクエストに適用する上限を返す
+func (p TimeoutPolicy) For(method, route string) time.Duration {
+	if d, ok := p.Routes[method+" "+route]; ok {
+		return d
+	}
+	return p.Default
+}
+
+// Timeout はリクエストのコンテキストに期限を設定するミドルウェア
+// 期限はユースケース・リポジトリを通してDBのクエリまで伝わり、超えるとクエリが中断される
+// ハンドラが何も返さずに期限を超えた場合は504を返す
+func Timeout(policy TimeoutPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := policy.For(c.Request.Method, c.FullPath())
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"message": http.StatusText(http.StatusGatewayTimeout)})
+		}
+	}
+}
+
+// ErrorStatus はユースケースが返したエラーのHTTPステータスとメッセージを返す
+// 入力の誤りは400、存在しない対象は404、重複した投稿は409、対応していない形式の画像は415、一括処理で他の項目が失敗したため処理しなかった項目は424、
+// 期限切れは504、キャンセル(クライアントの切断、サーバーの停止)は503、それ以外は500
+// DBドライバによってはコンテキストのエラーを包まずに返すため、ctxの状態も確認する
+func ErrorStatus(ctx context.Context, err error) (int, string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, usecase.ErrInvalid):
+		// どの項目が誤っているかをクライアントに伝える
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, usecase.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, usecase.ErrDuplicateComplaint):
+		// どのぐちと重複したかをクライアントに伝える
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, service.ErrInvalidImage):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrUnsupportedImage):
+		return http.StatusUnsupportedMediaType, err.Error()
+	case errors.Is(err, usecase.ErrBatchAborted):
+		return http.StatusFailedDependency, err.Error()
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		status = http.StatusServiceUnavailable
+	}
+	return status, http.StatusText(status)
+}
+
+// respondError はユースケースのエラーを対応するステータスで返す
+func respondError(c *gin.Context, err error) {
+	status, message := ErrorStatus(c.Request.Context(), err)
+	c.IndentedJSON(status, gin.H{"message": message})
+}
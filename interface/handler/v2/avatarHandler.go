@@ -0,0 +1,223 @@
+package v2
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
+	"github.com/backend-guchitter-app/interface/handler"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/bloom42/rz-go"
+	"github.com/gin-gonic/gin"
+)
+
+type AvatarHandler interface {
+	// Index is the handler to fetch a page of avatars.
+	Index(c *gin.Context)
+	Search(c *gin.Context)
+	Create(c *gin.Context)
+	DeleteByAvatarId(c *gin.Context)
+	CreateBatch(c *gin.Context)
+	UploadImage(c *gin.Context)
+	Image(c *gin.Context)
+}
+
+type avatarHandler struct {
+	avatarUseCase usecase.AvatarUseCase
+	images        handler.AvatarImagePolicy
+}
+
+// NewAvatarHandler is the initializer.
+func NewAvatarHandler(cu usecase.AvatarUseCase, images handler.AvatarImagePolicy) AvatarHandler {
+	return &avatarHandler{
+		avatarUseCase: cu,
+		images:        images,
+	}
+}
+
+// Index
+// @Summary Avatarsをページングして取得
+// @Description filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in
+// @Description fieldはavatarId, avatarName, color, createdAt, lastUpdate
+// @Tags Avatars
+// @Produce json
+// @Param filter[avatarName] query string false "アバター名"
+// @Param filter[lastUpdate][gte] query string false "更新日時の下限(例: -24h)"
+// @Param filter[lastUpdate][lte] query string false "更新日時の上限"
+// @Param tz query string false "Asia/Tokyo"
+// @Param limit query int false "取得件数(1〜100)" default(20)
+// @Param offset query int false "取得開始位置" default(0)
+// @Success 200 {object} AvatarList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /avatars [get]
+func (ch avatarHandler) Index(c *gin.Context) {
+	spec, err := handler.ParseFilterQuery(c, query.AvatarSchema, time.Now())
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, offset, err := parsePageQuery(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 次のページの有無を判定するため1件多く取得する
+	avatars, err := ch.avatarUseCase.Find(c.Request.Context(), spec.Page(limit+1, offset))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at Find()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	page, count := newPage(limit, offset, len(avatars))
+	c.JSON(http.StatusOK, AvatarList{Data: avatars[:count], Page: page})
+}
+
+// Search
+// @Summary avatarIdで検索したAvatarを1件返す
+// @Tags Avatars
+// @Produce json
+// @Param id path int true "アバターID"
+// @Success 200 {object} AvatarResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /avatars/{id} [get]
+func (ch avatarHandler) Search(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "id must be an integer")
+		return
+	}
+	avatar, err := ch.avatarUseCase.FindByAvatarId(c.Request.Context(), id)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at FindByAvatarId()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+	if avatar == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found")
+		return
+	}
+	c.JSON(http.StatusOK, AvatarResponse{Data: avatar})
+}
+
+// Create
+// @Summary Avatarを一件登録する
+// @Tags Avatars
+// @Accept json
+// @Produce json
+// @Param body body model.Avatar true "Avatar"
+// @Param Idempotency-Key header string false "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す"
+// @Success 201 {object} AvatarResponse "登録したAvatar"
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 "同じIdempotency-Keyを異なるボディで使った"
+// @Failure 500 {object} ErrorResponse
+// @Router /avatars [post]
+func (ch avatarHandler) Create(c *gin.Context) {
+	var newAvatar model.Avatar
+	if err := c.ShouldBindJSON(&newAvatar); err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := ch.avatarUseCase.Create(c.Request.Context(), newAvatar)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at Create()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, AvatarResponse{Data: result})
+}
+
+// DeleteByAvatarId
+// @Summary avatarIdで指定したAvatarを1件削除する
+// @Tags Avatars
+// @Produce json
+// @Param id path int true "アバターID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /avatars/{id} [delete]
+func (ch avatarHandler) DeleteByAvatarId(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "id must be an integer")
+		return
+	}
+	if err := ch.avatarUseCase.DeleteByAvatarId(c.Request.Context(), id); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at DeleteByAvatarId()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UploadImage
+// @Summary Avatarの画像をアップロードする
+// @Description PNG, JPEG, WebPを受け付け、形式はファイルの中身で判定する。中央を正方形に切り抜き、small(64px), medium(256px), large(512px)に縮小して保存する
+// @Description imageUrlは GET /v2/avatars/{id}/image?v=<画像のハッシュ> になる
+// @Tags Avatars
+// @Accept mpfd
+// @Produce json
+// @Param id path int true "アバターID"
+// @Param image formData file true "画像(PNG, JPEG, WebP。上限はguchitter_IMAGE_MAX_BYTES、デフォルトは5MB)"
+// @Success 200 {object} AvatarResponse "画像を更新したAvatar"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /avatars/{id}/image [post]
+func (ch avatarHandler) UploadImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "id must be an integer")
+		return
+	}
+	body, status, err := handler.ReadImageUpload(c, ch.images)
+	if err != nil {
+		abortWithError(c, status, err.Error())
+		return
+	}
+	avatar, err := ch.avatarUseCase.UploadImage(c.Request.Context(), id, body, c.Request.URL.Path)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at UploadImage()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, AvatarResponse{Data: avatar})
+}
+
+// Image
+// @Summary Avatarのアップロードした画像を返す
+// @Description ?v=が現在の画像のハッシュと一致する場合は期限なくキャッシュでき、それ以外はETagで確認させる
+// @Tags Avatars
+// @Produce png,jpeg
+// @Param id path int true "アバターID"
+// @Param size query string false "small, medium, large" default(medium)
+// @Param v query string false "画像のハッシュ(imageUrlに含まれる)"
+// @Param If-None-Match header string false "前回のETag"
+// @Success 200 {file} binary
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /avatars/{id}/image [get]
+func (ch avatarHandler) Image(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "id must be an integer")
+		return
+	}
+	image, err := ch.avatarUseCase.Image(c.Request.Context(), id, handler.AvatarImageSize(c))
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+	handler.ServeAvatarImage(c, image)
+}
@@ -0,0 +1,248 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/interface/handler"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// Complaintsの一括登録のリクエスト
+type ComplaintBatchRequest struct {
+	// atomic(省略時): 1件でも失敗すれば何も登録しない。bestEffort: 失敗した項目を除いて登録する
+	Mode  string            `json:"mode" enums:"atomic,bestEffort" example:"atomic"`
+	Items []model.Complaint `json:"items"`
+}
+
+// Avatarsの一括登録のリクエスト
+type AvatarBatchRequest struct {
+	// atomic(省略時): 1件でも失敗すれば何も登録しない。bestEffort: 失敗した項目を除いて登録する
+	Mode  string         `json:"mode" enums:"atomic,bestEffort" example:"atomic"`
+	Items []model.Avatar `json:"items"`
+}
+
+// 一括削除のリクエスト
+type BatchDeleteRequest struct {
+	// atomic(省略時): 存在しないIDがあれば何も削除しない。bestEffort: 存在するIDのみ削除する
+	Mode string `json:"mode" enums:"atomic,bestEffort" example:"atomic"`
+	Ids  []int  `json:"ids" example:"1,2,3"`
+}
+
+// 一括処理の件数
+type BatchSummary struct {
+	Succeeded int `json:"succeeded" example:"2"`
+	Failed    int `json:"failed" example:"1"`
+}
+
+// Complaintsの一括登録の1項目の結果
+type ComplaintBatchItem struct {
+	// リクエストのitemsでの位置
+	Index int `json:"index" example:"0"`
+	// この項目を1件ずつ登録した場合のHTTPステータス
+	Status int              `json:"status" example:"201"`
+	Data   *model.Complaint `json:"data,omitempty"`
+	Error  *ErrorBody       `json:"error,omitempty"`
+}
+
+type ComplaintBatchResponse struct {
+	Data    []ComplaintBatchItem `json:"data"`
+	Summary BatchSummary         `json:"summary"`
+}
+
+// Avatarsの一括登録の1項目の結果
+type AvatarBatchItem struct {
+	// リクエストのitemsでの位置
+	Index int `json:"index" example:"0"`
+	// この項目を1件ずつ登録した場合のHTTPステータス
+	Status int           `json:"status" example:"201"`
+	Data   *model.Avatar `json:"data,omitempty"`
+	Error  *ErrorBody    `json:"error,omitempty"`
+}
+
+type AvatarBatchResponse struct {
+	Data    []AvatarBatchItem `json:"data"`
+	Summary BatchSummary      `json:"summary"`
+}
+
+// 一括削除の1項目の結果
+type BatchDeleteItem struct {
+	// リクエストのidsでの位置
+	Index int `json:"index" example:"0"`
+	Id    int `json:"id" example:"1"`
+	// この項目を1件ずつ削除した場合のHTTPステータス。存在しないIDは404
+	Status int        `json:"status" example:"204"`
+	Error  *ErrorBody `json:"error,omitempty"`
+}
+
+type BatchDeleteResponse struct {
+	Data    []BatchDeleteItem `json:"data"`
+	Summary BatchSummary      `json:"summary"`
+}
+
+// NotFound は該当するエンドポイントがない場合の404を返す
+func NotFound(c *gin.Context) {
+	abortWithError(c, http.StatusNotFound, "Not Found")
+}
+
+// batchItemStatus は項目の結果のHTTPステータスとエラーを返す。errがnilの場合はsuccess
+func batchItemStatus(ctx context.Context, err error, success int) (int, *ErrorBody) {
+	if err == nil {
+		return success, nil
+	}
+	status, message := handler.ErrorStatus(ctx, err)
+	return status, &ErrorBody{Message: message}
+}
+
+// batchStatus は一括処理全体のHTTPステータスを返す
+// 全て成功すればsuccess、失敗した項目があればatomicでは422、bestEffortでは207
+func batchStatus(mode string, summary BatchSummary, success int) int {
+	switch {
+	case summary.Failed == 0:
+		return success
+	case mode == usecase.BatchModeBestEffort:
+		return http.StatusMultiStatus
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// CreateBatch
+// @Summary Complaintsを一括登録する
+// @Description 1つのトランザクションで最大1000件登録し、項目ごとの結果をitemsと同じ順で返す
+// @Description 各項目はPOST /complaintsと同じく検証する。重複・類似の投稿はバッチ内の前の項目とも比べ、設定によらず409の項目にする
+// @Tags Complaints
+// @Accept json
+// @Produce json
+// @Param body body ComplaintBatchRequest true "登録するComplaints"
+// @Param Idempotency-Key header string false "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す"
+// @Success 201 {object} ComplaintBatchResponse "全て登録した"
+// @Success 207 {object} ComplaintBatchResponse "bestEffortで一部の項目が失敗した"
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ComplaintBatchResponse "atomicで失敗した項目があり、何も登録しなかった"
+// @Failure 429 {object} ErrorResponse "一括処理の項目数の上限を超えた"
+// @Failure 500 {object} ErrorResponse
+// @Router /complaints:batch [post]
+func (ch complaintHandler) CreateBatch(c *gin.Context) {
+	var req ComplaintBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !takeBatchItems(c, len(req.Items)) {
+		return
+	}
+	ctx := c.Request.Context()
+	results, err := ch.complaintUseCase.CreateBatch(ctx, req.Items, req.Mode)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	res := ComplaintBatchResponse{Data: make([]ComplaintBatchItem, len(results))}
+	for i, result := range results {
+		status, errBody := batchItemStatus(ctx, result.Err, http.StatusCreated)
+		res.Data[i] = ComplaintBatchItem{Index: i, Status: status, Data: result.Value, Error: errBody}
+		countBatchItem(&res.Summary, result.Err)
+	}
+	c.JSON(batchStatus(req.Mode, res.Summary, http.StatusCreated), res)
+}
+
+// DeleteBatch
+// @Summary Complaintsを一括削除する
+// @Description 最大1000件のIDをまとめて削除し、項目ごとの結果をidsと同じ順で返す
+// @Tags Complaints
+// @Accept json
+// @Produce json
+// @Param body body BatchDeleteRequest true "削除するComplaintのID"
+// @Success 200 {object} BatchDeleteResponse "全て削除した"
+// @Success 207 {object} BatchDeleteResponse "bestEffortで存在しないIDがあった"
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} BatchDeleteResponse "atomicで存在しないIDがあり、何も削除しなかった"
+// @Failure 429 {object} ErrorResponse "一括処理の項目数の上限を超えた"
+// @Failure 500 {object} ErrorResponse
+// @Router /complaints:batchDelete [post]
+func (ch complaintHandler) DeleteBatch(c *gin.Context) {
+	var req BatchDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !takeBatchItems(c, len(req.Ids)) {
+		return
+	}
+	ctx := c.Request.Context()
+	errs, err := ch.complaintUseCase.DeleteBatch(ctx, req.Ids, req.Mode)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	res := BatchDeleteResponse{Data: make([]BatchDeleteItem, len(errs))}
+	for i, itemErr := range errs {
+		status, errBody := batchItemStatus(ctx, itemErr, http.StatusNoContent)
+		res.Data[i] = BatchDeleteItem{Index: i, Id: req.Ids[i], Status: status, Error: errBody}
+		countBatchItem(&res.Summary, itemErr)
+	}
+	c.JSON(batchStatus(req.Mode, res.Summary, http.StatusOK), res)
+}
+
+// CreateBatch
+// @Summary Avatarsを一括登録する
+// @Description 1つのトランザクションで最大1000件登録し、項目ごとの結果をitemsと同じ順で返す。各項目はPOST /avatarsと同じく検証する
+// @Tags Avatars
+// @Accept json
+// @Produce json
+// @Param body body AvatarBatchRequest true "登録するAvatars"
+// @Param Idempotency-Key header string false "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す"
+// @Success 201 {object} AvatarBatchResponse "全て登録した"
+// @Success 207 {object} AvatarBatchResponse "bestEffortで一部の項目が失敗した"
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} AvatarBatchResponse "atomicで失敗した項目があり、何も登録しなかった"
+// @Failure 429 {object} ErrorResponse "一括処理の項目数の上限を超えた"
+// @Failure 500 {object} ErrorResponse
+// @Router /avatars:batch [post]
+func (ch avatarHandler) CreateBatch(c *gin.Context) {
+	var req AvatarBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !takeBatchItems(c, len(req.Items)) {
+		return
+	}
+	ctx := c.Request.Context()
+	results, err := ch.avatarUseCase.CreateBatch(ctx, req.Items, req.Mode)
+	if err != nil {
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	res := AvatarBatchResponse{Data: make([]AvatarBatchItem, len(results))}
+	for i, result := range results {
+		status, errBody := batchItemStatus(ctx, result.Err, http.StatusCreated)
+		res.Data[i] = AvatarBatchItem{Index: i, Status: status, Data: result.Value, Error: errBody}
+		countBatchItem(&res.Summary, result.Err)
+	}
+	c.JSON(batchStatus(req.Mode, res.Summary, http.StatusCreated), res)
+}
+
+// takeBatchItems は項目の数だけ一括処理の項目数の上限を数え、超えた場合は429を返してfalseを返す
+// 項目数の誤りはユースケースで400にするため、ここでは数えない
+func takeBatchItems(c *gin.Context, n int) bool {
+	if n < 1 || n > usecase.MaxBatchSize || handler.TakeItems(c, n) {
+		return true
+	}
+	abortWithError(c, http.StatusTooManyRequests, "too many batch items, retry after the Retry-After seconds")
+	return false
+}
+
+func countBatchItem(summary *BatchSummary, err error) {
+	if err != nil {
+		summary.Failed++
+		return
+	}
+	summary.Succeeded++
+}
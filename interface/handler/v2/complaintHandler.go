@@ -0,0 +1,162 @@
+package v2
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
+	"github.com/backend-guchitter-app/interface/handler"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/bloom42/rz-go"
+	"github.com/gin-gonic/gin"
+)
+
+type ComplaintHandler interface {
+	// Index is the handler to fetch a page of complaints.
+	Index(c *gin.Context)
+	Search(c *gin.Context)
+	Create(c *gin.Context)
+	DeleteByComplaintId(c *gin.Context)
+	CreateBatch(c *gin.Context)
+	DeleteBatch(c *gin.Context)
+}
+
+type complaintHandler struct {
+	complaintUseCase usecase.ComplaintUseCase
+}
+
+// NewComplaintHandler is the initializer.
+func NewComplaintHandler(cu usecase.ComplaintUseCase) ComplaintHandler {
+	return &complaintHandler{
+		complaintUseCase: cu,
+	}
+}
+
+// Index
+// @Summary Complaintsをページングして取得
+// @Description filter[field]またはfilter[field][op]で絞り込む。opはeq, ne, gt, gte, lt, lte, in
+// @Description fieldはcomplaintId, avatarId, negativity, anger, sadness, createdAt, lastUpdate
+// @Tags Complaints
+// @Produce json
+// @Param filter[avatarId] query int false "アバターID"
+// @Param filter[lastUpdate][gte] query string false "更新日時の下限(例: -24h)"
+// @Param filter[lastUpdate][lte] query string false "更新日時の上限"
+// @Param tz query string false "Asia/Tokyo"
+// @Param limit query int false "取得件数(1〜100)" default(20)
+// @Param offset query int false "取得開始位置" default(0)
+// @Success 200 {object} ComplaintList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /complaints [get]
+func (ch complaintHandler) Index(c *gin.Context) {
+	spec, err := handler.ParseFilterQuery(c, query.ComplaintSchema, time.Now())
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, offset, err := parsePageQuery(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 次のページの有無を判定するため1件多く取得する
+	complaints, err := ch.complaintUseCase.Find(c.Request.Context(), spec.Page(limit+1, offset))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at Find()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+
+	page, count := newPage(limit, offset, len(complaints))
+	c.JSON(http.StatusOK, ComplaintList{Data: complaints[:count], Page: page})
+}
+
+// Search
+// @Summary avatarIdで検索したComplaintを1件返す
+// @Tags Complaints
+// @Produce json
+// @Param id path int true "アバターID"
+// @Success 200 {object} ComplaintResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /complaints/{id} [get]
+func (ch complaintHandler) Search(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "id must be an integer")
+		return
+	}
+	complaint, err := ch.complaintUseCase.FindByAvatarId(c.Request.Context(), id)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at FindByAvatarId()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+	if complaint == nil {
+		abortWithError(c, http.StatusNotFound, "Not Found")
+		return
+	}
+	c.JSON(http.StatusOK, ComplaintResponse{Data: complaint})
+}
+
+// Create
+// @Summary Complaintを一件登録する
+// @Tags Complaints
+// @Accept json
+// @Produce json
+// @Param body body model.Complaint true "Complaint"
+// @Param Idempotency-Key header string false "再送時に同じキーを付けると、処理せずに最初のレスポンスを返す"
+// @Description 同じアバターの最近の投稿と同じ・似たテキストの場合、設定に応じて409を返すか、元のComplaintのduplicateCountを増やして200で返す
+// @Success 201 {object} ComplaintResponse "登録したComplaint"
+// @Success 200 {object} ComplaintResponse "重複としてまとめた元のComplaint"
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 "同じIdempotency-Keyを異なるボディで使った"
+// @Failure 500 {object} ErrorResponse
+// @Router /complaints [post]
+func (ch complaintHandler) Create(c *gin.Context) {
+	var newComplaint model.Complaint
+	if err := c.ShouldBindJSON(&newComplaint); err != nil {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, merged, err := ch.complaintUseCase.Create(c.Request.Context(), newComplaint)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at Create()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+	status := http.StatusCreated
+	if merged {
+		status = http.StatusOK
+	}
+	c.JSON(status, ComplaintResponse{Data: result})
+}
+
+// DeleteByComplaintId
+// @Summary complaintIdで指定したComplaintを1件削除する
+// @Tags Complaints
+// @Produce json
+// @Param id path int true "愚痴ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /complaints/{id} [delete]
+func (ch complaintHandler) DeleteByComplaintId(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "id must be an integer")
+		return
+	}
+	if err := ch.complaintUseCase.DeleteByComplaintId(c.Request.Context(), id); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed at DeleteByComplaintId()", rz.Err(err))
+		abortWithUseCaseError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,8 @@
+// Package v2 は/v2のハンドラ
+// ユースケースは/v1と共通で、レスポンスの形式(エンベロープ、ページング)のみ異なる
+//
+// @title gin-swagger guchitter v2
+// @version 0.0.1
+// @description はじめてのswagger(v2)。一覧はページング付きのエンベロープで返す
+// @BasePath /v2
+package v2
@@ -0,0 +1,87 @@
+package v2
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/interface/handler"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// 一覧のページ情報
+type Page struct {
+	Limit  int `json:"limit" example:"20"`
+	Offset int `json:"offset" example:"0"`
+	// 次のページのoffset。次のページがない場合はnull
+	NextOffset *int `json:"nextOffset" example:"20"`
+}
+
+type ComplaintList struct {
+	Data []*model.Complaint `json:"data"`
+	Page Page               `json:"page"`
+}
+
+type ComplaintResponse struct {
+	Data *model.Complaint `json:"data"`
+}
+
+type AvatarList struct {
+	Data []*model.Avatar `json:"data"`
+	Page Page            `json:"page"`
+}
+
+type AvatarResponse struct {
+	Data *model.Avatar `json:"data"`
+}
+
+type ErrorBody struct {
+	Message string `json:"message" example:"Not Found"`
+}
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+func abortWithError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: ErrorBody{Message: message}})
+}
+
+// abortWithUseCaseError はユースケースのエラーを対応するステータスで返す
+func abortWithUseCaseError(c *gin.Context, err error) {
+	status, message := handler.ErrorStatus(c.Request.Context(), err)
+	abortWithError(c, status, message)
+}
+
+// parsePageQuery はクエリパラメータlimit, offsetを解釈する
+func parsePageQuery(c *gin.Context) (limit, offset int, err error) {
+	limit = defaultLimit
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", maxLimit)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// newPage はlimit+1件取得した結果からページ情報を組み立てる
+// 超過分があれば次のページがあるとみなし、itemsの件数をlimitに切り詰める
+func newPage(limit, offset, fetched int) (page Page, count int) {
+	page = Page{Limit: limit, Offset: offset}
+	if fetched > limit {
+		next := offset + limit
+		page.NextOffset = &next
+		return page, limit
+	}
+	return page, fetched
+}
@@ -0,0 +1,248 @@
+package router
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/backend-guchitter-app/config"
+	docsV1 "github.com/backend-guchitter-app/docs/v1"
+	docsV2 "github.com/backend-guchitter-app/docs/v2"
+	"github.com/backend-guchitter-app/idempotency"
+	"github.com/backend-guchitter-app/interface/handler"
+	handlerV2 "github.com/backend-guchitter-app/interface/handler/v2"
+	logging "github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/metrics"
+	"github.com/backend-guchitter-app/ratelimit"
+	"github.com/backend-guchitter-app/tracing"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/bloom42/rz-go"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+const (
+	// header name of unique request id
+	XRequestId = "X-Request-ID"
+	// 認証したユーザーのIDを設定するgin.Contextのキー。設定されていればアクセスログに含め、リクエスト数の制限に使う
+	UserIdKey = handler.UserIdKey
+)
+
+// バージョンなしの旧エンドポイントの廃止予定日時
+var legacyRoutesSunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
+// ルーターが依存するもの
+// main()やテストで組み立てて渡す
+type Deps struct {
+	ComplaintUseCase usecase.ComplaintUseCase
+	AvatarUseCase    usecase.AvatarUseCase
+	// CORSで許可するオリジン
+	AllowOrigins []string
+	// アップロードできるアバターの画像の上限。ゼロ値の場合はデフォルトの上限
+	AvatarImages handler.AvatarImagePolicy
+	// リクエストの処理時間の上限。ゼロ値の場合は上限なし
+	Timeouts handler.TimeoutPolicy
+	// リクエスト数の上限と、クライアントごとの残りを保存する先。RateLimitStoreがnilの場合は制限しない
+	RateLimits     handler.RateLimitPolicy
+	RateLimitStore ratelimit.Store
+	// Idempotency-Keyの扱いと、レスポンスを保存する先。IdempotencyStoreがnilの場合はヘッダを無視する
+	Idempotency      handler.IdempotencyPolicy
+	IdempotencyStore idempotency.Store
+	// X-Forwarded-Forを信頼するプロキシのIPアドレスまたはCIDR。空の場合は接続元のIPアドレスを使う
+	TrustedProxies []string
+	// 旧エンドポイントやSwagger UIの公開
+	Features config.FeatureConfig
+	// /readyzで確認する依存先と停止の状態。nilの場合、/readyzは常に200を返す
+	Readiness handler.Readiness
+	// /versionで返すビルドの情報
+	Build handler.BuildInfo
+}
+
+// NewRouter はミドルウェアと全てのエンドポイントを設定したルーターを返す
+func NewRouter(deps Deps) *gin.Engine {
+	complaintHandler := handler.NewComplaintHandler(deps.ComplaintUseCase)
+	avatarHandler := handler.NewAvatarHandler(deps.AvatarUseCase, deps.AvatarImages)
+	healthHandler := handler.NewHealthHandler(deps.Readiness, deps.Build)
+	exportHandler := handler.NewExportHandler(deps.ComplaintUseCase, deps.AvatarUseCase)
+	importHandler := handler.NewImportHandler(deps.ComplaintUseCase, deps.AvatarUseCase)
+
+	// v2はユースケースを共有し、レスポンスの形式のみ変える
+	complaintHandlerV2 := handlerV2.NewComplaintHandler(deps.ComplaintUseCase)
+	avatarHandlerV2 := handlerV2.NewAvatarHandler(deps.AvatarUseCase, deps.AvatarImages)
+
+	// アクセスログはrequestLoggerで出力するため、gin.Default()のLoggerは使わない
+	router := gin.New()
+	// c.ClientIP()はアクセスログとリクエスト数の制限に使う。ginのデフォルトは全てのプロキシを信頼するため、明示的に設定する
+	// 値はconfig.Loadで検証済み。不正な値の場合はどのプロキシも信頼しない
+	if err := router.SetTrustedProxies(deps.TrustedProxies); err != nil {
+		logging.Log.Error("Failed at SetTrustedProxies()", rz.Err(err))
+		router.SetTrustedProxies(nil)
+	}
+	// メトリクス。panicからの復帰で書いた500も数えるため、Recoveryより外側に置く
+	router.Use(requestMetrics())
+	router.Use(gin.Recovery())
+
+	// リクエストID設定
+	router.Use(requestid.New())
+
+	// トレース。traceparentを引き継ぎ、ログにトレースIDを付けるためrequestLoggerより前に置く
+	router.Use(requestTracing())
+
+	// ロギング設定
+	router.Use(requestLogger())
+
+	// CORS設定
+	corsConf := cors.DefaultConfig()
+	corsConf.AllowOrigins = deps.AllowOrigins
+	router.Use(cors.New(corsConf))
+
+	// リクエスト数の制限。制限したリクエストは処理時間の上限より前に返す
+	rateLimits := deps.RateLimits
+	if rateLimits.Actions == nil {
+		rateLimits.Actions = routeActions
+	}
+	router.Use(handler.RateLimit(rateLimits, deps.RateLimitStore))
+
+	// 処理時間の上限。ルートのパターンで上限を決めるため、ルーティング後に評価される
+	router.Use(handler.Timeout(deps.Timeouts))
+
+	// ヘルスチェック。ロードバランサーやオーケストレーターが使うため、バージョンなしで公開する
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/version", healthHandler.Version)
+
+	// Prometheusのメトリクス
+	if deps.Features.Metrics {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
+	// Idempotency-Keyによる再送の検出。ボディをメモリに読み込むため、登録のルートにのみ付ける
+	// ルートのミドルウェアはリクエスト数の制限と処理時間の上限の後に実行される
+	idempotent := handler.Idempotency(deps.Idempotency, deps.IdempotencyStore)
+
+	// エンドポイントの設定
+	registerV1Routes(router.Group("/v1"), idempotent, complaintHandler, avatarHandler)
+	registerV2Routes(router.Group("/v2"), idempotent, complaintHandlerV2, avatarHandlerV2)
+	registerExportRoutes(router.Group("/export"), exportHandler)
+	// 取り込みは認証がないため、有効にした場合のみ公開する
+	if deps.Features.Import {
+		registerImportRoutes(router.Group("/import"), importHandler)
+	}
+
+	// バージョンなしの旧エンドポイント。/v1と同じハンドラで、廃止予定をヘッダで通知する
+	if deps.Features.LegacyRoutes {
+		legacy := router.Group("", handler.Deprecated(handler.DeprecationPolicy{
+			VersionPrefix: "/v1",
+			Sunset:        legacyRoutesSunset,
+		}))
+		registerV1Routes(legacy, idempotent, complaintHandler, avatarHandler)
+	}
+
+	// http://localhost:8080/swagger/v1/index.html, /swagger/v2/index.html にswagger UI を表示する
+	if deps.Features.Swagger {
+		router.GET("/swagger/v1/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName(docsV1.SwaggerInfov1.InstanceName())))
+		router.GET("/swagger/v2/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName(docsV2.SwaggerInfov2.InstanceName())))
+	}
+
+	return router
+}
+
+// requestTracing はリクエストごとにスパンを開始し、リクエストのコンテキストに設定するミドルウェア
+// traceparentヘッダがあれば、そのトレースの子スパンにする
+func requestTracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.StartHTTP(ctx, c.Request.Method, route, c.Request.URL.Path, requestid.Get(c))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		tracing.SetHTTPStatus(span, c.Writer.Status())
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
+
+// requestLogger はリクエストIDとルートを付けたロガーをリクエストのコンテキストに設定し、
+// 処理の完了後にアクセスログを1行出力するミドルウェア
+// ユースケースやリポジトリではlogging.FromContext(ctx)でこのロガーを使う
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		// requestid.New()がヘッダになければ採番したID
+		requestId := requestid.Get(c)
+
+		contextFields := []rz.Field{
+			rz.String(XRequestId, requestId),
+			rz.String("route", c.FullPath()),
+		}
+		// ログからトレースを探せるよう、requestTracingが開始したスパンのIDを付ける
+		if traceId, spanId, ok := tracing.IDs(c.Request.Context()); ok {
+			contextFields = append(contextFields, rz.String("traceId", traceId), rz.String("spanId", spanId))
+		}
+		logger := logging.Log.With(rz.Fields(contextFields...))
+		c.Request = c.Request.WithContext(logging.WithContext(c.Request.Context(), logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		// ボディを書いていない場合のSize()は-1
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		fields := []rz.Field{
+			rz.String("method", c.Request.Method),
+			rz.String("path", c.Request.URL.Path),
+			rz.Int("status", status),
+			rz.Duration("latency", time.Since(start)),
+			rz.Int("bytes", size),
+			rz.String("clientIp", c.ClientIP()),
+			rz.String("userAgent", c.Request.UserAgent()),
+		}
+		if userId := c.GetString(UserIdKey); userId != "" {
+			fields = append(fields, rz.String("userId", userId))
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, rz.String("errors", c.Errors.String()))
+		}
+
+		accessLog := logging.FromContext(c.Request.Context())
+		switch {
+		case status >= 500:
+			accessLog.Error("access", fields...)
+		case status >= 400:
+			accessLog.Warn("access", fields...)
+		default:
+			accessLog.Info("access", fields...)
+		}
+	}
+}
+
+// requestMetrics はルートのパターンごとのリクエスト数と処理時間を記録するミドルウェア
+// ルーター全体に設定するので、エンドポイントを追加すると自動で記録される
+func requestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// パスごとにラベルを作らないよう、ルートのパターンを使う。該当するルートがない場合は空になる
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
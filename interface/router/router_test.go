@@ -0,0 +1,36 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/backend-guchitter-app/config"
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
+	"github.com/backend-guchitter-app/interface/router"
+	"github.com/backend-guchitter-app/interface/router/routertest"
+)
+
+func TestRoutesInMemory(t *testing.T) {
+	routertest.RunRouteCases(t, routertest.NewInMemory, routertest.RouteCases())
+}
+
+func TestRoutesSQLite(t *testing.T) {
+	routertest.RunRouteCases(t, routertest.NewSQLite, routertest.RouteCases())
+}
+
+func TestImportRoutesDisabledByDefault(t *testing.T) {
+	r := router.NewRouter(router.Deps{
+		AllowOrigins:   []string{routertest.FrontOrigin},
+		Features:       config.Default().Features,
+		RateLimitStore: inmemory.NewRateLimitStore(),
+	})
+	for _, path := range []string{"/import/complaints", "/import/avatars"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader("avatarName\nIchika\n")))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("POST %s status = %d; want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
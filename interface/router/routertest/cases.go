@@ -0,0 +1,376 @@
+package routertest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// RouteCase はルーターに送るリクエスト1件と期待するレスポンス
+type RouteCase struct {
+	Name    string
+	Method  string
+	Path    string
+	Body    string
+	Headers map[string]string
+	// このリクエストの前に同じHarnessへ順に送るリクエスト。再送などの確認に使う。レスポンスは検証しない
+	Before []Request
+
+	WantStatus int
+	// 値まで一致を確認するヘッダ
+	WantHeaders map[string]string
+	// 値は問わず、存在を確認するヘッダ
+	WantHeaderKeys []string
+	// ゴールデンファイル名(testdata/<Golden>.golden.json)。空の場合はボディを比較しない
+	Golden string
+}
+
+// Request はRouteCaseの前に送るリクエスト
+type Request struct {
+	Method  string
+	Path    string
+	Body    string
+	Headers map[string]string
+}
+
+// RunRouteCases はケースごとにnewHarnessで新しいHarnessを作り、リクエストを送って結果を検証する
+// ケース同士でデータを共有しないので、登録・削除のケースも順序に依存しない
+func RunRouteCases(t *testing.T, newHarness func(t *testing.T) *Harness, cases []RouteCase) {
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			h := newHarness(t)
+			for _, req := range tc.Before {
+				h.Do(req.Method, req.Path, req.Body, req.Headers)
+			}
+			w := h.Do(tc.Method, tc.Path, tc.Body, tc.Headers)
+
+			if w.Code != tc.WantStatus {
+				t.Errorf("%s %s status = %d; want %d\n%s", tc.Method, tc.Path, w.Code, tc.WantStatus, w.Body.String())
+			}
+			for key, want := range tc.WantHeaders {
+				if got := w.Header().Get(key); got != want {
+					t.Errorf("%s %s header %s = %q; want %q", tc.Method, tc.Path, key, got, want)
+				}
+			}
+			for _, key := range tc.WantHeaderKeys {
+				if w.Header().Get(key) == "" {
+					t.Errorf("%s %s header %s is missing", tc.Method, tc.Path, key)
+				}
+			}
+			if tc.Golden != "" {
+				AssertGolden(t, tc.Golden, w.Body.String())
+			}
+		})
+	}
+}
+
+// RouteCases はルーターの全エンドポイントを対象にしたケースの一覧
+// データはHarness.Seedで登録したものを前提とする
+func RouteCases() []RouteCase {
+	legacyHeaders := map[string]string{
+		"Deprecation": "true",
+		"Sunset":      "Fri, 30 Apr 2027 00:00:00 GMT",
+	}
+
+	idempotencyKey := map[string]string{"Idempotency-Key": "3f9c2e1a-create-complaint"}
+
+	imageBody, imageHeaders := imageUpload("image", "nino.png", avatarPNG)
+	imageVersion := avatarPNGVersion()
+	uploadImage := []Request{{Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: imageBody, Headers: imageHeaders}}
+	notImageBody, notImageHeaders := imageUpload("image", "nino.png", []byte("GIF89a not supported"))
+	brokenImageBody, brokenImageHeaders := imageUpload("image", "nino.png", []byte("\x89PNG\r\n\x1a\nbroken"))
+	largeImageBody, largeImageHeaders := imageUpload("image", "nino.png", bytes.Repeat([]byte{0}, 5<<20+1))
+	missingImageBody, missingImageHeaders := imageUpload("file", "nino.png", avatarPNG)
+
+	return []RouteCase{
+		// v1 Complaints
+		{Name: "v1 complaints index", Method: http.MethodGet, Path: "/v1/complaints", WantStatus: http.StatusOK, Golden: "v1_complaints_index"},
+		{Name: "v1 complaints filter", Method: http.MethodGet, Path: "/v1/complaints?filter[avatarId]=1&filter[complaintId][gt]=1", WantStatus: http.StatusOK, Golden: "v1_complaints_filter"},
+		{Name: "v1 complaints filter in", Method: http.MethodGet, Path: "/v1/complaints?filter[complaintId][in]=1,3", WantStatus: http.StatusOK, Golden: "v1_complaints_filter_in"},
+		{Name: "v1 complaints minAnger", Method: http.MethodGet, Path: "/v1/complaints?minAnger=0.5", WantStatus: http.StatusOK, Golden: "v1_complaints_min_anger"},
+		{Name: "v1 complaints unknown filter", Method: http.MethodGet, Path: "/v1/complaints?filter[complaintText]=x", WantStatus: http.StatusBadRequest, Golden: "v1_complaints_unknown_filter"},
+		{Name: "v1 complaints bad operator", Method: http.MethodGet, Path: "/v1/complaints?filter[avatarId][like]=1", WantStatus: http.StatusBadRequest, Golden: "v1_complaints_bad_operator"},
+		{Name: "v1 complaints bad minAnger", Method: http.MethodGet, Path: "/v1/complaints?minAnger=high", WantStatus: http.StatusBadRequest, Golden: "v1_complaints_bad_min_anger"},
+		{Name: "v1 complaints bad tz", Method: http.MethodGet, Path: "/v1/complaints?filter[lastUpdate][gte]=today&tz=Mars/Olympus", WantStatus: http.StatusBadRequest, Golden: "v1_complaints_bad_tz"},
+		{Name: "v1 complaints search", Method: http.MethodGet, Path: "/v1/complaints/2", WantStatus: http.StatusOK, Golden: "v1_complaints_search"},
+		{Name: "v1 complaints search not found", Method: http.MethodGet, Path: "/v1/complaints/99", WantStatus: http.StatusNotFound, Golden: "v1_not_found"},
+		{Name: "v1 complaints create", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, WantStatus: http.StatusOK,
+			WantHeaders: map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "9"}, WantHeaderKeys: []string{"RateLimit-Reset"}, Golden: "v1_complaints_create"},
+		{Name: "complaints create shares the rate limit across versions", Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintText":"電車が遅れて会議に間に合わなかった","avatarId":2}`,
+			Before: []Request{
+				{Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`},
+				{Method: http.MethodPost, Path: "/complaints", Body: `{"complaintText":"隣の工事の音がうるさい","avatarId":2}`},
+			},
+			WantStatus: http.StatusCreated, WantHeaders: map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "7"}},
+		{Name: "v1 complaints create duplicate", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"勘弁してくれ！！","avatarId":1}`, WantStatus: http.StatusConflict, Golden: "v1_complaints_create_duplicate"},
+		{Name: "v1 complaints create near-duplicate", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"うちの上司がほんとにムカつく","avatarId":1}`, WantStatus: http.StatusConflict, Golden: "v1_complaints_create_near_duplicate"},
+		{Name: "v1 complaints create same text by another avatar", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"勘弁してくれ!","avatarId":2}`, WantStatus: http.StatusOK},
+		{Name: "v1 complaints create without avatar", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい"}`, WantStatus: http.StatusBadRequest, Golden: "v1_complaints_create_without_avatar"},
+		{Name: "v1 complaints create invalid json", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":`, WantStatus: http.StatusBadRequest},
+		{Name: "v1 complaints between-time", Method: http.MethodGet, Path: "/v1/complaints/between-time?from=-1h&to=now", WantStatus: http.StatusOK,
+			WantHeaders: map[string]string{"Deprecation": "true", "Link": `</v1/complaints>; rel="successor-version"`}, Golden: "v1_complaints_index"},
+		{Name: "v1 complaints between-time bad from", Method: http.MethodGet, Path: "/v1/complaints/between-time?from=yesterday-ish", WantStatus: http.StatusBadRequest, Golden: "v1_between_time_bad_from"},
+		{Name: "v1 complaints between-time reversed", Method: http.MethodGet, Path: "/v1/complaints/between-time?from=now&to=-1h", WantStatus: http.StatusBadRequest, Golden: "v1_between_time_reversed"},
+		{Name: "v1 complaints between-time empty", Method: http.MethodGet, Path: "/v1/complaints/between-time?from=2000-01-01&to=2000-01-02", WantStatus: http.StatusNotFound, Golden: "v1_not_found"},
+		{Name: "v1 complaints delete", Method: http.MethodDelete, Path: "/v1/complaints/3", WantStatus: http.StatusNoContent},
+
+		// v1 Avatars
+		{Name: "v1 avatars index", Method: http.MethodGet, Path: "/v1/avatars", WantStatus: http.StatusOK, Golden: "v1_avatars_index"},
+		{Name: "v1 avatars filter", Method: http.MethodGet, Path: "/v1/avatars?filter[avatarName]=Miku", WantStatus: http.StatusOK, Golden: "v1_avatars_filter"},
+		{Name: "v1 avatars string comparison", Method: http.MethodGet, Path: "/v1/avatars?filter[avatarName][gt]=M", WantStatus: http.StatusBadRequest, Golden: "v1_avatars_string_comparison"},
+		{Name: "v1 avatars search", Method: http.MethodGet, Path: "/v1/avatars/1", WantStatus: http.StatusOK, Golden: "v1_avatars_search"},
+		{Name: "v1 avatars search not found", Method: http.MethodGet, Path: "/v1/avatars/99", WantStatus: http.StatusNotFound, Golden: "v1_not_found"},
+		{Name: "v1 avatars create", Method: http.MethodPost, Path: "/v1/avatars", Body: `{"avatarName":"Ichika","avatarText":"だよね","color":"#f4c2c2"}`, WantStatus: http.StatusOK, Golden: "v1_avatars_create"},
+		{Name: "v1 avatars create rgb color", Method: http.MethodPost, Path: "/v1/avatars", Body: `{"avatarName":"Ichika","avatarText":"だよね","color":"rgb(244 194 194)"}`, WantStatus: http.StatusOK, Golden: "v1_avatars_create"},
+		{Name: "v1 avatars create named color", Method: http.MethodPost, Path: "/v1/avatars", Body: `{"avatarName":"Itsuki","avatarText":"です","color":"DarkRed"}`, WantStatus: http.StatusOK, Golden: "v1_avatars_create_named_color"},
+		{Name: "v1 avatars create invalid color", Method: http.MethodPost, Path: "/v1/avatars", Body: `{"avatarName":"Ichika","color":"#f4c2c2c2"}`, WantStatus: http.StatusBadRequest, Golden: "v1_avatars_invalid_color"},
+		{Name: "v1 avatars create invalid json", Method: http.MethodPost, Path: "/v1/avatars", Body: `[`, WantStatus: http.StatusBadRequest},
+		{Name: "v1 avatars between-time", Method: http.MethodGet, Path: "/v1/avatars/between-time?from=today&tz=Asia/Tokyo", WantStatus: http.StatusOK,
+			WantHeaders: map[string]string{"Deprecation": "true"}, Golden: "v1_avatars_index"},
+		{Name: "v1 avatars delete", Method: http.MethodDelete, Path: "/v1/avatars/2", WantStatus: http.StatusNoContent},
+
+		// v1 Avatarの画像
+		{Name: "v1 avatar image upload", Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: imageBody, Headers: imageHeaders,
+			WantStatus: http.StatusOK, Golden: "v1_avatar_image_upload"},
+		{Name: "v1 avatar image upload unsupported", Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: notImageBody, Headers: notImageHeaders,
+			WantStatus: http.StatusUnsupportedMediaType, Golden: "v1_avatar_image_unsupported"},
+		{Name: "v1 avatar image upload broken", Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: brokenImageBody, Headers: brokenImageHeaders,
+			WantStatus: http.StatusBadRequest, Golden: "v1_avatar_image_broken"},
+		{Name: "v1 avatar image upload too large", Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: largeImageBody, Headers: largeImageHeaders,
+			WantStatus: http.StatusRequestEntityTooLarge, Golden: "v1_avatar_image_too_large"},
+		{Name: "v1 avatar image upload without image", Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: missingImageBody, Headers: missingImageHeaders,
+			WantStatus: http.StatusBadRequest, Golden: "v1_avatar_image_missing"},
+		{Name: "v1 avatar image upload not found", Method: http.MethodPost, Path: "/v1/avatars/99/image", Body: imageBody, Headers: imageHeaders,
+			WantStatus: http.StatusNotFound, Golden: "v1_not_found"},
+		{Name: "v1 avatar image", Method: http.MethodGet, Path: "/v1/avatars/1/image", Before: uploadImage,
+			WantStatus:  http.StatusOK,
+			WantHeaders: map[string]string{"Content-Type": "image/png", "Cache-Control": "public, no-cache", "ETag": `"` + imageVersion + `"`, "X-Content-Type-Options": "nosniff"}},
+		{Name: "v1 avatar image versioned", Method: http.MethodGet, Path: "/v1/avatars/1/image?size=small&v=" + imageVersion, Before: uploadImage,
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Content-Type": "image/png", "Cache-Control": "public, max-age=31536000, immutable"}},
+		{Name: "v1 avatar image not modified", Method: http.MethodGet, Path: "/v1/avatars/1/image?size=large", Before: uploadImage,
+			Headers:    map[string]string{"If-None-Match": `"` + imageVersion + `"`},
+			WantStatus: http.StatusNotModified, WantHeaders: map[string]string{"ETag": `"` + imageVersion + `"`}},
+		{Name: "v1 avatar image bad size", Method: http.MethodGet, Path: "/v1/avatars/1/image?size=huge", Before: uploadImage,
+			WantStatus: http.StatusBadRequest, Golden: "v1_avatar_image_bad_size"},
+		{Name: "v1 avatar image not uploaded", Method: http.MethodGet, Path: "/v1/avatars/1/image", WantStatus: http.StatusNotFound, Golden: "v1_not_found"},
+		{Name: "v1 avatar image deleted with avatar", Method: http.MethodGet, Path: "/v1/avatars/1/image",
+			Before:     append(uploadImage, Request{Method: http.MethodDelete, Path: "/v1/avatars/1"}),
+			WantStatus: http.StatusNotFound, Golden: "v1_not_found"},
+
+		// バージョンなしの旧エンドポイント
+		{Name: "legacy complaints index", Method: http.MethodGet, Path: "/complaints", WantStatus: http.StatusOK,
+			WantHeaders: merge(legacyHeaders, map[string]string{"Link": `</v1/complaints>; rel="successor-version"`}), Golden: "v1_complaints_index"},
+		{Name: "legacy avatars search", Method: http.MethodGet, Path: "/avatars/1", WantStatus: http.StatusOK,
+			WantHeaders: merge(legacyHeaders, map[string]string{"Link": `</v1/avatars/1>; rel="successor-version"`}), Golden: "v1_avatars_search"},
+		{Name: "legacy complaints create", Method: http.MethodPost, Path: "/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, WantStatus: http.StatusOK,
+			WantHeaders: legacyHeaders, Golden: "v1_complaints_create"},
+
+		// Idempotency-Key
+		{Name: "v1 complaints create with idempotency key", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, Headers: idempotencyKey,
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Idempotent-Replayed": ""}, Golden: "v1_complaints_create"},
+		// 重複の判定より前に保存したレスポンスを返すので、409にならない
+		{Name: "v1 complaints create replayed", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, Headers: idempotencyKey,
+			Before:     []Request{{Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, Headers: idempotencyKey}},
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Idempotent-Replayed": "true"}, Golden: "v1_complaints_create"},
+		{Name: "v1 complaints create idempotency key with another body", Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"火曜もつらい","avatarId":2}`, Headers: idempotencyKey,
+			Before:     []Request{{Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, Headers: idempotencyKey}},
+			WantStatus: http.StatusUnprocessableEntity, Golden: "idempotency_key_reused"},
+		// キーはルートごとに区別する
+		{Name: "v1 avatars create with idempotency key used for complaints", Method: http.MethodPost, Path: "/v1/avatars", Body: `{"avatarName":"Ichika","avatarText":"だよね","color":"#f4c2c2"}`, Headers: idempotencyKey,
+			Before:     []Request{{Method: http.MethodPost, Path: "/v1/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, Headers: idempotencyKey}},
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Idempotent-Replayed": ""}, Golden: "v1_avatars_create"},
+		{Name: "v2 complaints create replayed", Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, Headers: idempotencyKey,
+			Before:     []Request{{Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, Headers: idempotencyKey}},
+			WantStatus: http.StatusCreated, WantHeaders: map[string]string{"Idempotent-Replayed": "true"}, Golden: "v2_complaints_create"},
+		// 画像のアップロードと取り込みはIdempotency-Keyを扱わない(multipartの区切りは再送のたびに変わりうる)
+		{Name: "v1 avatar image upload with idempotency key", Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: imageBody, Headers: merge(imageHeaders, idempotencyKey),
+			Before:     []Request{{Method: http.MethodPost, Path: "/v1/avatars/1/image", Body: imageBody, Headers: merge(imageHeaders, idempotencyKey)}},
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Idempotent-Replayed": ""}, Golden: "v1_avatar_image_upload"},
+		{Name: "idempotency key too long", Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`,
+			Headers: map[string]string{"Idempotency-Key": strings.Repeat("k", 256)}, WantStatus: http.StatusBadRequest, Golden: "idempotency_key_too_long"},
+
+		// v2 Complaints
+		{Name: "v2 complaints index", Method: http.MethodGet, Path: "/v2/complaints", WantStatus: http.StatusOK, Golden: "v2_complaints_index"},
+		{Name: "v2 complaints first page", Method: http.MethodGet, Path: "/v2/complaints?limit=2", WantStatus: http.StatusOK, Golden: "v2_complaints_first_page"},
+		{Name: "v2 complaints last page", Method: http.MethodGet, Path: "/v2/complaints?limit=2&offset=2", WantStatus: http.StatusOK, Golden: "v2_complaints_last_page"},
+		{Name: "v2 complaints filter", Method: http.MethodGet, Path: "/v2/complaints?filter[sadness][gte]=0.5", WantStatus: http.StatusOK, Golden: "v2_complaints_filter"},
+		{Name: "v2 complaints bad limit", Method: http.MethodGet, Path: "/v2/complaints?limit=0", WantStatus: http.StatusBadRequest, Golden: "v2_bad_limit"},
+		{Name: "v2 complaints bad offset", Method: http.MethodGet, Path: "/v2/complaints?offset=-1", WantStatus: http.StatusBadRequest, Golden: "v2_bad_offset"},
+		{Name: "v2 complaints between-time is not routed", Method: http.MethodGet, Path: "/v2/complaints/between-time", WantStatus: http.StatusBadRequest, Golden: "v2_bad_id"},
+		{Name: "v2 complaints search", Method: http.MethodGet, Path: "/v2/complaints/2", WantStatus: http.StatusOK, Golden: "v2_complaints_search"},
+		{Name: "v2 complaints search not found", Method: http.MethodGet, Path: "/v2/complaints/99", WantStatus: http.StatusNotFound, Golden: "v2_not_found"},
+		{Name: "v2 complaints create", Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintText":"月曜はつらい","avatarId":2}`, WantStatus: http.StatusCreated, Golden: "v2_complaints_create"},
+		{Name: "v2 complaints create ignores server fields", Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintId":1,"complaintText":"月曜はつらい","avatarId":2,"duplicateCount":999}`, WantStatus: http.StatusCreated, Golden: "v2_complaints_create"},
+		{Name: "v2 complaints create duplicate", Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintText":"雨で悲しい","avatarId":2}`, WantStatus: http.StatusConflict, Golden: "v2_complaints_create_duplicate"},
+		{Name: "v2 complaints create invalid json", Method: http.MethodPost, Path: "/v2/complaints", Body: `{`, WantStatus: http.StatusBadRequest, Golden: "v2_invalid_json"},
+		{Name: "v2 complaints delete", Method: http.MethodDelete, Path: "/v2/complaints/1", WantStatus: http.StatusNoContent},
+		{Name: "v2 complaints delete bad id", Method: http.MethodDelete, Path: "/v2/complaints/one", WantStatus: http.StatusBadRequest, Golden: "v2_bad_id"},
+
+		{Name: "v2 complaints create empty text", Method: http.MethodPost, Path: "/v2/complaints", Body: `{"complaintText":"  ","avatarId":2}`, WantStatus: http.StatusBadRequest, Golden: "v2_complaints_create_empty_text"},
+
+		// v2 一括処理
+		{Name: "v2 complaints batch", Method: http.MethodPost, Path: "/v2/complaints:batch",
+			Body:       `{"items":[{"complaintText":"月曜はつらい","avatarId":2},{"complaintText":"残業が終わらない","avatarId":1}]}`,
+			WantStatus: http.StatusCreated, Golden: "v2_complaints_batch"},
+		// 1件目は保存済みのぐち、3件目はバッチ内の1件目と重複する
+		{Name: "v2 complaints batch atomic with failures", Method: http.MethodPost, Path: "/v2/complaints:batch",
+			Body:       `{"items":[{"complaintText":"雨で悲しい","avatarId":2},{"complaintText":"","avatarId":1},{"complaintText":"月曜はつらい","avatarId":2},{"complaintText":"月曜は辛い","avatarId":1}]}`,
+			WantStatus: http.StatusUnprocessableEntity, Golden: "v2_complaints_batch_atomic"},
+		{Name: "v2 complaints batch best effort", Method: http.MethodPost, Path: "/v2/complaints:batch",
+			Body:       `{"mode":"bestEffort","items":[{"complaintText":"月曜はつらい","avatarId":2},{"complaintText":"","avatarId":1},{"complaintText":"月曜はつらい!!","avatarId":2}]}`,
+			WantStatus: http.StatusMultiStatus, Golden: "v2_complaints_batch_best_effort"},
+		{Name: "v2 complaints batch bad mode", Method: http.MethodPost, Path: "/v2/complaints:batch", Body: `{"mode":"some","items":[{"complaintText":"月曜はつらい","avatarId":2}]}`,
+			WantStatus: http.StatusBadRequest, Golden: "v2_batch_bad_mode"},
+		{Name: "v2 complaints batch empty", Method: http.MethodPost, Path: "/v2/complaints:batch", Body: `{"items":[]}`,
+			WantStatus: http.StatusBadRequest, Golden: "v2_batch_empty"},
+		{Name: "v2 complaints batchDelete", Method: http.MethodPost, Path: "/v2/complaints:batchDelete", Body: `{"ids":[1,3]}`,
+			WantStatus: http.StatusOK, Golden: "v2_complaints_batch_delete"},
+		{Name: "v2 complaints batchDelete atomic with missing id", Method: http.MethodPost, Path: "/v2/complaints:batchDelete", Body: `{"ids":[1,99]}`,
+			WantStatus: http.StatusUnprocessableEntity, Golden: "v2_complaints_batch_delete_atomic"},
+		{Name: "v2 complaints batchDelete best effort", Method: http.MethodPost, Path: "/v2/complaints:batchDelete", Body: `{"mode":"bestEffort","ids":[1,99]}`,
+			WantStatus: http.StatusMultiStatus, Golden: "v2_complaints_batch_delete_best_effort"},
+		{Name: "v2 complaints batch counts items for the rate limit", Method: http.MethodPost, Path: "/v2/complaints:batch",
+			Body:       `{"items":[{"complaintText":"月曜はつらい","avatarId":2}]}`,
+			Before:     []Request{{Method: http.MethodPost, Path: "/v2/complaints:batchDelete", Body: `{"mode":"bestEffort","ids":[` + batchIds(1000) + `]}`}},
+			WantStatus: http.StatusTooManyRequests, WantHeaderKeys: []string{"Retry-After"}, Golden: "v2_complaints_batch_too_many_items"},
+		{Name: "v2 complaints unknown custom method", Method: http.MethodPost, Path: "/v2/complaints:purge", Body: `{}`, WantStatus: http.StatusNotFound, Golden: "v2_not_found"},
+		{Name: "v2 avatars batch", Method: http.MethodPost, Path: "/v2/avatars:batch",
+			Body:       `{"items":[{"avatarName":"Ichika","avatarText":"だよね"},{"avatarName":"Yotsuba","avatarText":"です!"}]}`,
+			WantStatus: http.StatusCreated, Golden: "v2_avatars_batch"},
+		{Name: "v2 avatars batch best effort", Method: http.MethodPost, Path: "/v2/avatars:batch",
+			Body:       `{"mode":"bestEffort","items":[{"avatarName":"","avatarText":"だよね"},{"avatarName":"Yotsuba","avatarText":"です!"}]}`,
+			WantStatus: http.StatusMultiStatus, Golden: "v2_avatars_batch_best_effort"},
+
+		// v2 Avatars
+		{Name: "v2 avatars index", Method: http.MethodGet, Path: "/v2/avatars?limit=1", WantStatus: http.StatusOK, Golden: "v2_avatars_first_page"},
+		{Name: "v2 avatars search", Method: http.MethodGet, Path: "/v2/avatars/2", WantStatus: http.StatusOK, Golden: "v2_avatars_search"},
+		{Name: "v2 avatars search not found", Method: http.MethodGet, Path: "/v2/avatars/99", WantStatus: http.StatusNotFound, Golden: "v2_not_found"},
+		{Name: "v2 avatars create", Method: http.MethodPost, Path: "/v2/avatars", Body: `{"avatarName":"Ichika","avatarText":"だよね"}`, WantStatus: http.StatusCreated, Golden: "v2_avatars_create"},
+		{Name: "v2 avatars create invalid color", Method: http.MethodPost, Path: "/v2/avatars", Body: `{"avatarName":"Ichika","color":"rgba(0, 0, 0, 0.5)"}`, WantStatus: http.StatusBadRequest, Golden: "v2_avatars_invalid_color"},
+		{Name: "v2 avatars delete", Method: http.MethodDelete, Path: "/v2/avatars/1", WantStatus: http.StatusNoContent},
+		{Name: "v2 avatar image upload", Method: http.MethodPost, Path: "/v2/avatars/2/image", Body: imageBody, Headers: imageHeaders,
+			WantStatus: http.StatusOK, Golden: "v2_avatar_image_upload"},
+		{Name: "v2 avatar image upload unsupported", Method: http.MethodPost, Path: "/v2/avatars/2/image", Body: notImageBody, Headers: notImageHeaders,
+			WantStatus: http.StatusUnsupportedMediaType, Golden: "v2_avatar_image_unsupported"},
+		{Name: "v2 avatar image", Method: http.MethodGet, Path: "/v2/avatars/1/image?size=small", Before: uploadImage,
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Content-Type": "image/png", "ETag": `"` + imageVersion + `"`}},
+		{Name: "v2 avatar image not uploaded", Method: http.MethodGet, Path: "/v2/avatars/2/image", WantStatus: http.StatusNotFound, Golden: "v2_not_found"},
+
+		// 書き出し。CSVは日時が変わるため、登録日時が未来の範囲で列名の行のみを比べる
+		{Name: "export complaints jsonl", Method: http.MethodGet, Path: "/export/complaints?format=jsonl",
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Content-Type": "application/jsonl"},
+			WantHeaderKeys: []string{"Content-Disposition"}, Golden: "export_complaints_jsonl"},
+		{Name: "export complaints ndjson", Method: http.MethodGet, Path: "/export/complaints?format=ndjson",
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Content-Type": "application/x-ndjson"}, Golden: "export_complaints_jsonl"},
+		{Name: "export complaints csv", Method: http.MethodGet, Path: "/export/complaints?from=%2B1h",
+			WantStatus: http.StatusOK, WantHeaders: map[string]string{"Content-Type": "text/csv; charset=utf-8"},
+			WantHeaderKeys: []string{"Content-Disposition"}, Golden: "export_complaints_csv_empty"},
+		{Name: "export avatars csv without bom", Method: http.MethodGet, Path: "/export/avatars?format=csv&bom=false&from=%2B1h",
+			WantStatus: http.StatusOK, Golden: "export_avatars_csv_empty"},
+		{Name: "export avatars jsonl", Method: http.MethodGet, Path: "/export/avatars?format=jsonl",
+			WantStatus: http.StatusOK, Golden: "export_avatars_jsonl"},
+		{Name: "export invalid format", Method: http.MethodGet, Path: "/export/complaints?format=xml", WantStatus: http.StatusBadRequest, Golden: "export_invalid_format"},
+
+		// 取り込み
+		{Name: "import complaints csv", Method: http.MethodPost, Path: "/import/complaints",
+			Body:       "complaintText,avatarId\n月曜はつらい,2\n,1\n残業が終わらない,x\n\"'=改行を\n含むぐち\",1\n",
+			WantStatus: http.StatusOK, Golden: "import_complaints_csv"},
+		{Name: "import complaints resume", Method: http.MethodPost, Path: "/import/complaints?format=jsonl&resumeAfter=1",
+			Body:       `{"complaintText":"月曜はつらい","avatarId":2}` + "\n" + `{"complaintText":"残業が終わらない","avatarId":1}` + "\n",
+			WantStatus: http.StatusOK, Golden: "import_complaints_resume"},
+		{Name: "import avatars dry run", Method: http.MethodPost, Path: "/import/avatars?format=ndjson&dryRun=true",
+			Body:       `{"avatarName":"Ichika","avatarText":"だよね"}` + "\n\n" + `{"avatarName":""}` + "\n" + `{"avatarName":1}`,
+			WantStatus: http.StatusOK, Golden: "import_avatars_dry_run"},
+		{Name: "import missing column", Method: http.MethodPost, Path: "/import/complaints", Body: "complaintText\n月曜はつらい\n",
+			WantStatus: http.StatusBadRequest, Golden: "import_missing_column"},
+		{Name: "import malformed csv", Method: http.MethodPost, Path: "/import/complaints", Body: "complaintText,avatarId\na\"b,1\n",
+			WantStatus: http.StatusBadRequest, Golden: "import_malformed_csv"},
+		{Name: "import invalid batch size", Method: http.MethodPost, Path: "/import/avatars?batchSize=5000", Body: "avatarName\nIchika\n",
+			WantStatus: http.StatusBadRequest, Golden: "import_invalid_batch_size"},
+		{Name: "import too large", Method: http.MethodPost, Path: "/import/complaints", Body: "complaintText,avatarId\n" + strings.Repeat("a", 32<<20),
+			WantStatus: http.StatusRequestEntityTooLarge, Golden: "import_too_large"},
+		{Name: "import rate limited", Method: http.MethodPost, Path: "/import/avatars?dryRun=true", Body: "avatarName\nIchika\n",
+			Before: []Request{
+				{Method: http.MethodPost, Path: "/import/avatars?dryRun=true", Body: "avatarName\nIchika\n"},
+				{Method: http.MethodPost, Path: "/import/avatars?dryRun=true", Body: "avatarName\nIchika\n"},
+			},
+			WantStatus: http.StatusTooManyRequests},
+
+		// ヘルスチェック
+		{Name: "healthz", Method: http.MethodGet, Path: "/healthz", WantStatus: http.StatusOK, Golden: "healthz"},
+		{Name: "readyz", Method: http.MethodGet, Path: "/readyz", WantStatus: http.StatusOK, Golden: "readyz"},
+		{Name: "version", Method: http.MethodGet, Path: "/version", WantStatus: http.StatusOK, Golden: "version"},
+		{Name: "metrics", Method: http.MethodGet, Path: "/metrics", WantStatus: http.StatusOK, WantHeaderKeys: []string{"Content-Type"}},
+
+		// CORS
+		{Name: "cors preflight", Method: http.MethodOptions, Path: "/v1/complaints",
+			Headers:     map[string]string{"Origin": FrontOrigin, "Access-Control-Request-Method": http.MethodPost},
+			WantStatus:  http.StatusNoContent,
+			WantHeaders: map[string]string{"Access-Control-Allow-Origin": FrontOrigin}},
+		{Name: "cors preflight from unknown origin", Method: http.MethodOptions, Path: "/v1/complaints",
+			Headers:    map[string]string{"Origin": "http://evil.example", "Access-Control-Request-Method": http.MethodPost},
+			WantStatus: http.StatusForbidden},
+		{Name: "cors simple request", Method: http.MethodGet, Path: "/v1/avatars",
+			Headers:     map[string]string{"Origin": FrontOrigin},
+			WantStatus:  http.StatusOK,
+			WantHeaders: map[string]string{"Access-Control-Allow-Origin": FrontOrigin}},
+
+		// リクエストID
+		{Name: "request id is propagated", Method: http.MethodGet, Path: "/v1/avatars/1",
+			Headers:     map[string]string{"X-Request-ID": "test-request-id"},
+			WantStatus:  http.StatusOK,
+			WantHeaders: map[string]string{"X-Request-ID": "test-request-id"}},
+		{Name: "request id is generated", Method: http.MethodGet, Path: "/v1/avatars/1",
+			WantStatus: http.StatusOK, WantHeaderKeys: []string{"X-Request-ID"}},
+
+		// Swagger
+		{Name: "swagger v1", Method: http.MethodGet, Path: "/swagger/v1/doc.json", WantStatus: http.StatusOK},
+		{Name: "swagger v2", Method: http.MethodGet, Path: "/swagger/v2/doc.json", WantStatus: http.StatusOK},
+	}
+}
+
+func merge(maps ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, m := range maps {
+		for key, value := range m {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// アップロードに使う6x4の半透明のPNG。透過を含むので、縮小した画像もPNGになる
+var avatarPNG, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAYAAAAECAYAAACtBE5DAAAAK0lEQVR4nATAwQAAMBADsDwKU4jCDW9Yl/BfURRFEQMAAGIAAEAMAAC4AQDlzALVORa2OAAAAABJRU5ErkJggg==")
+
+// avatarPNGVersion はavatarPNGをアップロードした画像のバージョン(ハッシュの先頭16文字)を返す
+func avatarPNGVersion() string {
+	sum := sha256.Sum256(avatarPNG)
+	return hex.EncodeToString(sum[:8])
+}
+
+// imageUpload はbodyをfieldのファイルとして送るmultipart/form-dataのボディとヘッダを返す
+func imageUpload(field, filename string, body []byte) (string, map[string]string) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.SetBoundary("routertest-boundary")
+	part, _ := w.CreateFormFile(field, filename)
+	part.Write(body)
+	w.Close()
+	return buf.String(), map[string]string{"Content-Type": w.FormDataContentType()}
+}
+
+// batchIds は1からnまでのIDをカンマ区切りで返す
+func batchIds(n int) string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,50 @@
+package routertest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"testing"
+)
+
+// 実行のたびに変わる値。ゴールデンファイルとの比較前に置き換える
+var volatileFields = regexp.MustCompile(`("(?:createdAt|lastUpdate)":\s*)"[^"]*"`)
+
+// testdataDir はこのパッケージのtestdataの絶対パス
+// 呼び出し元のテストのパッケージに関わらず同じファイルを参照する
+func testdataDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata")
+}
+
+// Normalize は日時のような実行ごとに変わる値を固定の文字列に置き換える
+func Normalize(body string) string {
+	return volatileFields.ReplaceAllString(body, `$1"<time>"`)
+}
+
+// AssertGolden はbodyをtestdata/<name>.golden.jsonと比較する
+// UPDATE_GOLDEN=1の場合は比較せずにファイルを書き換える
+func AssertGolden(t *testing.T, name, body string) {
+	t.Helper()
+	path := filepath.Join(testdataDir(), name+".golden.json")
+	got := Normalize(body)
+
+	if os.Getenv("UPDATE_GOLDEN") == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with UPDATE_GOLDEN=1 to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("response body does not match %s\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,155 @@
+// Package routertest はルーター全体をHTTPで叩くテストの土台を提供する
+//
+// NewRouterをインメモリまたはSQLiteのリポジトリで組み立て、httptestでリクエストを送る
+//
+//	func TestRoutes(t *testing.T) {
+//		routertest.RunRouteCases(t, routertest.NewInMemory, routertest.RouteCases())
+//	}
+//
+// ゴールデンファイルはtestdata/<name>.golden.jsonにあり、UPDATE_GOLDEN=1で作り直せる
+package routertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/backend-guchitter-app/config"
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/repository"
+	"github.com/backend-guchitter-app/domain/service"
+	"github.com/backend-guchitter-app/idempotency"
+	"github.com/backend-guchitter-app/infrastructure/fingerprint"
+	"github.com/backend-guchitter-app/infrastructure/imaging"
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
+	"github.com/backend-guchitter-app/infrastructure/persistence"
+	"github.com/backend-guchitter-app/infrastructure/sentiment"
+	"github.com/backend-guchitter-app/interface/handler"
+	"github.com/backend-guchitter-app/interface/router"
+	"github.com/backend-guchitter-app/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSで許可するテスト用のオリジン
+const FrontOrigin = "http://localhost:3000"
+
+// /versionで返すテスト用のビルドの情報
+var Build = handler.BuildInfo{Version: "test", Commit: "0000000", BuildTime: "2022-11-27T00:00:00Z"}
+
+// Harness はテスト用に組み立てたルーターとリポジトリ
+// リポジトリを直接触ってデータを準備・確認できる
+type Harness struct {
+	Router      *gin.Engine
+	Complaints  repository.ComplaintRepository
+	Avatars     repository.AvatarRepository
+	Idempotency idempotency.Store
+	Blobs       service.BlobStore
+}
+
+// NewInMemory はインメモリのリポジトリでHarnessを作り、Seedのデータを登録する
+func NewInMemory(t *testing.T) *Harness {
+	t.Helper()
+	return newHarness(t, inmemory.NewComplaintRepository(), inmemory.NewAvatarRepository(), inmemory.NewIdempotencyStore())
+}
+
+// NewSQLite はテストごとに空のインメモリSQLiteでHarnessを作り、Seedのデータを登録する
+func NewSQLite(t *testing.T) *Harness {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db := config.ConnectSQLite(fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return newHarness(t, persistence.NewComplaintPersistence(db), persistence.NewAvatarPersistence(db), persistence.NewIdempotencyStore(db))
+}
+
+func newHarness(t *testing.T, complaints repository.ComplaintRepository, avatars repository.AvatarRepository, idempotencyStore idempotency.Store) *Harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	blobs := inmemory.NewBlobStore()
+	// 重複の判定はデフォルトでは無効のため、判定を確認できるようrejectにする
+	duplicates := config.Default().Duplicates
+	duplicates.Action = usecase.DuplicateActionReject
+	// 取り込みもデフォルトでは無効のため、有効にする
+	features := config.Default().Features
+	features.Import = true
+	h := &Harness{
+		Router: router.NewRouter(router.Deps{
+			ComplaintUseCase: usecase.NewComplaintUseCase(complaints, sentiment.NewLexiconAnalyzer(), fingerprint.NewSimHashFingerprinter(), duplicates.Policy()),
+			AvatarUseCase:    usecase.NewAvatarUseCase(avatars, blobs, imaging.NewThumbnailer(), config.Default().Colors.Policy()),
+			AllowOrigins:     []string{FrontOrigin},
+			AvatarImages:     handler.AvatarImagePolicy{MaxBytes: config.Default().Images.MaxBytes},
+			Features:         features,
+			RateLimits: handler.RateLimitPolicy{
+				Default: config.Default().RateLimits.Default,
+				Routes:  config.Default().RateLimits.Routes,
+				Items:   config.Default().RateLimits.Items,
+			},
+			RateLimitStore: inmemory.NewRateLimitStore(),
+			Idempotency: handler.IdempotencyPolicy{
+				TTL:         config.Default().Idempotency.TTL,
+				LockTimeout: config.Default().Idempotency.LockTimeout,
+			},
+			IdempotencyStore: idempotencyStore,
+			Build:            Build,
+		}),
+		Complaints:  complaints,
+		Avatars:     avatars,
+		Idempotency: idempotencyStore,
+		Blobs:       blobs,
+	}
+	h.Seed(t)
+	return h
+}
+
+// Seed はRouteCasesが前提とするデータを登録する
+// アバター1, 2とぐち1〜3が作られる
+func (h *Harness) Seed(t *testing.T) {
+	t.Helper()
+	analyzer := sentiment.NewLexiconAnalyzer()
+	fingerprinter := fingerprint.NewSimHashFingerprinter()
+
+	for _, a := range []model.Avatar{
+		{AvatarName: "Nino", AvatarText: "なのよ", ImageUrl: "https://hoge.com/nino", Color: "#f6f6f6"},
+		{AvatarName: "Miku", AvatarText: "ですっ", ImageUrl: "https://hoge.com/miku", Color: "#a7d8de"},
+	} {
+		if _, err := h.Avatars.Create(context.Background(), a); err != nil {
+			t.Fatalf("seed avatar: %v", err)
+		}
+	}
+	for _, c := range []model.Complaint{
+		{ComplaintText: "勘弁してくれ!", AvatarId: 1},
+		{ComplaintText: "上司がほんとにムカつく", AvatarId: 1},
+		{ComplaintText: "雨で悲しい", AvatarId: 2},
+	} {
+		c.Sentiment = analyzer.Analyze(c.ComplaintText)
+		c.Fingerprint = fingerprinter.Fingerprint(c.ComplaintText)
+		if _, err := h.Complaints.Create(context.Background(), c); err != nil {
+			t.Fatalf("seed complaint: %v", err)
+		}
+	}
+}
+
+// Do はルーターにリクエストを送り、レスポンスを返す
+func (h *Harness) Do(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"path"
+	"strings"
+
+	"github.com/backend-guchitter-app/interface/handler"
+	handlerV2 "github.com/backend-guchitter-app/interface/handler/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// routeActions は/v1, /v2, バージョンなしで同じ操作をするルートを操作の名前にまとめる
+// リクエスト数の上限は操作ごとに数えるため、別名を使い分けても上限を超えられない
+var routeActions = map[string]string{
+	"POST /v1/complaints": "complaints.create",
+	"POST /v2/complaints": "complaints.create",
+	"POST /complaints":    "complaints.create",
+	"POST /v1/avatars":    "avatars.create",
+	"POST /v2/avatars":    "avatars.create",
+	"POST /avatars":       "avatars.create",
+}
+
+// registerV1Routes は/v1のエンドポイントを設定する
+// バージョンなしの旧エンドポイントも同じ構成で設定する。idempotentは登録のルートに付けるIdempotency-Keyのミドルウェア
+func registerV1Routes(rg *gin.RouterGroup, idempotent gin.HandlerFunc, complaintHandler handler.ComplaintHandler, avatarHandler handler.AvatarHandler) {
+	// Complaints
+	rg.GET("/complaints", complaintHandler.Index)
+	rg.GET("/complaints/:id", complaintHandler.Search)
+	rg.POST("/complaints", idempotent, complaintHandler.Create)
+	// 非推奨: GET /complaints?filter[lastUpdate][gte]=...&filter[lastUpdate][lte]=... を使う
+	rg.GET("/complaints/between-time", handler.Deprecated(handler.DeprecationPolicy{
+		Successor: path.Join(rg.BasePath(), "/complaints"),
+	}), complaintHandler.FindBetweenTimestamp)
+	rg.DELETE("/complaints/:id", complaintHandler.DeleteByComplaintId)
+
+	// Avatars
+	rg.GET("/avatars", avatarHandler.Index)
+	rg.GET("/avatars/:id", avatarHandler.Search)
+	rg.POST("/avatars", idempotent, avatarHandler.Create)
+	// 非推奨: GET /avatars?filter[lastUpdate][gte]=...&filter[lastUpdate][lte]=... を使う
+	rg.GET("/avatars/between-time", handler.Deprecated(handler.DeprecationPolicy{
+		Successor: path.Join(rg.BasePath(), "/avatars"),
+	}), avatarHandler.FindBetweenTimestamp)
+	rg.DELETE("/avatars/:id", avatarHandler.DeleteByAvatarId)
+	rg.POST("/avatars/:id/image", avatarHandler.UploadImage)
+	rg.GET("/avatars/:id/image", avatarHandler.Image)
+}
+
+// registerV2Routes は/v2のエンドポイントを設定する
+// 非推奨のbetween-timeは/v2には用意しない
+func registerV2Routes(rg *gin.RouterGroup, idempotent gin.HandlerFunc, complaintHandler handlerV2.ComplaintHandler, avatarHandler handlerV2.AvatarHandler) {
+	// Complaints
+	rg.GET("/complaints", complaintHandler.Index)
+	rg.GET("/complaints/:id", complaintHandler.Search)
+	rg.POST("/complaints", idempotent, complaintHandler.Create)
+	rg.DELETE("/complaints/:id", complaintHandler.DeleteByComplaintId)
+	rg.POST("/complaints:method", idempotent, customMethods(map[string]gin.HandlerFunc{
+		"batch":       complaintHandler.CreateBatch,
+		"batchDelete": complaintHandler.DeleteBatch,
+	}, handlerV2.NotFound))
+
+	// Avatars
+	rg.GET("/avatars", avatarHandler.Index)
+	rg.GET("/avatars/:id", avatarHandler.Search)
+	rg.POST("/avatars", idempotent, avatarHandler.Create)
+	rg.DELETE("/avatars/:id", avatarHandler.DeleteByAvatarId)
+	rg.POST("/avatars/:id/image", avatarHandler.UploadImage)
+	rg.GET("/avatars/:id/image", avatarHandler.Image)
+	rg.POST("/avatars:method", idempotent, customMethods(map[string]gin.HandlerFunc{
+		"batch": avatarHandler.CreateBatch,
+	}, handlerV2.NotFound))
+}
+
+// registerExportRoutes は/exportのエンドポイントを設定する
+// 書き出しの形式はAPIのバージョンによらないので、バージョンなしで公開する
+func registerExportRoutes(rg *gin.RouterGroup, exportHandler handler.ExportHandler) {
+	rg.GET("/complaints", exportHandler.Complaints)
+	rg.GET("/avatars", exportHandler.Avatars)
+}
+
+// registerImportRoutes は/importのエンドポイントを設定する
+// /exportと同じく、バージョンなしで公開する
+func registerImportRoutes(rg *gin.RouterGroup, importHandler handler.ImportHandler) {
+	rg.POST("/complaints", importHandler.Complaints)
+	rg.POST("/avatars", importHandler.Avatars)
+}
+
+// customMethods は"/complaints:batch"のようなカスタムメソッドを、":"の後の名前でmethodsのハンドラに振り分ける
+// ginはパスの途中の":"以降をパラメータとして扱うため、ルートは"/complaints:method"として登録する
+// パラメータの値は":batch"のように":"を含む。該当するメソッドがなければnotFoundを呼ぶ
+func customMethods(methods map[string]gin.HandlerFunc, notFound gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		param := c.Param("method")
+		if h, ok := methods[strings.TrimPrefix(param, ":")]; ok && strings.HasPrefix(param, ":") {
+			h(c)
+			return
+		}
+		notFound(c)
+	}
+}
@@ -0,0 +1,98 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HTTPServer はsrvを起動・停止するHook
+// 停止時は新しい接続の受け付けをやめ、処理中のリクエストが終わるのをctxの期限まで待つ
+// 期限を超えた場合は残りの接続を切断する
+func HTTPServer(srv *http.Server, registry *Registry) Hook {
+	return Hook{
+		Name: "http server",
+		OnStart: func(ctx context.Context) error {
+			// ポートの使用中などはここで検出して起動を失敗させる
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					registry.Fail(err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// Database はGORMのコネクションプールを閉じるHook
+// checkは/readyzでDBを確認する処理
+func Database(db *gorm.DB, check func(ctx context.Context) error) Hook {
+	return Hook{
+		Name:  "database",
+		Check: check,
+		OnStop: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		},
+	}
+}
+
+// DrainDelay は停止を始めてから、delayの間だけHTTPサーバーの停止を遅らせるHook
+// HTTPサーバーの後に登録する。その間/readyzは503を返すので、ロードバランサーが振り分けをやめるのを待てる
+func DrainDelay(delay time.Duration) Hook {
+	return Hook{
+		Name: "drain delay",
+		OnStop: func(ctx context.Context) error {
+			select {
+			case <-time.After(delay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// Worker はctxがキャンセルされるまで動き続けるバックグラウンドの処理のHook
+// 停止時はrunに渡したctxをキャンセルし、runが返るのを待つ
+func Worker(name string, run func(ctx context.Context)) Hook {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	return Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	}
+}
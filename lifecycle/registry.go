@@ -0,0 +1,156 @@
+// Package lifecycle はアプリケーションを構成する部品の起動と停止を管理する
+//
+// 部品はHookとして登録した順に起動し、SIGTERM/SIGINTを受けるか部品が失敗すると逆順に停止する
+// DB接続を先に、HTTPサーバーを最後に登録すれば、リクエストを捌き切ってからバックグラウンドの処理とDB接続を閉じられる
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/backend-guchitter-app/logging"
+	"github.com/bloom42/rz-go"
+)
+
+// Hook は部品の起動・停止の処理
+type Hook struct {
+	Name string
+	// OnStart は部品を起動する。ブロックせずに返すこと。nilの場合は何もしない
+	OnStart func(ctx context.Context) error
+	// OnStop は部品を停止する。ctxの期限までに終わらせること。nilの場合は何もしない
+	OnStop func(ctx context.Context) error
+	// Check は部品がリクエストを処理できる状態かを確認する。/readyzで使う。nilの場合は確認しない
+	Check func(ctx context.Context) error
+}
+
+// Registry は登録された部品の起動と停止を管理する
+type Registry struct {
+	mu       sync.Mutex
+	hooks    []Hook
+	failed   chan error
+	stopping chan struct{}
+	once     sync.Once
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		failed:   make(chan error, 1),
+		stopping: make(chan struct{}),
+	}
+}
+
+// Append は部品を登録する。Runの前に呼ぶこと
+func (r *Registry) Append(hook Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
+
+// Fail は起動後の部品が続行できなくなったことを通知し、全体を停止させる
+func (r *Registry) Fail(err error) {
+	select {
+	case r.failed <- err:
+	default:
+		// 既に他の部品が失敗を通知している
+	}
+}
+
+// Checks はCheckを持つ部品の名前と確認の処理を返す
+func (r *Registry) Checks() map[string]func(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	checks := map[string]func(ctx context.Context) error{}
+	for _, hook := range r.hooks {
+		if hook.Check != nil {
+			checks[hook.Name] = hook.Check
+		}
+	}
+	return checks
+}
+
+// Stopping は停止を始めると閉じられるチャネルを返す
+func (r *Registry) Stopping() <-chan struct{} {
+	return r.stopping
+}
+
+// Run は部品を登録順に起動し、シグナルかctxのキャンセル、部品の失敗を待ってから逆順に停止する
+// 停止はshutdownTimeoutまでに終わらせ、超えた部品はctxのキャンセルで打ち切る
+// 2回目のシグナルでは停止を待たずにプロセスを終了する
+func (r *Registry) Run(ctx context.Context, shutdownTimeout time.Duration) error {
+	r.mu.Lock()
+	hooks := append([]Hook{}, r.hooks...)
+	r.mu.Unlock()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+
+	started := 0
+	var runErr error
+	for _, hook := range hooks {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				runErr = fmt.Errorf("start %s: %w", hook.Name, err)
+				break
+			}
+		}
+		logging.Log.Info("started", rz.String("component", hook.Name))
+		started++
+	}
+
+	if runErr == nil {
+		select {
+		case sig := <-signals:
+			logging.Log.Info("shutdown requested", rz.String("signal", sig.String()))
+		case <-ctx.Done():
+			logging.Log.Info("shutdown requested", rz.Err(ctx.Err()))
+		case err := <-r.failed:
+			runErr = err
+			logging.Log.Error("component failed, shutting down", rz.Err(err))
+		}
+	}
+	// 以降のシグナルはデフォルトの動作(即時終了)に戻す
+	signal.Stop(signals)
+	r.once.Do(func() { close(r.stopping) })
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var stopErrs []error
+	for i := started - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		if err := hook.OnStop(stopCtx); err != nil {
+			logging.Log.Error("stop failed", rz.String("component", hook.Name), rz.Err(err))
+			stopErrs = append(stopErrs, fmt.Errorf("stop %s: %w", hook.Name, err))
+			continue
+		}
+		logging.Log.Info("stopped", rz.String("component", hook.Name))
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+	if len(stopErrs) > 0 {
+		return joinErrors(stopErrs)
+	}
+	return nil
+}
+
+// joinErrors は複数のエラーを1つにまとめる(errors.JoinはGo 1.20以降のため)
+func joinErrors(errs []error) error {
+	msg := errs[0].Error()
+	for _, err := range errs[1:] {
+		msg += "; " + err.Error()
+	}
+	return errors.New(msg)
+}
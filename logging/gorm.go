@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bloom42/rz-go"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// gormLogger はGORMのログをコンテキストのロガーに出力する
+// リポジトリがWithContextでリクエストのコンテキストを渡すので、SQLのログにもリクエストIDが付く
+type gormLogger struct {
+	level              gormlogger.LogLevel
+	slowQueryThreshold time.Duration
+}
+
+// NewGormLogger はoptsのSQLLevel, SlowQueryThresholdに従うGORMのロガーを返す
+func NewGormLogger(opts Options) gormlogger.Interface {
+	return &gormLogger{
+		level:              opts.SQLLevel,
+		slowQueryThreshold: opts.SlowQueryThreshold,
+	}
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace はSQLの実行ごとに呼ばれる
+// エラーはError、閾値を超えたSQLはWarn、それ以外はSQLLevelがInfoの場合にDebugで出力する
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	logger := FromContext(ctx)
+	fields := func() []rz.Field {
+		sql, rows := fc()
+		return []rz.Field{
+			rz.String("sql", sql),
+			rz.Int64("rows", rows),
+			rz.Duration("elapsed", elapsed),
+		}
+	}
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		logger.Error("sql failed", append(fields(), rz.Err(err))...)
+	case l.slowQueryThreshold > 0 && elapsed > l.slowQueryThreshold && l.level >= gormlogger.Warn:
+		logger.Warn("slow sql", append(fields(), rz.Duration("threshold", l.slowQueryThreshold))...)
+	case l.level >= gormlogger.Info:
+		logger.Debug("sql", fields()...)
+	}
+}
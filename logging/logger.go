@@ -1,24 +1,46 @@
 package logging
 
 import (
+	"context"
 	"os"
 
 	"github.com/bloom42/rz-go"
 )
 
 var (
-	Log rz.Logger
+	// Log はリクエストに紐づかない処理で使うロガー
+	// リクエストの処理中はFromContextでリクエストIDなどが付いたロガーを使う
+	Log = New(DefaultOptions())
 )
 
-// SetupLoggerはLoggerのフィールドをセットアップします。
-func SetupLogger(requestId string) {
+type ctxKey struct{}
+
+// New はoptsの設定で、ホスト名と環境のフィールドを付けたロガーを返す
+func New(opts Options) rz.Logger {
 	hostName, _ := os.Hostname()
 	env := os.Getenv("GO_ENV")
-	Log = rz.New(
-		rz.Fields(
-			rz.String("X-Request-ID", requestId),
-			rz.String("hostname", hostName),
-			rz.String("environment", env),
-		),
-	)
+	options := append(loggerOptions(opts), rz.Fields(
+		rz.String("hostname", hostName),
+		rz.String("environment", env),
+	))
+	return rz.New(options...)
+}
+
+// WithContext はloggerを保持したコンテキストを返す
+func WithContext(ctx context.Context, logger rz.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &logger)
+}
+
+// FromContext はコンテキストのロガーを返す。保持していない場合はLog
+func FromContext(ctx context.Context) *rz.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*rz.Logger); ok {
+		return logger
+	}
+	return &Log
+}
+
+// With はコンテキストのロガーにフィールドを追加したコンテキストを返す
+// 認証などで後から分かった値をリクエストのログに含めるときに使う
+func With(ctx context.Context, fields ...rz.Field) context.Context {
+	return WithContext(ctx, FromContext(ctx).With(rz.Fields(fields...)))
 }
@@ -0,0 +1,168 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bloom42/rz-go"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// ログの出力形式
+const (
+	FormatJSON = "json"
+	// 開発時に人が読むための形式
+	FormatConsole = "console"
+)
+
+// 伏せた値の代わりに出力する文字列
+const redacted = "[REDACTED]"
+
+// 値を伏せるフィールド名のデフォルト。部分一致で、大文字小文字を区別しない
+var DefaultRedactKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "apikey", "api_key"}
+
+// ロガーの設定
+type Options struct {
+	Level  rz.LogLevel
+	Format string
+	// Debug, Infoのログを1秒あたりSampleBurst件まで出力し、超えた分はSampleEvery件に1件だけ出力する
+	// SampleBurstが0の場合は間引かない。Warn以上は常に出力する
+	SampleBurst int
+	SampleEvery int
+	// 値を伏せるフィールド名
+	RedactKeys []string
+
+	// GORMのログレベル。InfoでSQLを全てDebugのログに出力する
+	SQLLevel gormlogger.LogLevel
+	// この時間を超えたSQLをWarnのログに出力する。0の場合は出力しない
+	SlowQueryThreshold time.Duration
+}
+
+// DefaultOptions は本番向けのデフォルトの設定を返す
+func DefaultOptions() Options {
+	return Options{
+		Level:              rz.InfoLevel,
+		Format:             FormatJSON,
+		RedactKeys:         DefaultRedactKeys,
+		SQLLevel:           gormlogger.Warn,
+		SlowQueryThreshold: 200 * time.Millisecond,
+	}
+}
+
+// Setup はoptsでLogを作り直す
+// リクエストのロガーはLogから作るので、サーバーの起動前に呼ぶこと
+func Setup(opts Options) {
+	Log = New(opts)
+}
+
+func loggerOptions(opts Options) []rz.LoggerOption {
+	options := []rz.LoggerOption{
+		rz.Level(opts.Level),
+		rz.Formatter(formatter(opts.Format, opts.RedactKeys)),
+	}
+	if opts.SampleBurst > 0 {
+		options = append(options, rz.Sampler(belowWarnSampler{next: &rz.SamplerBurst{
+			Burst:       uint32(opts.SampleBurst),
+			Period:      time.Second,
+			NextSampler: rz.SamplerRandom(opts.SampleEvery),
+		}}))
+	}
+	return options
+}
+
+// belowWarnSampler はDebug, Infoのログだけを間引く
+type belowWarnSampler struct {
+	next rz.LogSampler
+}
+
+func (s belowWarnSampler) Sample(lvl rz.LogLevel) bool {
+	if lvl >= rz.WarnLevel {
+		return true
+	}
+	return s.next.Sample(lvl)
+}
+
+// formatter は機密情報を伏せてからformatの形式で出力する
+func formatter(format string, redactKeys []string) rz.LogFormatter {
+	keys := make([]string, len(redactKeys))
+	for i, key := range redactKeys {
+		keys[i] = strings.ToLower(key)
+	}
+
+	return func(ev *rz.Event) ([]byte, error) {
+		fields, err := ev.Fields()
+		if err != nil {
+			return nil, err
+		}
+		redact(fields, keys)
+
+		if format == FormatConsole {
+			return formatConsole(fields), nil
+		}
+		b, err := json.Marshal(fields)
+		return append(b, '\n'), err
+	}
+}
+
+// redact はkeysを含む名前のフィールドの値を伏せる。入れ子のオブジェクトも対象にする
+func redact(fields map[string]interface{}, keys []string) {
+	for name, value := range fields {
+		if isSensitive(name, keys) {
+			fields[name] = redacted
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			redact(v, keys)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					redact(m, keys)
+				}
+			}
+		}
+	}
+}
+
+func isSensitive(name string, keys []string) bool {
+	name = strings.ToLower(name)
+	for _, key := range keys {
+		if strings.Contains(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatConsole は"時刻 |レベル| メッセージ key=value ..."の1行にする
+func formatConsole(fields map[string]interface{}) []byte {
+	var b bytes.Buffer
+	level, _ := fields[rz.DefaultLevelFieldName].(string)
+	if len(level) > 4 {
+		level = level[:4]
+	}
+	fmt.Fprintf(&b, "%-20v |%-4s| %v", fields[rz.DefaultTimestampFieldName], strings.ToUpper(level), fields[rz.DefaultMessageFieldName])
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		switch name {
+		case rz.DefaultTimestampFieldName, rz.DefaultLevelFieldName, rz.DefaultMessageFieldName:
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := json.Marshal(fields[name])
+		if err != nil {
+			value = []byte(fmt.Sprint(fields[name]))
+		}
+		fmt.Fprintf(&b, " %s=%s", name, value)
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
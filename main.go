@@ -1,95 +1,208 @@
 package main
 
 import (
-	"os"
+	"context"
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+	// tzクエリパラメータのため、zoneinfoのない環境でもタイムゾーンを解決できるようにする
+	_ "time/tzdata"
 
 	"github.com/backend-guchitter-app/config"
-	_ "github.com/backend-guchitter-app/docs"
+	"github.com/backend-guchitter-app/db/migrations"
+	"github.com/backend-guchitter-app/domain/repository"
+	"github.com/backend-guchitter-app/domain/service"
+	"github.com/backend-guchitter-app/idempotency"
+	"github.com/backend-guchitter-app/infrastructure/blobstore"
+	"github.com/backend-guchitter-app/infrastructure/fingerprint"
+	"github.com/backend-guchitter-app/infrastructure/imaging"
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
 	"github.com/backend-guchitter-app/infrastructure/persistence"
+	"github.com/backend-guchitter-app/infrastructure/sentiment"
 	"github.com/backend-guchitter-app/interface/handler"
-	logging "github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/interface/router"
+	"github.com/backend-guchitter-app/lifecycle"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/metrics"
+	"github.com/backend-guchitter-app/tracing"
 	"github.com/backend-guchitter-app/usecase"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-contrib/requestid"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-const (
-	// header name of unique request id
-	XRequestId = "X-Request-ID"
+// ビルド時に -ldflags "-X main.version=..." で埋め込む。make buildを参照
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = ""
 )
 
 // @title gin-swagger guchitter
 // @version 0.0.1
 // @lisence.name rudy
 // @description はじめてのswagger
+// @BasePath /v1
 func main() {
+	// 設定の読み込み。不正な値があれば全て表示して終了する
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// ロガーの設定。リクエストのロガーはlogging.Logから作るため最初に行う
+	logging.Setup(cfg.Log.Options())
+
+	// 起動・停止を管理する部品の登録先。DB接続を先に登録し、HTTPサーバーより後に閉じる
+	registry := lifecycle.NewRegistry()
+
+	// トレースの設定。最初に登録し、他の部品を止めた後に未送信のスパンを送る
+	build := buildInfo()
+	traceOpts := cfg.Trace.Options()
+	traceOpts.ServiceVersion = build.Version
+	traceOpts.Environment = cfg.Env
+	shutdownTracing, err := tracing.Setup(context.Background(), traceOpts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	registry.Append(lifecycle.Hook{Name: "tracing", OnStop: shutdownTracing})
+
 	// 依存性の注入
-	complaintPersistence := persistence.NewComplaintPersistence(config.Connect())
-	complaintUseCase := usecase.NewComplaintUseCase(complaintPersistence)
-	complaintHandler := handler.NewComplaintHandler(complaintUseCase)
-
-	avatarPersistence := persistence.NewAvatarPersistence(config.Connect())
-	avatarUseCase := usecase.NewAvatarUseCase(avatarPersistence)
-	avatarHandler := handler.NewAvatarHandler(avatarUseCase)
-
-	router := gin.Default()
-
-	// リクエストID設定
-	router.Use(requestid.New())
-
-	// ロギング設定
-	router.Use(loggerSetup())
-
-	// CORS設定
-	corsConf := cors.DefaultConfig()
-	env := os.Getenv("GUCHITTER_ENV")
-
-	if env == "production" {
-		env = "production"
-	} else {
-		env = "development"
-	}
-
-	godotenv.Load(".env." + env)
-	FRONT_ORIGIN := os.Getenv("guchitter_FRONT_ORIGIN")
-	corsConf.AllowOrigins = []string{FRONT_ORIGIN}
-	router.Use(cors.New(corsConf))
-
-	// エンドポイントの設定
-	// Complaints
-	router.GET("/complaints", complaintHandler.Index)
-	router.GET("/complaints/:id", complaintHandler.Search)
-	router.POST("/complaints", complaintHandler.Create)
-	// TODO refactor endopoint
-	router.GET("/complaints/between-time", complaintHandler.FindBetweenTimestamp)
-	router.DELETE("/complaints/:id", complaintHandler.DeleteByComplaintId)
-
-	// Avatars
-	router.GET("/avatars", avatarHandler.Index)
-	router.GET("/avatars/:id", avatarHandler.Search)
-	router.POST("/avatars", avatarHandler.Create)
-	// TODO refactor endopoint
-	router.GET("/avatars/between-time", avatarHandler.FindBetweenTimestamp)
-	router.DELETE("/avatars/:id", avatarHandler.DeleteByAvatarId)
-
-	// http://localhost:8080/swagger/index.html にswagger UI を表示する
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	port := os.Getenv("PORT")
-	router.Run(":" + port)
+	complaintRepository, avatarRepository, idempotencyStore, err := newRepositories(cfg, registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sentimentAnalyzer := sentiment.NewLexiconAnalyzer()
+	complaintUseCase := usecase.NewComplaintUseCase(complaintRepository, sentimentAnalyzer, fingerprint.NewSimHashFingerprinter(), cfg.Duplicates.Policy())
+	blobStore, err := newBlobStore(cfg.Images)
+	if err != nil {
+		log.Fatal(err)
+	}
+	avatarUseCase := usecase.NewAvatarUseCase(avatarRepository, blobStore, imaging.NewThumbnailer(), cfg.Colors.Policy())
+
+	timeouts := handler.TimeoutPolicy{
+		Default: cfg.Timeouts.Request,
+		Routes:  cfg.Timeouts.Routes,
+	}
+	r := router.NewRouter(router.Deps{
+		ComplaintUseCase: complaintUseCase,
+		AvatarUseCase:    avatarUseCase,
+		AllowOrigins:     cfg.AllowOrigins,
+		AvatarImages:     handler.AvatarImagePolicy{MaxBytes: cfg.Images.MaxBytes},
+		Timeouts:         timeouts,
+		RateLimits: handler.RateLimitPolicy{
+			Default: cfg.RateLimits.Default,
+			Routes:  cfg.RateLimits.Routes,
+			Items:   cfg.RateLimits.Items,
+		},
+		RateLimitStore: inmemory.NewRateLimitStore(),
+		Idempotency: handler.IdempotencyPolicy{
+			TTL:         cfg.Idempotency.TTL,
+			LockTimeout: cfg.Idempotency.LockTimeout,
+			Timeouts:    timeouts,
+		},
+		IdempotencyStore: idempotencyStore,
+		TrustedProxies:   cfg.TrustedProxies,
+		Features:         cfg.Features,
+		Readiness:        registry,
+		Build:            build,
+	})
+
+	// 保存期間を過ぎたIdempotency-Keyのレスポンスを削除する
+	registry.Append(lifecycle.Worker("idempotency sweeper", func(ctx context.Context) {
+		idempotency.Sweep(ctx, idempotencyStore, idempotencySweepInterval)
+	}))
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	registry.Append(lifecycle.HTTPServer(srv, registry))
+	// 停止時はHTTPサーバーより先に待ち、その間に/readyzの503でロードバランサーから外れる
+	if cfg.ShutdownDrainDelay > 0 {
+		registry.Append(lifecycle.DrainDelay(cfg.ShutdownDrainDelay))
+	}
+
+	// SIGTERM/SIGINTを受けると、処理中のリクエストを待ってから逆順に停止する
+	if err := registry.Run(context.Background(), cfg.ShutdownTimeout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newRepositories は guchitter_DRIVER に応じたリポジトリとIdempotency-Keyの保存先の実装を返す
+// DBに接続した場合は、/readyzでの確認と停止時にコネクションプールを閉じるようregistryに登録する
+// memoryの場合はDBに接続しない
+func newRepositories(cfg *config.Config, registry *lifecycle.Registry) (repository.ComplaintRepository, repository.AvatarRepository, idempotency.Store, error) {
+	if cfg.Database.Driver == config.DriverMemory {
+		return inmemory.NewComplaintRepository(), inmemory.NewAvatarRepository(), inmemory.NewIdempotencyStore(), nil
+	}
+
+	db, err := config.Connect(cfg.Database, logging.NewGormLogger(cfg.Log.Options()))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// クエリの処理時間とコネクションプールの状態を/metricsで公開する
+	dbName := cfg.Database.Name
+	if cfg.Database.Driver == config.DriverSQLite {
+		dbName = cfg.Database.SQLitePath
+	}
+	if err := db.Use(metrics.GormPlugin{DBName: dbName}); err != nil {
+		return nil, nil, nil, err
+	}
+	// SQLごとのスパン
+	if err := db.Use(tracing.GormPlugin{System: cfg.Database.Driver}); err != nil {
+		return nil, nil, nil, err
+	}
+	registry.Append(lifecycle.Database(db, persistence.Ping(db)))
+
+	// db/migrationsはMySQL用。SQLiteはモデルからテーブルを作るため確認しない
+	if cfg.Database.Driver == config.DriverMySQL {
+		latest, err := migrations.Latest()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		registry.Append(lifecycle.Hook{Name: "migrations", Check: persistence.MigrationsApplied(db, latest)})
+	}
+	return persistence.NewComplaintPersistence(db), persistence.NewAvatarPersistence(db), persistence.NewIdempotencyStore(db), nil
 }
 
-// Logger設定を行うミドルウェア
-func loggerSetup() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// logging設定
-		requestId := c.Request.Header.Get(XRequestId)
-		logging.SetupLogger(requestId)
-		c.Next()
+// newBlobStore は guchitter_IMAGE_STORE に応じたアバターの画像の保存先を返す
+func newBlobStore(cfg config.ImageConfig) (service.BlobStore, error) {
+	switch cfg.Store {
+	case config.ImageStoreMemory:
+		return inmemory.NewBlobStore(), nil
+	case config.ImageStoreS3:
+		return blobstore.NewS3Store(blobstore.S3Options{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PathStyle:       cfg.S3.PathStyle,
+		})
+	default:
+		return blobstore.NewLocalStore(cfg.Dir)
+	}
+}
+
+// 期限切れのIdempotency-Keyを削除する間隔
+const idempotencySweepInterval = 10 * time.Minute
+
+// buildInfo は/versionで返すビルドの情報を返す
+// ldflagsでコミットを埋め込んでいない場合は、go buildが記録したVCSの情報を使う
+func buildInfo() handler.BuildInfo {
+	info := handler.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = s.Value
+			}
+		}
 	}
+	return info
 }
@@ -0,0 +1,91 @@
+// Package metrics はPrometheusのメトリクスを1か所で定義・登録する
+//
+// メトリクスは全てRegistryに登録し、/metricsでHandler()から公開する
+// メトリクスを追加する場合はこのファイルにpromauto.With(Registry)で定義する
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// メトリクス名の接頭辞
+const namespace = "guchitter"
+
+// Registry はアプリケーションの全てのメトリクスの登録先
+var Registry = newRegistry()
+
+// HTTP
+var (
+	// HTTPRequests はginのルートのパターン、メソッド、ステータスごとのリクエスト数
+	HTTPRequests = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route template, method and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration はginのルートのパターン、メソッド、ステータスごとの処理時間
+	HTTPRequestDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RateLimited はルートのパターン、メソッドごとの上限を超えて429を返したリクエスト数
+	RateLimited = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Number of HTTP requests rejected by rate limiting, by route template and method.",
+	}, []string{"method", "route"})
+)
+
+// DB
+var (
+	// DBQueryDuration はGORMの操作とテーブルごとのクエリの処理時間。statusはokまたはerror
+	DBQueryDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database query latency by GORM operation, table and status.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "status"})
+)
+
+// ドメインのイベント
+var (
+	// ComplaintsCreated はアバターごとの登録されたぐちの数
+	ComplaintsCreated = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "complaints_created_total",
+		Help:      "Number of complaints created by avatar.",
+	}, []string{"avatar_id"})
+
+	// ModerationRejections は理由ごとの登録を拒否したぐちの数
+	ModerationRejections = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "moderation_rejections_total",
+		Help:      "Number of complaints rejected by moderation, by reason.",
+	}, []string{"reason"})
+)
+
+func newRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// Handler はRegistryのメトリクスをPrometheusの形式で返すハンドラ
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,93 @@
+// Package ratelimit はトークンバケットによるリクエスト数の制限の型と、バケットを保存するStoreを定義する
+//
+// プロセス内の実装はinfrastructure/inmemoryにある
+// 複数のプロセスで制限を共有する場合は、Redisなどを使ったStoreを実装する
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit は期間あたりのリクエスト数の上限
+// 容量Requestsのバケットに、Periodの間にRequests個のトークンが補充される
+// ゼロ値は制限なし
+type Limit struct {
+	// バケットの容量。連続して許可するリクエスト数
+	Requests int
+	// Requests個のトークンが補充されるまでの時間
+	Period time.Duration
+}
+
+// ParseLimit は"10/1m"のような"リクエスト数/期間"の形式を解釈する
+// "none"または"0"は制限なし
+func ParseLimit(v string) (Limit, error) {
+	v = strings.TrimSpace(v)
+	if v == "none" || v == "0" {
+		return Limit{}, nil
+	}
+	requests, period, ok := strings.Cut(v, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must be like 10/1m", v)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q must have a positive number of requests", v)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q must have a positive period like 1m", v)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// UnmarshalText はYAMLなどで"10/1m"の形式を読み込む
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "none"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// Enabled は制限があればtrueを返す
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Rate は1秒あたりに補充されるトークンの数
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result はトークンを取り出した結果
+type Result struct {
+	// リクエストを許可するか
+	Allowed bool
+	// バケットの容量
+	Limit int
+	// 取り出した後に残っているトークンの数
+	Remaining int
+	// 次のリクエストが許可されるまでの時間。Allowedの場合は0
+	RetryAfter time.Duration
+	// バケットが満杯に戻るまでの時間
+	Reset time.Duration
+}
+
+// Store はキーごとのバケットを保存する
+type Store interface {
+	// Take はkeyのバケットからトークンをn個取り出す。リクエスト数の制限ではnは1
+	// バケットがなければ満杯のバケットを作る。トークンがn個なければ取り出さず、AllowedがfalseのResultを返す
+	Take(ctx context.Context, key string, limit Limit, n int) (Result, error)
+}
@@ -0,0 +1,161 @@
+// Package tracing はOpenTelemetryのトレースを設定し、スパンを作る関数を提供する
+//
+// HTTPのリクエスト(ルーターのミドルウェア)、ユースケースのメソッド、GORMのSQL(GormPlugin)ごとにスパンを作る
+// 受け取ったW3Cのtraceparentヘッダを引き継ぐ
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// スパンの出力先
+const (
+	// 出力しない。traceparentの引き継ぎとログへのトレースIDの付与は行う
+	ExporterNone = "none"
+	// 標準出力にJSONで出力する。ローカルでの確認用
+	ExporterStdout = "stdout"
+	// OTLP(HTTP)でコレクターに送る
+	ExporterOTLP = "otlp"
+)
+
+// スパンを作るTracerの名前
+const instrumentationName = "github.com/backend-guchitter-app"
+
+// RequestIdKey はスパンに付けるリクエストID(X-Request-ID)の属性
+var RequestIdKey = attribute.Key("http.request_id")
+
+// SQLで変更・取得した行数の属性
+var rowsAffectedKey = attribute.Key("db.rows_affected")
+
+// トレースの設定
+type Options struct {
+	Exporter string
+	// OTLPの送信先(host:port)。空の場合はOTEL_EXPORTER_OTLP_ENDPOINTまたはlocalhost:4318
+	Endpoint string
+	// OTLPをTLSなしで送る
+	Insecure bool
+	// 記録するトレースの割合(0〜1)。traceparentで記録済みとされたトレースは常に記録する
+	SampleRatio float64
+	ServiceName string
+	// サービスのバージョン。/versionと同じ値
+	ServiceVersion string
+	// development, production
+	Environment string
+}
+
+// DefaultOptions はデフォルトの設定を返す
+func DefaultOptions() Options {
+	return Options{
+		Exporter:    ExporterNone,
+		SampleRatio: 1,
+		ServiceName: "backend-guchitter-app",
+	}
+}
+
+// Setup はoptsに従ってグローバルのTracerProviderとtraceparentのPropagatorを設定する
+// 返す関数は未送信のスパンを送ってから終了する。停止時に呼ぶこと
+func Setup(ctx context.Context, opts Options) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(ctx context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		clientOpts := []otlptracehttp.Option{}
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(opts.ServiceName),
+		semconv.ServiceVersionKey.String(opts.ServiceVersion),
+		semconv.DeploymentEnvironmentKey.String(opts.Environment),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start はctxのスパンを親とするスパンを開始する
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError はerrがあればスパンに記録し、スパンを失敗にする。errがnilの場合は何もしない
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// IDs はctxのスパンのトレースIDとスパンIDを返す。スパンがない場合はokがfalse
+// ログとトレースを突き合わせるため、ロガーのフィールドに使う
+func IDs(ctx context.Context) (traceId, spanId string, ok bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", "", false
+	}
+	return sc.TraceID().String(), sc.SpanID().String(), true
+}
+
+// Extract はリクエストヘッダのtraceparentをctxに取り込む
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// StartHTTP はHTTPのリクエスト1件のスパンを開始する。スパン名は"GET /v1/complaints/:id"のようにルートのパターンを使う
+func StartHTTP(ctx context.Context, method, route, path, requestId string) (context.Context, trace.Span) {
+	return Start(ctx, method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(method),
+			semconv.HTTPRouteKey.String(route),
+			semconv.HTTPTargetKey.String(path),
+			RequestIdKey.String(requestId),
+		),
+	)
+}
+
+// SetHTTPStatus はレスポンスのステータスをスパンに記録する。5xxの場合はスパンを失敗にする
+func SetHTTPStatus(span trace.Span, status int) {
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+	if status >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/backend-guchitter-app/domain/color"
+	"github.com/backend-guchitter-app/domain/model"
+)
+
+// paletteShade はLight, Darkを求めるときに白・黒を混ぜる割合
+const paletteShade = 0.4
+
+// ColorPolicy はアバターの色の検証と配色の求め方
+type ColorPolicy struct {
+	// フロントエンドの背景色。BackgroundContrastはこの色と比べる
+	Background color.RGB
+	// 背景色とのコントラスト比がこれ未満の色は登録できない。0なら制限しない
+	MinContrast float64
+}
+
+// NormalizeColor はアバターの色を検証し、#rrggbbにして返す。空の色はそのまま
+// APIを通さずにアバターを登録する場合(db seedなど)も、これで同じく検証する
+func (p ColorPolicy) NormalizeColor(s string) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+	c, err := color.Parse(s)
+	if err != nil {
+		return s, &ValidationError{Field: "color", Message: "must be a hex, rgb() or CSS named color"}
+	}
+	if contrast := color.Contrast(c, p.Background); contrast < p.MinContrast {
+		return s, &ValidationError{Field: "color", Message: fmt.Sprintf("must have a contrast ratio of at least %g against the background %s, got %.2f", p.MinContrast, p.Background.Hex(), contrast)}
+	}
+	return c.Hex(), nil
+}
+
+// normalizeColor はアバターの色をNormalizeColorで#rrggbbにする
+func (p ColorPolicy) normalizeColor(avatar model.Avatar) (model.Avatar, error) {
+	c, err := p.NormalizeColor(avatar.Color)
+	if err != nil {
+		return avatar, err
+	}
+	avatar.Color = c
+	return avatar, nil
+}
+
+// withPalette はavatarのPaletteを設定して返す
+// 正規化する前に登録した色など、解釈できない色の場合はPaletteを設定しない
+func (p ColorPolicy) withPalette(avatar *model.Avatar) *model.Avatar {
+	if avatar == nil {
+		return nil
+	}
+	avatar.Palette = nil
+	base, err := color.Parse(avatar.Color)
+	if err != nil {
+		return avatar
+	}
+
+	text := color.Black
+	if color.Contrast(base, color.White) > color.Contrast(base, color.Black) {
+		text = color.White
+	}
+	textContrast := color.Contrast(base, text)
+	backgroundContrast := color.Contrast(base, p.Background)
+	avatar.Palette = &model.Palette{
+		Base:                 base.Hex(),
+		Light:                color.Mix(base, color.White, paletteShade).Hex(),
+		Dark:                 color.Mix(base, color.Black, paletteShade).Hex(),
+		TextColor:            text.Hex(),
+		TextContrast:         roundContrast(textContrast),
+		TextLevel:            contrastLevel(textContrast),
+		BackgroundContrast:   roundContrast(backgroundContrast),
+		BackgroundAccessible: backgroundContrast >= 3,
+	}
+	return avatar
+}
+
+func (p ColorPolicy) withPalettes(avatars []*model.Avatar) []*model.Avatar {
+	for _, a := range avatars {
+		p.withPalette(a)
+	}
+	return avatars
+}
+
+// contrastLevel は文字のコントラスト比が満たすWCAGの達成基準を返す
+func contrastLevel(contrast float64) string {
+	switch {
+	case contrast >= 7:
+		return "AAA"
+	case contrast >= 4.5:
+		return "AA"
+	case contrast >= 3:
+		return "AA-large"
+	}
+	return "fail"
+}
+
+// roundContrast はコントラスト比を小数第2位までにする
+// 基準の境界をまたがないよう切り捨てる(6.996を7.00と表示してAAAでないことのないように)。1e-9は浮動小数点の誤差の分
+func roundContrast(contrast float64) float64 {
+	return math.Floor(contrast*100+1e-9) / 100
+}
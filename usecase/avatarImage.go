@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/service"
+	"github.com/backend-guchitter-app/logging"
+	"github.com/backend-guchitter-app/tracing"
+	"github.com/bloom42/rz-go"
+)
+
+// アバターの画像の大きさ。GET /avatars/:id/imageのsizeで指定する
+const (
+	AvatarImageSmall  = "small"
+	AvatarImageMedium = "medium"
+	AvatarImageLarge  = "large"
+)
+
+// 各大きさの正方形の辺の長さ(px)
+var avatarImageSizes = []struct {
+	name   string
+	pixels int
+}{
+	{AvatarImageSmall, 64},
+	{AvatarImageMedium, 256},
+	{AvatarImageLarge, 512},
+}
+
+// errImagesDisabled はBlobStoreなしで作ったユースケースで画像を扱おうとしたときのエラー
+var errImagesDisabled = errors.New("avatar images are not configured")
+
+// AvatarImage はアバターの画像。Bodyは呼び出し側で閉じる
+type AvatarImage struct {
+	*service.Blob
+	// 元の画像のハッシュ。アップロードするたびに変わり、imageUrlの?v=に付ける
+	Version string
+}
+
+// UploadImage はbodyの画像を各大きさに縮小して保存し、アバターのimageUrlをurlPath?v=<画像のハッシュ>にする
+// urlPathは画像を返すエンドポイントのパス。前の画像は新しい画像を保存した後に削除する
+func (cu avatarUseCase) UploadImage(ctx context.Context, id int, body []byte, urlPath string) (*model.Avatar, error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.UploadImage")
+	defer span.End()
+	if cu.blobs == nil {
+		return nil, errImagesDisabled
+	}
+
+	avatar, err := cu.avatarRepository.FindByAvatarId(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if avatar == nil {
+		return nil, ErrNotFound
+	}
+
+	pixels := make([]int, len(avatarImageSizes))
+	for i, s := range avatarImageSizes {
+		pixels[i] = s.pixels
+	}
+	images, err := cu.thumbnailer.Thumbnails(body, pixels)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	version := hex.EncodeToString(sum[:8])
+	file := version + images[0].Ext
+	for i, s := range avatarImageSizes {
+		if err := cu.blobs.Put(ctx, avatarImageKey(id, s.name, file), images[i].Body, images[i].ContentType); err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
+
+	updated, err := cu.avatarRepository.UpdateImage(ctx, id, file, urlPath+"?v="+version)
+	if err != nil || updated == nil {
+		// 保存の途中で削除されたアバターの画像を残さない
+		if file != avatar.ImageFile {
+			cu.deleteImages(ctx, id, file)
+		}
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	if avatar.ImageFile != "" && avatar.ImageFile != file {
+		cu.deleteImages(ctx, id, avatar.ImageFile)
+	}
+	return cu.colorPolicy.withPalette(updated), nil
+}
+
+// Image はアバターのsizeの画像を返す。アバターまたは画像がなければErrNotFound
+func (cu avatarUseCase) Image(ctx context.Context, id int, size string) (*AvatarImage, error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.Image")
+	defer span.End()
+	if cu.blobs == nil {
+		return nil, errImagesDisabled
+	}
+	if !isAvatarImageSize(size) {
+		return nil, &ValidationError{Field: "size", Message: fmt.Sprintf("must be %s, %s or %s", AvatarImageSmall, AvatarImageMedium, AvatarImageLarge)}
+	}
+
+	avatar, err := cu.avatarRepository.FindByAvatarId(ctx, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if avatar == nil || avatar.ImageFile == "" {
+		return nil, ErrNotFound
+	}
+
+	blob, err := cu.blobs.Get(ctx, avatarImageKey(id, size, avatar.ImageFile))
+	if errors.Is(err, service.ErrBlobNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return &AvatarImage{Blob: blob, Version: strings.TrimSuffix(avatar.ImageFile, path.Ext(avatar.ImageFile))}, nil
+}
+
+// deleteImages はアバターのfileの画像を全ての大きさで削除する
+// 画像は参照されなくなった後に消すので、失敗してもログに出力して続ける
+func (cu avatarUseCase) deleteImages(ctx context.Context, id int, file string) {
+	for _, s := range avatarImageSizes {
+		key := avatarImageKey(id, s.name, file)
+		if err := cu.blobs.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Warn("Failed at BlobStore.Delete()", rz.Err(err), rz.String("key", key))
+		}
+	}
+}
+
+// avatarImageKey はBlobStoreのキー"avatars/<id>/<size>/<file>"を返す
+func avatarImageKey(id int, size, file string) string {
+	return fmt.Sprintf("avatars/%d/%s/%s", id, size, file)
+}
+
+func isAvatarImageSize(size string) bool {
+	for _, s := range avatarImageSizes {
+		if s.name == size {
+			return true
+		}
+	}
+	return false
+}
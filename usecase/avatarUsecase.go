@@ -1,49 +1,202 @@
 package usecase
 
 import (
+	"context"
+	"time"
+
 	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
 	"github.com/backend-guchitter-app/domain/repository"
+	"github.com/backend-guchitter-app/domain/service"
+	"github.com/backend-guchitter-app/tracing"
 )
 
 type AvatarUseCase interface {
-	FindAll() ([]*model.Avatar, error)
-	FindByAvatarId(id int) (*model.Avatar, error)
-	Create(avatar model.Avatar) (*model.Avatar, error)
-	FindBetweenTimestamp(from string, to string) ([]*model.Avatar, error)
-	DeleteByAvatarId(id int) error
+	FindAll(ctx context.Context) ([]*model.Avatar, error)
+	FindByAvatarId(ctx context.Context, id int) (*model.Avatar, error)
+	Create(ctx context.Context, avatar model.Avatar) (*model.Avatar, error)
+	Find(ctx context.Context, spec query.Spec) ([]*model.Avatar, error)
+	FindBetweenTimestamp(ctx context.Context, from time.Time, to time.Time) ([]*model.Avatar, error)
+	DeleteByAvatarId(ctx context.Context, id int) error
+	CreateBatch(ctx context.Context, avatars []model.Avatar, mode string) ([]BatchItem[model.Avatar], error)
+	CheckBatch(ctx context.Context, avatars []model.Avatar) ([]error, error)
+	Export(ctx context.Context, from time.Time, to time.Time, fn func(*model.Avatar) error) error
+	UploadImage(ctx context.Context, id int, body []byte, urlPath string) (*model.Avatar, error)
+	Image(ctx context.Context, id int, size string) (*AvatarImage, error)
 }
 
 type avatarUseCase struct {
 	avatarRepository repository.AvatarRepository
+	blobs            service.BlobStore
+	thumbnailer      service.Thumbnailer
+	colorPolicy      ColorPolicy
 }
 
-func NewAvatarUseCase(cr repository.AvatarRepository) AvatarUseCase {
+// NewAvatarUseCase はアバターのユースケースを返す
+// blobsにはアップロードした画像を保存する。画像を扱わないコマンドなどではnilを渡す
+// 返すアバターにはcpで求めた配色を付ける
+func NewAvatarUseCase(cr repository.AvatarRepository, blobs service.BlobStore, thumbnailer service.Thumbnailer, cp ColorPolicy) AvatarUseCase {
 	return &avatarUseCase{
 		avatarRepository: cr,
+		blobs:            blobs,
+		thumbnailer:      thumbnailer,
+		colorPolicy:      cp,
+	}
+}
+
+func (cu avatarUseCase) FindAll(ctx context.Context) ([]*model.Avatar, error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.FindAll")
+	defer span.End()
+	avatarList, err := cu.avatarRepository.FindAll(ctx)
+	tracing.RecordError(span, err)
+	return cu.colorPolicy.withPalettes(avatarList), err
+}
+
+func (cu avatarUseCase) FindByAvatarId(ctx context.Context, id int) (*model.Avatar, error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.FindByAvatarId")
+	defer span.End()
+	avatar, err := cu.avatarRepository.FindByAvatarId(ctx, id)
+	tracing.RecordError(span, err)
+	return cu.colorPolicy.withPalette(avatar), err
+}
+
+func (cu avatarUseCase) Create(ctx context.Context, avatar model.Avatar) (*model.Avatar, error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.Create")
+	defer span.End()
+	avatar, err := cu.checkAvatar(avatar)
+	if err != nil {
+		return nil, err
+	}
+	result, err := cu.avatarRepository.Create(ctx, avatar)
+	tracing.RecordError(span, err)
+	return cu.colorPolicy.withPalette(result), err
+}
+
+// CreateBatch はavatarsを1つのトランザクションで登録し、項目ごとの結果を返す
+// 各項目はCreateと同じく検証する。項目の失敗はBatchItemのErrに、モードや項目数の誤りとDBのエラーはerrに返す
+func (cu avatarUseCase) CreateBatch(ctx context.Context, avatars []model.Avatar, mode string) ([]BatchItem[model.Avatar], error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.CreateBatch")
+	defer span.End()
+	mode, err := validateBatch(mode, "items", len(avatars))
+	if err != nil {
+		return nil, err
+	}
+
+	items, accepted, acceptedIndexes := cu.checkAvatars(avatars)
+	if abortIfFailed(mode, items) || len(accepted) == 0 {
+		return items, nil
+	}
+
+	created, err := cu.avatarRepository.CreateBatch(ctx, accepted)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
 	}
+	for k, a := range created {
+		items[acceptedIndexes[k]].Value = cu.colorPolicy.withPalette(a)
+	}
+	return items, nil
+}
+
+// CheckBatch はCreateBatchと同じく検証し、登録せずに項目ごとのエラーを返す。取り込みのdry-runに使う
+func (cu avatarUseCase) CheckBatch(ctx context.Context, avatars []model.Avatar) ([]error, error) {
+	if _, err := validateBatch(BatchModeBestEffort, "items", len(avatars)); err != nil {
+		return nil, err
+	}
+	items, _, _ := cu.checkAvatars(avatars)
+	return batchErrors(items), nil
 }
 
-func (cu avatarUseCase) FindAll() ([]*model.Avatar, error) {
-	avatarList, err := cu.avatarRepository.FindAll()
-	return avatarList, err
+// checkAvatars はavatarsを検証し、項目ごとの結果と、登録できる項目とそのavatarsでの位置を返す
+func (cu avatarUseCase) checkAvatars(avatars []model.Avatar) ([]BatchItem[model.Avatar], []model.Avatar, []int) {
+	items := make([]BatchItem[model.Avatar], len(avatars))
+	accepted := make([]model.Avatar, 0, len(avatars))
+	acceptedIndexes := make([]int, 0, len(avatars))
+	for i, avatar := range avatars {
+		avatar, err := cu.checkAvatar(avatar)
+		if err != nil {
+			items[i].Err = err
+			continue
+		}
+		accepted = append(accepted, avatar)
+		acceptedIndexes = append(acceptedIndexes, i)
+	}
+	return items, accepted, acceptedIndexes
 }
 
-func (cu avatarUseCase) FindByAvatarId(id int) (*model.Avatar, error) {
-	avatar, err := cu.avatarRepository.FindByAvatarId(id)
-	return avatar, err
+// checkAvatar はavatarを検証し、登録する形にして返す
+func (cu avatarUseCase) checkAvatar(avatar model.Avatar) (model.Avatar, error) {
+	avatar = prepareAvatar(avatar)
+	if err := validateAvatar(avatar); err != nil {
+		return avatar, err
+	}
+	return cu.colorPolicy.normalizeColor(avatar)
 }
 
-func (cu avatarUseCase) Create(avatar model.Avatar) (*model.Avatar, error) {
-	result, err := cu.avatarRepository.Create(avatar)
-	return result, err
+// prepareAvatar はクライアントの指定を無視する項目を消す
+func prepareAvatar(avatar model.Avatar) model.Avatar {
+	// 登録日時・更新日時はクライアントの指定を無視してサーバー側で付与する
+	avatar.CreatedAt = time.Time{}
+	avatar.LastUpdate = time.Time{}
+	// 配色は色から求める
+	avatar.Palette = nil
+	return avatar
 }
 
-func (cu avatarUseCase) FindBetweenTimestamp(from string, to string) ([]*model.Avatar, error) {
-	avatarList, err := cu.avatarRepository.FindBetweenTimestamp(from, to)
-	return avatarList, err
+func (cu avatarUseCase) Find(ctx context.Context, spec query.Spec) ([]*model.Avatar, error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.Find")
+	defer span.End()
+	avatarList, err := cu.avatarRepository.Find(ctx, spec)
+	tracing.RecordError(span, err)
+	return cu.colorPolicy.withPalettes(avatarList), err
 }
 
-func (cu avatarUseCase) DeleteByAvatarId(id int) error {
-	err := cu.avatarRepository.DeleteByAvatarId(id)
+// FindBetweenTimestamp は更新日時がfrom以上to以下のAvatarを返す
+// ゼロ値のfrom, toは条件に含めない
+func (cu avatarUseCase) FindBetweenTimestamp(ctx context.Context, from time.Time, to time.Time) ([]*model.Avatar, error) {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.FindBetweenTimestamp")
+	defer span.End()
+	spec := query.Spec{}
+	if !from.IsZero() {
+		spec = spec.Where("lastUpdate", query.OpGte, from)
+	}
+	if !to.IsZero() {
+		spec = spec.Where("lastUpdate", query.OpLte, to)
+	}
+	avatarList, err := cu.avatarRepository.Find(ctx, spec)
+	tracing.RecordError(span, err)
+	return cu.colorPolicy.withPalettes(avatarList), err
+}
+
+// Export は登録日時がfrom以上to以下のAvatarを主キー順に1件ずつfnに渡す
+// ゼロ値のfrom, toは条件に含めない
+func (cu avatarUseCase) Export(ctx context.Context, from time.Time, to time.Time, fn func(*model.Avatar) error) error {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.Export")
+	defer span.End()
+	err := cu.avatarRepository.Each(ctx, exportSpec(from, to), fn)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// DeleteByAvatarId はAvatarを削除し、アップロードした画像があれば削除する
+func (cu avatarUseCase) DeleteByAvatarId(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "AvatarUseCase.DeleteByAvatarId")
+	defer span.End()
+	var imageFile string
+	if cu.blobs != nil {
+		avatar, err := cu.avatarRepository.FindByAvatarId(ctx, id)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return err
+		}
+		if avatar != nil {
+			imageFile = avatar.ImageFile
+		}
+	}
+	err := cu.avatarRepository.DeleteByAvatarId(ctx, id)
+	tracing.RecordError(span, err)
+	if err == nil && imageFile != "" {
+		cu.deleteImages(ctx, id, imageFile)
+	}
 	return err
 }
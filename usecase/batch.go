@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 一括処理のモード
+const (
+	// 1件でも失敗すれば何も処理しない
+	BatchModeAtomic = "atomic"
+	// 失敗した項目を除いて処理する
+	BatchModeBestEffort = "bestEffort"
+)
+
+// MaxBatchSize は1回の一括処理で扱う項目数の上限
+const MaxBatchSize = 1000
+
+var (
+	// ErrBatchAborted はBatchModeAtomicで他の項目が失敗したため処理しなかった項目のエラー
+	ErrBatchAborted = errors.New("not processed because another item failed")
+	// ErrNotFound は対象が存在しない項目のエラー
+	ErrNotFound = errors.New("not found")
+)
+
+// BatchItem は一括処理の1項目の結果。Errがnilなら成功で、Valueに処理後の値が入る
+type BatchItem[T any] struct {
+	Value *T
+	Err   error
+}
+
+// validateBatch は一括処理のモードと項目数を確認し、モードを返す。modeが空の場合はBatchModeAtomic
+// fieldは項目のリストのJSONのフィールド名
+func validateBatch(mode, field string, size int) (string, error) {
+	if mode == "" {
+		mode = BatchModeAtomic
+	}
+	if mode != BatchModeAtomic && mode != BatchModeBestEffort {
+		return "", &ValidationError{Field: "mode", Message: fmt.Sprintf("must be %s or %s", BatchModeAtomic, BatchModeBestEffort)}
+	}
+	if size < 1 || size > MaxBatchSize {
+		return "", &ValidationError{Field: field, Message: fmt.Sprintf("must have between 1 and %d items", MaxBatchSize)}
+	}
+	return mode, nil
+}
+
+// abortIfFailed はBatchModeAtomicで失敗した項目があれば、他の項目をErrBatchAbortedにしてtrueを返す
+func abortIfFailed[T any](mode string, items []BatchItem[T]) bool {
+	if mode != BatchModeAtomic {
+		return false
+	}
+	failed := false
+	for _, item := range items {
+		if item.Err != nil {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return false
+	}
+	for i := range items {
+		if items[i].Err == nil {
+			items[i] = BatchItem[T]{Err: ErrBatchAborted}
+		}
+	}
+	return true
+}
+
+// batchErrors は項目ごとのエラーを返す
+func batchErrors[T any](items []BatchItem[T]) []error {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = item.Err
+	}
+	return errs
+}
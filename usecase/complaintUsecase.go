@@ -1,49 +1,357 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/domain/query"
 	"github.com/backend-guchitter-app/domain/repository"
+	"github.com/backend-guchitter-app/domain/service"
+	"github.com/backend-guchitter-app/metrics"
+	"github.com/backend-guchitter-app/tracing"
 )
 
 type ComplaintUseCase interface {
-	FindAll() ([]*model.Complaint, error)
-	FindByAvatarId(id int) (*model.Complaint, error)
-	Create(complaint model.Complaint) (*model.Complaint, error)
-	FindBetweenTimestamp(from string, to string) ([]*model.Complaint, error)
-	DeleteByComplaintId(id int) error
+	FindAll(ctx context.Context) ([]*model.Complaint, error)
+	FindByAvatarId(ctx context.Context, id int) (*model.Complaint, error)
+	// mergedは重複として既存のぐちにまとめ、resultがその元のぐちである場合にtrue
+	Create(ctx context.Context, complaint model.Complaint) (result *model.Complaint, merged bool, err error)
+	Find(ctx context.Context, spec query.Spec) ([]*model.Complaint, error)
+	FindBetweenTimestamp(ctx context.Context, from time.Time, to time.Time) ([]*model.Complaint, error)
+	DeleteByComplaintId(ctx context.Context, id int) error
+	CreateBatch(ctx context.Context, complaints []model.Complaint, mode string) ([]BatchItem[model.Complaint], error)
+	CheckBatch(ctx context.Context, complaints []model.Complaint) ([]error, error)
+	DeleteBatch(ctx context.Context, ids []int, mode string) ([]error, error)
+	Export(ctx context.Context, from time.Time, to time.Time, fn func(*model.Complaint) error) error
+}
+
+// 重複・類似の投稿の扱い
+const (
+	// 判定しない
+	DuplicateActionOff = "off"
+	// ErrDuplicateComplaintを返して登録しない
+	DuplicateActionReject = "reject"
+	// 登録せず、元のぐちのDuplicateCountを1増やして元のぐちを返す
+	DuplicateActionMerge = "merge"
+)
+
+// ErrDuplicateComplaint は同じアバターの最近の投稿と同じ、または似たテキストのぐちを登録しようとしたときのエラー
+var ErrDuplicateComplaint = errors.New("duplicate complaint")
+
+// 重複・類似の投稿の判定方法
+type DuplicatePolicy struct {
+	// off, reject, merge
+	Action string
+	// 同じアバターのこの期間内の投稿と比べる
+	Window time.Duration
+	// SimHashの異なるビットがこの数以下なら類似とみなす。負の場合は完全一致のみ判定する
+	MaxDistance int
 }
 
 type complaintUseCase struct {
 	complaintRepository repository.ComplaintRepository
+	sentimentAnalyzer   service.SentimentAnalyzer
+	fingerprinter       service.Fingerprinter
+	duplicatePolicy     DuplicatePolicy
 }
 
-func NewComplaintUseCase(cr repository.ComplaintRepository) ComplaintUseCase {
+func NewComplaintUseCase(cr repository.ComplaintRepository, sa service.SentimentAnalyzer, fp service.Fingerprinter, dp DuplicatePolicy) ComplaintUseCase {
 	return &complaintUseCase{
 		complaintRepository: cr,
+		sentimentAnalyzer:   sa,
+		fingerprinter:       fp,
+		duplicatePolicy:     dp,
 	}
 }
 
-func (cu complaintUseCase) FindAll() ([]*model.Complaint, error) {
-	complaintList, err := cu.complaintRepository.FindAll()
+func (cu complaintUseCase) FindAll(ctx context.Context) ([]*model.Complaint, error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.FindAll")
+	defer span.End()
+	complaintList, err := cu.complaintRepository.FindAll(ctx)
+	tracing.RecordError(span, err)
 	return complaintList, err
 }
 
-func (cu complaintUseCase) FindByAvatarId(id int) (*model.Complaint, error) {
-	complaint, err := cu.complaintRepository.FindByAvatarId(id)
+func (cu complaintUseCase) FindByAvatarId(ctx context.Context, id int) (*model.Complaint, error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.FindByAvatarId")
+	defer span.End()
+	complaint, err := cu.complaintRepository.FindByAvatarId(ctx, id)
+	tracing.RecordError(span, err)
 	return complaint, err
 }
 
-func (cu complaintUseCase) Create(complaint model.Complaint) (*model.Complaint, error) {
-	result, err := cu.complaintRepository.Create(complaint)
-	return result, err
+func (cu complaintUseCase) Create(ctx context.Context, complaint model.Complaint) (*model.Complaint, bool, error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.Create")
+	defer span.End()
+	complaint = cu.prepare(complaint)
+	if err := validateComplaint(complaint); err != nil {
+		return nil, false, err
+	}
+
+	if cu.duplicatePolicy.Action != DuplicateActionOff {
+		recent, err := cu.recentComplaints(ctx, complaint.AvatarId)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, false, err
+		}
+		if original, reason := cu.matchDuplicate(recent, complaint); original != nil {
+			if cu.duplicatePolicy.Action == DuplicateActionReject {
+				return nil, false, rejectDuplicate(reason, fmt.Sprintf("complaint %d", original.ComplaintId))
+			}
+			merged, err := cu.complaintRepository.IncrementDuplicateCount(ctx, original.ComplaintId)
+			if err != nil {
+				tracing.RecordError(span, err)
+				return nil, false, err
+			}
+			// 比べた後に元のぐちが削除された場合は、新しいぐちとして登録する
+			if merged != nil {
+				return merged, true, nil
+			}
+		}
+	}
+
+	result, err := cu.complaintRepository.Create(ctx, complaint)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, false, err
+	}
+	metrics.ComplaintsCreated.WithLabelValues(strconv.Itoa(result.AvatarId)).Inc()
+	return result, false, nil
+}
+
+// CreateBatch はcomplaintsを1つのトランザクションで登録し、項目ごとの結果を返す
+// 各項目はCreateと同じく検証し、重複・類似はバッチ内の前の項目とも比べる
+// 一括登録ではDuplicateActionMergeの場合も重複した項目は登録せず、ErrDuplicateComplaintを返す
+// 項目の失敗はBatchItemのErrに、モードや項目数の誤りとDBのエラーはerrに返す
+func (cu complaintUseCase) CreateBatch(ctx context.Context, complaints []model.Complaint, mode string) ([]BatchItem[model.Complaint], error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.CreateBatch")
+	defer span.End()
+	mode, err := validateBatch(mode, "items", len(complaints))
+	if err != nil {
+		return nil, err
+	}
+
+	items, accepted, acceptedIndexes, err := cu.checkBatch(ctx, complaints)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if abortIfFailed(mode, items) || len(accepted) == 0 {
+		return items, nil
+	}
+
+	created, err := cu.complaintRepository.CreateBatch(ctx, accepted)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	for k, c := range created {
+		items[acceptedIndexes[k]].Value = c
+		metrics.ComplaintsCreated.WithLabelValues(strconv.Itoa(c.AvatarId)).Inc()
+	}
+	return items, nil
+}
+
+// CheckBatch はCreateBatchと同じく検証し、登録せずに項目ごとのエラーを返す。取り込みのdry-runに使う
+func (cu complaintUseCase) CheckBatch(ctx context.Context, complaints []model.Complaint) ([]error, error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.CheckBatch")
+	defer span.End()
+	if _, err := validateBatch(BatchModeBestEffort, "items", len(complaints)); err != nil {
+		return nil, err
+	}
+
+	items, _, _, err := cu.checkBatch(ctx, complaints)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return batchErrors(items), nil
+}
+
+// checkBatch はcomplaintsを検証し、項目ごとの結果と、登録できる項目とそのcomplaintsでの位置を返す
+func (cu complaintUseCase) checkBatch(ctx context.Context, complaints []model.Complaint) ([]BatchItem[model.Complaint], []model.Complaint, []int, error) {
+	items := make([]BatchItem[model.Complaint], len(complaints))
+	accepted := make([]model.Complaint, 0, len(complaints))
+	acceptedIndexes := make([]int, 0, len(complaints))
+	// アバターごとの比較対象。DBの最近の投稿に、バッチ内で受け付けた項目を加えていく
+	candidates := map[int][]*model.Complaint{}
+	batchIndexes := map[*model.Complaint]int{}
+	for i, complaint := range complaints {
+		complaint = cu.prepare(complaint)
+		if err := validateComplaint(complaint); err != nil {
+			items[i].Err = err
+			continue
+		}
+
+		if cu.duplicatePolicy.Action != DuplicateActionOff {
+			recent, ok := candidates[complaint.AvatarId]
+			if !ok {
+				var err error
+				if recent, err = cu.recentComplaints(ctx, complaint.AvatarId); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			if original, reason := cu.matchDuplicate(recent, complaint); original != nil {
+				target := fmt.Sprintf("complaint %d", original.ComplaintId)
+				if j, ok := batchIndexes[original]; ok {
+					target = fmt.Sprintf("item %d", j)
+				}
+				items[i].Err = rejectDuplicate(reason, target)
+				candidates[complaint.AvatarId] = recent
+				continue
+			}
+			added := complaint
+			batchIndexes[&added] = i
+			candidates[complaint.AvatarId] = append(recent, &added)
+		}
+
+		accepted = append(accepted, complaint)
+		acceptedIndexes = append(acceptedIndexes, i)
+	}
+	return items, accepted, acceptedIndexes, nil
+}
+
+// prepare はクライアントの指定を無視する項目を消し、登録時に算出する項目を設定する
+func (cu complaintUseCase) prepare(complaint model.Complaint) model.Complaint {
+	// 登録日時・更新日時はクライアントの指定を無視してサーバー側で付与する
+	complaint.CreatedAt = time.Time{}
+	complaint.LastUpdate = time.Time{}
+	// IDとまとめた投稿の数はサーバー側で決める
+	complaint.ComplaintId = 0
+	complaint.DuplicateCount = 0
+	// 感情スコアはクライアントから受け取らず、登録時にテキストから算出する
+	complaint.Sentiment = cu.sentimentAnalyzer.Analyze(complaint.ComplaintText)
+	complaint.Fingerprint = cu.fingerprinter.Fingerprint(complaint.ComplaintText)
+	return complaint
+}
+
+// recentComplaints はavatarIdのアバターのWindow内の投稿を返す
+// ぐちには投稿したユーザーがないため、投稿者ごとでなくアバターごとに比べる
+func (cu complaintUseCase) recentComplaints(ctx context.Context, avatarId int) ([]*model.Complaint, error) {
+	spec := query.Spec{}.
+		Where("avatarId", query.OpEq, avatarId).
+		Where("createdAt", query.OpGte, time.Now().Add(-cu.duplicatePolicy.Window))
+	return cu.complaintRepository.Find(ctx, spec)
+}
+
+// matchDuplicate はcandidatesから、complaintと同じまたは似たテキストのぐちを探す
+// 完全一致を優先し、なければSimHashが最も近いものを返す。reasonはduplicateまたはnear_duplicate
+func (cu complaintUseCase) matchDuplicate(candidates []*model.Complaint, complaint model.Complaint) (original *model.Complaint, reason string) {
+	bestDistance := cu.duplicatePolicy.MaxDistance + 1
+	for _, c := range candidates {
+		if c.TextHash == complaint.TextHash {
+			return c, "duplicate"
+		}
+		// 指紋を算出する前に登録されたぐちは比べない
+		if c.TextHash == "" {
+			continue
+		}
+		if d := c.Distance(complaint.Fingerprint); d < bestDistance {
+			original, bestDistance = c, d
+		}
+	}
+	if original != nil {
+		return original, "near_duplicate"
+	}
+	return nil, ""
 }
 
-func (cu complaintUseCase) FindBetweenTimestamp(from string, to string) ([]*model.Complaint, error) {
-	complaintList, err := cu.complaintRepository.FindBetweenTimestamp(from, to)
+// rejectDuplicate は拒否した数を記録し、reasonに応じたErrDuplicateComplaintを返す
+// originalはどれと重複したかの説明("complaint 3"など)
+func rejectDuplicate(reason, original string) error {
+	metrics.ModerationRejections.WithLabelValues(reason).Inc()
+	if reason == "duplicate" {
+		return fmt.Errorf("%w: same text as %s", ErrDuplicateComplaint, original)
+	}
+	return fmt.Errorf("%w: similar text to %s", ErrDuplicateComplaint, original)
+}
+
+func (cu complaintUseCase) Find(ctx context.Context, spec query.Spec) ([]*model.Complaint, error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.Find")
+	defer span.End()
+	complaintList, err := cu.complaintRepository.Find(ctx, spec)
+	tracing.RecordError(span, err)
+	return complaintList, err
+}
+
+// FindBetweenTimestamp は更新日時がfrom以上to以下のComplaintを返す
+// ゼロ値のfrom, toは条件に含めない
+func (cu complaintUseCase) FindBetweenTimestamp(ctx context.Context, from time.Time, to time.Time) ([]*model.Complaint, error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.FindBetweenTimestamp")
+	defer span.End()
+	spec := query.Spec{}
+	if !from.IsZero() {
+		spec = spec.Where("lastUpdate", query.OpGte, from)
+	}
+	if !to.IsZero() {
+		spec = spec.Where("lastUpdate", query.OpLte, to)
+	}
+	complaintList, err := cu.complaintRepository.Find(ctx, spec)
+	tracing.RecordError(span, err)
 	return complaintList, err
 }
 
-func (cu complaintUseCase) DeleteByComplaintId(id int) error {
-	err := cu.complaintRepository.DeleteByComplaintId(id)
+// Export は登録日時がfrom以上to以下のComplaintを主キー順に1件ずつfnに渡す
+// ゼロ値のfrom, toは条件に含めない
+func (cu complaintUseCase) Export(ctx context.Context, from time.Time, to time.Time, fn func(*model.Complaint) error) error {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.Export")
+	defer span.End()
+	err := cu.complaintRepository.Each(ctx, exportSpec(from, to), fn)
+	tracing.RecordError(span, err)
+	return err
+}
+
+func (cu complaintUseCase) DeleteByComplaintId(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.DeleteByComplaintId")
+	defer span.End()
+	err := cu.complaintRepository.DeleteByComplaintId(ctx, id)
+	tracing.RecordError(span, err)
 	return err
 }
+
+// DeleteBatch はidsのComplaintを1つのトランザクションで削除し、idsと同じ順で項目ごとのエラーを返す
+// 存在しないIDの項目はErrNotFound。BatchModeAtomicで存在しないIDがあれば何も削除しない
+func (cu complaintUseCase) DeleteBatch(ctx context.Context, ids []int, mode string) ([]error, error) {
+	ctx, span := tracing.Start(ctx, "ComplaintUseCase.DeleteBatch")
+	defer span.End()
+	mode, err := validateBatch(mode, "ids", len(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := cu.complaintRepository.Find(ctx, query.Spec{}.Where("complaintId", query.OpIn, ids))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	found := make(map[int]bool, len(existing))
+	for _, c := range existing {
+		found[c.ComplaintId] = true
+	}
+
+	items := make([]BatchItem[int], len(ids))
+	targets := make([]int, 0, len(ids))
+	for i, id := range ids {
+		if !found[id] {
+			items[i].Err = ErrNotFound
+			continue
+		}
+		targets = append(targets, id)
+	}
+	if !abortIfFailed(mode, items) && len(targets) > 0 {
+		if err := cu.complaintRepository.DeleteByComplaintIds(ctx, targets); err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
+
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = item.Err
+	}
+	return errs, nil
+}
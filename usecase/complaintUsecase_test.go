@@ -0,0 +1,57 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/backend-guchitter-app/domain/model"
+	"github.com/backend-guchitter-app/infrastructure/fingerprint"
+	"github.com/backend-guchitter-app/infrastructure/inmemory"
+	"github.com/backend-guchitter-app/infrastructure/sentiment"
+	"github.com/backend-guchitter-app/usecase"
+)
+
+func newComplaintUseCase(action string) usecase.ComplaintUseCase {
+	return usecase.NewComplaintUseCase(inmemory.NewComplaintRepository(), sentiment.NewLexiconAnalyzer(), fingerprint.NewSimHashFingerprinter(),
+		usecase.DuplicatePolicy{Action: action, Window: time.Hour, MaxDistance: 3})
+}
+
+func TestCreateIgnoresServerFields(t *testing.T) {
+	cu := newComplaintUseCase(usecase.DuplicateActionMerge)
+	ctx := context.Background()
+
+	created, merged, err := cu.Create(ctx, model.Complaint{ComplaintId: 42, ComplaintText: "月曜はつらい", AvatarId: 1, DuplicateCount: 999})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if merged {
+		t.Errorf("Create() merged = true, want false")
+	}
+	if created.DuplicateCount != 0 {
+		t.Errorf("DuplicateCount = %d, want 0", created.DuplicateCount)
+	}
+	if created.ComplaintId == 42 {
+		t.Errorf("ComplaintId = 42, want an id assigned by the repository")
+	}
+}
+
+func TestCreateMerged(t *testing.T) {
+	cu := newComplaintUseCase(usecase.DuplicateActionMerge)
+	ctx := context.Background()
+
+	original, _, err := cu.Create(ctx, model.Complaint{ComplaintText: "月曜はつらい", AvatarId: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	result, merged, err := cu.Create(ctx, model.Complaint{ComplaintText: "月曜はつらい", AvatarId: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !merged {
+		t.Errorf("Create() merged = false, want true")
+	}
+	if result.ComplaintId != original.ComplaintId || result.DuplicateCount != 1 {
+		t.Errorf("Create() = complaint %d with duplicateCount %d, want complaint %d with 1", result.ComplaintId, result.DuplicateCount, original.ComplaintId)
+	}
+}
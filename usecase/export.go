@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/backend-guchitter-app/domain/query"
+)
+
+// exportSpec は登録日時がfrom以上to以下のものを書き出す条件を返す
+// ゼロ値のfrom, toは条件に含めない
+func exportSpec(from, to time.Time) query.Spec {
+	spec := query.Spec{}
+	if !from.IsZero() {
+		spec = spec.Where("createdAt", query.OpGte, from)
+	}
+	if !to.IsZero() {
+		spec = spec.Where("createdAt", query.OpLte, to)
+	}
+	return spec
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/backend-guchitter-app/domain/model"
+)
+
+// ErrInvalid は登録する内容が条件を満たさないときのエラー
+var ErrInvalid = errors.New("invalid input")
+
+// ValidationError は条件を満たさない項目と理由
+// errors.Is(err, ErrInvalid)で判定できる
+type ValidationError struct {
+	// JSONのフィールド名
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// validateComplaint はComplaintを登録できるか確認する。長さの上限はDBのカラムに合わせる
+func validateComplaint(c model.Complaint) error {
+	switch {
+	case strings.TrimSpace(c.ComplaintText) == "":
+		return &ValidationError{Field: "complaintText", Message: "is required"}
+	case utf8.RuneCountInString(c.ComplaintText) > 255:
+		return &ValidationError{Field: "complaintText", Message: "must be at most 255 characters"}
+	case c.AvatarId < 1:
+		return &ValidationError{Field: "avatarId", Message: "must be a positive integer"}
+	}
+	return nil
+}
+
+// validateAvatar はAvatarを登録できるか確認する。長さの上限はDBのカラムに合わせる
+func validateAvatar(a model.Avatar) error {
+	switch {
+	case strings.TrimSpace(a.AvatarName) == "":
+		return &ValidationError{Field: "avatarName", Message: "is required"}
+	case utf8.RuneCountInString(a.AvatarName) > 45:
+		return &ValidationError{Field: "avatarName", Message: "must be at most 45 characters"}
+	case utf8.RuneCountInString(a.AvatarText) > 45:
+		return &ValidationError{Field: "avatarText", Message: "must be at most 45 characters"}
+	case utf8.RuneCountInString(a.ImageUrl) > 255:
+		return &ValidationError{Field: "imageUrl", Message: "must be at most 255 characters"}
+	case utf8.RuneCountInString(a.Color) > 45:
+		return &ValidationError{Field: "color", Message: "must be at most 45 characters"}
+	}
+	return nil
+}